@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// readPseudonyms reads the -anonymize-map file at path, one "author: pseudonym" line
+// per author in the syntax of the config file. A missing file is an empty map.
+func readPseudonyms(path string) (map[string]string, error) {
+	pseudonyms := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pseudonyms, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pseudonyms: %s", err)
+	}
+	settings, err := parseConfig(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	for _, setting := range settings {
+		if len(setting.values) != 1 {
+			return nil, fmt.Errorf("%s:%d: expected \"author: pseudonym\"", path, setting.line)
+		}
+		pseudonyms[setting.name] = setting.values[0]
+	}
+	return pseudonyms, nil
+}
+
+// writePseudonyms saves pseudonyms to path for readPseudonyms, sorted by pseudonym.
+func writePseudonyms(path string, pseudonyms map[string]string) error {
+	authors := make([]string, 0, len(pseudonyms))
+	for author := range pseudonyms {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if pseudonyms[authors[i]] != pseudonyms[authors[j]] {
+			return pseudonyms[authors[i]] < pseudonyms[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+
+	var b strings.Builder
+	b.WriteString("# Pseudonyms of gitstats -anonymize; keep this file private\n")
+	for _, author := range authors {
+		fmt.Fprintf(&b, "%s: %s\n", author, pseudonyms[author])
+	}
+	// The authors are the personal data the pseudonyms hide
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("failed to write pseudonyms: %s", err)
+	}
+	return nil
+}
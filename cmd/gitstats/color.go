@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// colorOutput reports whether ANSI colors should be written to f in the -color mode:
+// always, never, or with auto only when f is a terminal and the NO_COLOR convention
+// (https://no-color.org) does not ask otherwise.
+func colorOutput(f *os.File, mode string) bool {
+	if mode == "always" {
+		return true
+	}
+	return !colorDisabled(mode) && isTerminal(f)
+}
+
+// colorDisabled reports whether the -color mode, or NO_COLOR with auto, turns colors off
+// whatever the output.
+func colorDisabled(mode string) bool {
+	return mode == "never" || mode == "auto" && os.Getenv("NO_COLOR") != ""
+}
+
+// isTerminal reports whether f is a character device such as a terminal, as opposed
+// to a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// unicodeTerminal reports whether the locale advertises UTF-8, so box-drawing
+// characters can be displayed.
+func unicodeTerminal() bool {
+	for _, env := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(env); value != "" {
+			value = strings.ToUpper(value)
+			return strings.Contains(value, "UTF-8") || strings.Contains(value, "UTF8")
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestColorOutput(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "report.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	for _, noColor := range []string{"", "1"} {
+		t.Setenv("NO_COLOR", noColor)
+		for _, f := range []*os.File{file, w} {
+			for mode, want := range map[string]bool{"always": true, "never": false, "auto": false} {
+				if got := colorOutput(f, mode); got != want {
+					t.Errorf("NO_COLOR=%q, %s, -color %s: got %v, want %v", noColor, f.Name(), mode, got, want)
+				}
+			}
+		}
+	}
+
+	t.Setenv("NO_COLOR", "")
+	if colorDisabled("auto") || !colorDisabled("never") || colorDisabled("always") {
+		t.Error("colorDisabled without NO_COLOR: want only never to disable colors")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if !colorDisabled("auto") || colorDisabled("always") {
+		t.Error("colorDisabled with NO_COLOR: want auto disabled and always kept")
+	}
+}
+
+func TestColorFlag(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	args := []string{"-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache"}
+
+	// runGitstats pipes stdout and sets NO_COLOR
+	for mode, want := range map[string]bool{"auto": false, "never": false, "always": true} {
+		stdout, stderr, code := runGitstats(t, base, append(args, "-color", mode)...)
+		if code != 0 {
+			t.Fatalf("-color %s: exit status %d: %s", mode, code, stderr)
+		}
+		if got := strings.Contains(stdout, "\033["); got != want {
+			t.Errorf("-color %s: colors %v, want %v:\n%s", mode, got, want, stdout)
+		}
+	}
+}
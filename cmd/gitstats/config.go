@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// defaultConfigFile is looked up in the working directory when -config is not given.
+const defaultConfigFile = "gitstats.yaml"
+
+// configSetting is one option of the config file, with every value of a list.
+type configSetting struct {
+	name   string
+	values []string
+	line   int
+}
+
+// applyConfig sets the flags named in the config file at path, or in
+// gitstats.yaml when path is empty, unless they were given on the command line. A
+// missing gitstats.yaml is not an error; a missing -config file is.
+func applyConfig(flags *flag.FlagSet, path string) error {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config: %s", err)
+	}
+
+	settings, err := parseConfig(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	for _, setting := range settings {
+		if flags.Lookup(setting.name) == nil || setting.name == "config" {
+			return fmt.Errorf("%s:%d: unknown option %s", path, setting.line, setting.name)
+		}
+		if given[setting.name] {
+			continue
+		}
+		for _, value := range setting.values {
+			if err := flags.Set(setting.name, value); err != nil {
+				return fmt.Errorf("%s:%d: invalid value %q for %s: %s", path, setting.line, value, setting.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseConfig reads the YAML subset of the config file: "name: value" lines, where
+// the value may be a [a, b] list or be followed by "- item" lines, and # comments.
+func parseConfig(data string) ([]configSetting, error) {
+	var settings []configSetting
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if item, ok := strings.CutPrefix(line, "- "); ok || line == "-" {
+			if len(settings) == 0 {
+				return nil, fmt.Errorf("line %d: list item without an option", i+1)
+			}
+			last := &settings[len(settings)-1]
+			last.values = append(last.values, unquote(strings.TrimSpace(item)))
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"option: value\"", i+1)
+		}
+		setting := configSetting{name: strings.TrimSpace(name), line: i + 1}
+		value = strings.TrimSpace(value)
+		if list, ok := strings.CutPrefix(value, "["); ok {
+			list, ok = strings.CutSuffix(list, "]")
+			if !ok {
+				return nil, fmt.Errorf("line %d: unterminated list", i+1)
+			}
+			for _, item := range strings.Split(list, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					setting.values = append(setting.values, unquote(item))
+				}
+			}
+		} else if value != "" {
+			setting.values = []string{unquote(value)}
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+// stripComment drops a # comment that starts the line or follows whitespace outside
+// quotes, so "#" inside values such as Title (#12) or "fix # later" is kept. Quotes
+// only count at the start of a value, not in O'Brien.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && (i == 0 || strings.ContainsRune(" \t:[,-", rune(line[i-1]))):
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote removes the single or double quotes around value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
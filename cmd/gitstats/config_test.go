@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripComment(t *testing.T) {
+	tests := []struct{ line, want string }{
+		{"# a comment", ""},
+		{"months: 3 # last quarter", "months: 3 "},
+		{"months: 3\t# tab", "months: 3\t"},
+		{"title: Fix (#12)", "title: Fix (#12)"},
+		{"title: a#b", "title: a#b"},
+		{`title: "fix # later" # note`, `title: "fix # later" `},
+		{"title: 'fix # later'", "title: 'fix # later'"},
+		{"exclude: [\"#ops\", b] # note", "exclude: [\"#ops\", b] "},
+		{"- 'x # y'", "- 'x # y'"},
+		{"author: O'Brien # note", "author: O'Brien "},
+	}
+	for _, tt := range tests {
+		if got := stripComment(tt.line); got != tt.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	tests := []struct{ value, want string }{
+		{`"go,md"`, "go,md"},
+		{"'go'", "go"},
+		{`""`, ""},
+		{"go", "go"},
+		{`"go'`, `"go'`},
+		{`"`, `"`},
+		{"O'Brien", "O'Brien"},
+	}
+	for _, tt := range tests {
+		if got := unquote(tt.value); got != tt.want {
+			t.Errorf("unquote(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	settings, err := parseConfig(`# gitstats defaults
+ext: "go,md"   # quoted, with a comment
+months: 3
+title: "Q1 # draft"
+skip-dir: [vendor, 'node_modules', ]
+exclude:
+  - bot@example.com
+  - "#ops@example.com"
+all:
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []configSetting{
+		{name: "ext", values: []string{"go,md"}, line: 2},
+		{name: "months", values: []string{"3"}, line: 3},
+		{name: "title", values: []string{"Q1 # draft"}, line: 4},
+		{name: "skip-dir", values: []string{"vendor", "node_modules"}, line: 5},
+		{name: "exclude", values: []string{"bot@example.com", "#ops@example.com"}, line: 6},
+		{name: "all", line: 9},
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("parseConfig = %+v, want %+v", settings, want)
+	}
+
+	for data, msg := range map[string]string{
+		"- orphan":         "line 1: list item without an option",
+		"months 3":         "line 1: expected",
+		"\next: [go, md\n": "line 2: unterminated list",
+	} {
+		if _, err := parseConfig(data); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("parseConfig(%q) = %v, want %q", data, err, msg)
+		}
+	}
+}
+
+// configFlags returns a flag set with a few of the options of gitstats.
+func configFlags() (*flag.FlagSet, *string, *int, *multiFlag) {
+	flags := flag.NewFlagSet("gitstats", flag.ContinueOnError)
+	ext := flags.String("ext", "", "")
+	months := flags.Int("m", 1, "")
+	var skipDirs multiFlag
+	flags.Var(&skipDirs, "skip-dir", "")
+	flags.String("config", "", "")
+	return flags, ext, months, &skipDirs
+}
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gitstats.yaml")
+	if err := os.WriteFile(path, []byte("ext: go\nm: 6\nskip-dir: [vendor, testdata]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, ext, months, skipDirs := configFlags()
+	if err := flags.Parse([]string{"-m", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(flags, path); err != nil {
+		t.Fatal(err)
+	}
+	if *ext != "go" || !reflect.DeepEqual(*skipDirs, multiFlag{"vendor", "testdata"}) {
+		t.Errorf("ext %q, skip-dir %v, want the config values", *ext, *skipDirs)
+	}
+	if *months != 2 {
+		t.Errorf("m = %d, want 2 from the command line over 6 from the config", *months)
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	for data, msg := range map[string]string{
+		"ext: go\nfoo: bar\n": "gitstats.yaml:2: unknown option foo",
+		"config: other.yaml":  "gitstats.yaml:1: unknown option config",
+		"m: three":            `gitstats.yaml:1: invalid value "three" for m`,
+	} {
+		path := filepath.Join(dir, "gitstats.yaml")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		flags, _, _, _ := configFlags()
+		if err := applyConfig(flags, path); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%q: %v, want %q", data, err, msg)
+		}
+	}
+
+	flags, _, _, _ := configFlags()
+	if err := applyConfig(flags, filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("missing -config file accepted")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := applyConfig(flags, ""); err != nil {
+		t.Errorf("missing gitstats.yaml: %s, want the built-in defaults", err)
+	}
+}
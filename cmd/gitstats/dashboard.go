@@ -0,0 +1,163 @@
+package main
+
+// dashboardHTML is the page served by serve at /. It reads /api/stats and renders a
+// leaderboard of the selected month and repository, with a sparkline of each author's
+// insertions over the analyzed months.
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Git statistics</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+form { margin-bottom: 1.5em; }
+label { margin-right: 1em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { padding: 0.3em 0.8em; border-bottom: 1px solid #d0d7de; }
+th { text-align: left; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.ins { color: #1a7f37; }
+.del { color: #cf222e; }
+tr.repo { cursor: pointer; }
+tr.repo:hover { background: #f6f8fa; }
+polyline { fill: none; stroke: #2da44e; stroke-width: 1.5; }
+#error { color: #cf222e; }
+</style>
+</head>
+<body>
+<h1>Git statistics</h1>
+<form id="filters">
+<label>Since <input id="since" placeholder="2024-01-01 or 6.months.ago"></label>
+<label>Month <select id="month"></select></label>
+<label>Repository <select id="repo"></select></label>
+</form>
+<p id="error"></p>
+<h2>Leaderboard</h2>
+<table>
+<thead><tr><th>#</th><th>Author</th><th>Commits</th><th>Insertions</th><th>Deletions</th><th>Net</th><th>Trend</th></tr></thead>
+<tbody id="leaderboard"></tbody>
+</table>
+<h2>Repositories</h2>
+<table>
+<thead><tr><th>Repository</th><th>Commits</th><th>Insertions</th><th>Deletions</th><th>Net</th></tr></thead>
+<tbody id="repos"></tbody>
+</table>
+<script>
+"use strict";
+let report = null;
+
+function el(tag, text, className) {
+  const e = document.createElement(tag);
+  if (text !== undefined) e.textContent = text;
+  if (className) e.className = className;
+  return e;
+}
+
+function months(stats) {
+  const all = new Set();
+  for (const author in stats.authors) for (const month in stats.authors[author]) all.add(month);
+  return [...all].sort();
+}
+
+function sum(byMonth, month) {
+  const total = {insertions: 0, deletions: 0, commits: 0};
+  for (const m in byMonth) {
+    if (month && m !== month) continue;
+    total.insertions += byMonth[m].insertions;
+    total.deletions += byMonth[m].deletions;
+    total.commits += byMonth[m].commits;
+  }
+  return total;
+}
+
+function sparkline(byMonth, all) {
+  const values = all.map(m => byMonth[m] ? byMonth[m].insertions : 0);
+  const max = Math.max(1, ...values);
+  const svg = document.createElementNS("http://www.w3.org/2000/svg", "svg");
+  svg.setAttribute("width", "100");
+  svg.setAttribute("height", "20");
+  const line = document.createElementNS("http://www.w3.org/2000/svg", "polyline");
+  const step = values.length > 1 ? 100 / (values.length - 1) : 0;
+  line.setAttribute("points", values.map((v, i) => (i * step) + "," + (19 - v * 18 / max)).join(" "));
+  svg.appendChild(line);
+  return svg;
+}
+
+function option(select, value, text, selected) {
+  const o = el("option", text);
+  o.value = value;
+  o.selected = value === selected;
+  select.appendChild(o);
+}
+
+function render() {
+  const repoSelect = document.getElementById("repo");
+  const monthSelect = document.getElementById("month");
+  const repo = repoSelect.value, month = monthSelect.value;
+  const stats = repo && report.repositories && report.repositories[repo] ? report.repositories[repo] : report;
+  const all = months(report);
+
+  repoSelect.textContent = "";
+  option(repoSelect, "", "All repositories", repo);
+  for (const name of Object.keys(report.repositories || {}).sort()) option(repoSelect, name, name, repo);
+  monthSelect.textContent = "";
+  option(monthSelect, "", "All months", month);
+  for (const m of all.slice().reverse()) option(monthSelect, m, m, month);
+
+  const rows = Object.keys(stats.authors).map(author => ({author, ...sum(stats.authors[author], month)}))
+    .filter(row => row.commits > 0)
+    .sort((a, b) => (b.insertions - b.deletions) - (a.insertions - a.deletions) || a.author.localeCompare(b.author));
+  const leaderboard = document.getElementById("leaderboard");
+  leaderboard.textContent = "";
+  rows.forEach((row, i) => {
+    const tr = el("tr");
+    const name = report.names && report.names[row.author];
+    tr.append(el("td", i + 1, "num"), el("td", name ? name + " <" + row.author + ">" : row.author),
+      el("td", row.commits, "num"), el("td", row.insertions, "num ins"), el("td", row.deletions, "num del"),
+      el("td", row.insertions - row.deletions, "num"));
+    const trend = el("td");
+    trend.appendChild(sparkline(stats.authors[row.author], all));
+    tr.appendChild(trend);
+    leaderboard.appendChild(tr);
+  });
+
+  const repos = document.getElementById("repos");
+  repos.textContent = "";
+  for (const name of Object.keys(report.repositories || {}).sort()) {
+    const total = {insertions: 0, deletions: 0, commits: 0};
+    for (const author in report.repositories[name].authors) {
+      const s = sum(report.repositories[name].authors[author], month);
+      total.insertions += s.insertions;
+      total.deletions += s.deletions;
+      total.commits += s.commits;
+    }
+    const tr = el("tr", undefined, "repo");
+    tr.append(el("td", name), el("td", total.commits, "num"), el("td", total.insertions, "num ins"),
+      el("td", total.deletions, "num del"), el("td", total.insertions - total.deletions, "num"));
+    tr.onclick = () => { repoSelect.value = name; render(); };
+    repos.appendChild(tr);
+  }
+}
+
+async function load() {
+  const since = document.getElementById("since").value.trim();
+  const response = await fetch("/api/stats" + (since ? "?since=" + encodeURIComponent(since) : ""));
+  const error = document.getElementById("error");
+  if (!response.ok) {
+    error.textContent = await response.text();
+    return;
+  }
+  error.textContent = "";
+  report = await response.json();
+  render();
+}
+
+document.getElementById("month").onchange = render;
+document.getElementById("repo").onchange = render;
+document.getElementById("since").onchange = load;
+document.getElementById("filters").onsubmit = e => { e.preventDefault(); load(); };
+load();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// readIdentities reads the identity file at path. It uses the YAML subset of the
+// config file, with one canonical author per key and its aliases as the value:
+//
+//	Alice Smith <alice@corp.com>: [alice@home.net, 1234+alice@users.noreply.github.com]
+//	bob@corp.com:
+//	  - bob@home.net
+//	  - Bobby
+//
+// The key is an email, or a display name followed by the email in angle brackets.
+func readIdentities(path string) ([]gitstats.Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identities: %s", err)
+	}
+	settings, err := parseConfig(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	var identities []gitstats.Identity
+	for _, setting := range settings {
+		identity := gitstats.Identity{Email: setting.name, Aliases: setting.values}
+		if name, email, ok := strings.Cut(setting.name, "<"); ok {
+			identity.Name = strings.TrimSpace(name)
+			identity.Email = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(email), ">"))
+		}
+		if !strings.Contains(identity.Email, "@") {
+			return nil, fmt.Errorf("%s:%d: expected an email or \"Name <email>\", got %q", path, setting.line, setting.name)
+		}
+		identities = append(identities, identity)
+	}
+	return identities, nil
+}
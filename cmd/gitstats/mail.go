@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// mailReport sends report to the recipients through the SMTP server at addr
+// (host:port), authenticating with SMTP_USERNAME and SMTP_PASSWORD when set. The report
+// is sent as HTML for -format html and as plain text otherwise.
+func mailReport(addr, from string, to []string, subject string, report []byte, html bool) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid -smtp-server %q, want host:port: %s", addr, err)
+	}
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		if from == "" {
+			from = username
+		}
+	}
+	if from == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "localhost"
+		}
+		from = "gitstats@" + hostname
+	}
+
+	contentType := "text/plain"
+	if html {
+		contentType = "text/html"
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	// Reports have long table lines and non-ASCII borders, beyond what SMTP carries raw
+	fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	qp.Write(report)
+	qp.Close()
+
+	if err := smtp.SendMail(addr, auth, from, to, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send report mail: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// smtpMessage is what a client sent to serveSMTP.
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// serveSMTP answers one SMTP session on l, just enough for smtp.SendMail, and sends the
+// message received to messages.
+func serveSMTP(l net.Listener, messages chan<- smtpMessage) {
+	conn, err := l.Accept()
+	if err != nil {
+		close(messages)
+		return
+	}
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var msg smtpMessage
+	text.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			close(messages)
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			msg.from = arg
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, arg)
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Go ahead")
+			data, _ := io.ReadAll(text.DotReader())
+			msg.data = string(data)
+			text.PrintfLine("250 OK")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			messages <- msg
+			close(messages)
+			return
+		default:
+			text.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+func TestMailReport(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	messages := make(chan smtpMessage, 1)
+	go serveSMTP(l, messages)
+	t.Setenv("SMTP_USERNAME", "")
+
+	report := "Total lines by developer:\n  alice@example.com  " + strings.Repeat("─", 60) + "\n"
+	err = mailReport(l.Addr().String(), "stats@example.com", []string{"lead@example.com", "team@example.com"}, "März report", []byte(report), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, ok := <-messages
+	if !ok {
+		t.Fatal("no message received")
+	}
+	if msg.from != "FROM:<stats@example.com>" || fmt.Sprint(msg.to) != "[TO:<lead@example.com> TO:<team@example.com>]" {
+		t.Errorf("envelope from %q to %q", msg.from, msg.to)
+	}
+
+	header, body, _ := strings.Cut(msg.data, "\n\n")
+	for _, want := range []string{
+		"From: stats@example.com", "To: lead@example.com, team@example.com", "Subject: =?utf-8?q?M=C3=A4rz_report?=",
+		"Content-Type: text/plain; charset=utf-8", "Content-Transfer-Encoding: quoted-printable",
+	} {
+		if !strings.Contains(header+"\n", want+"\n") {
+			t.Errorf("missing %q in the headers:\n%s", want, header)
+		}
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(bufio.NewReader(strings.NewReader(body))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ReplaceAll(string(decoded), "\r\n", "\n") != report {
+		t.Errorf("body = %q, want the report %q", decoded, report)
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if len(line) > 76 {
+			t.Errorf("body line of %d characters, beyond SMTP's limits", len(line))
+		}
+	}
+
+	if err := mailReport("localhost", "", []string{"lead@example.com"}, "report", nil, true); err == nil || !strings.Contains(err.Error(), "want host:port") {
+		t.Errorf("server without port: %v, want an error", err)
+	}
+}
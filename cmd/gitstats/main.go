@@ -0,0 +1,1032 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// multiFlag is a repeatable string flag.
+type multiFlag []string
+
+func (m *multiFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *multiFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+func main() {
+	// main exits with exitCode once its deferred calls ran: 2 after a failure, so that
+	// a scan that failed never passes a -fail-if gate, and 1 when a -fail-if condition
+	// holds
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+	// fail reports a failure on stderr
+	fail := func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+		exitCode = 2
+	}
+
+	// "gitstats serve" keeps scanning instead of printing one report, "gitstats watch"
+	// keeps writing the report on a schedule, and "gitstats ownership" reports who owns
+	// the current lines instead of the changes
+	subcommand := ""
+	if len(os.Args) > 1 && (os.Args[1] == "serve" || os.Args[1] == "watch" || os.Args[1] == "ownership") {
+		subcommand = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	serving := subcommand == "serve"
+
+	monthsBackPtr := flag.Int("m", 1, "Number of periods (months by default, see -granularity) to check backward")
+	granularityStr := flag.String("granularity", "month", "Bucket size of the report: day, week or month (-m counts these buckets)")
+	flag.StringVar(granularityStr, "bucket", "month", "Same as -granularity")
+	periodStartDayPtr := flag.Int("period-start-day", 1, "Day of the month (1-28) months start on, e.g. 26 for reporting periods from the 26th to the 25th")
+	tzStr := flag.String("tz", "Local", "Time zone of the period boundaries commits are bucketed in: Local, UTC, an offset like +09:00, a name like Europe/Berlin, or commit for each commit's own offset")
+	flag.StringVar(tzStr, "timezone", "Local", "Same as -tz")
+	sinceStr := flag.String("since", "", "Analyze from this date (YYYY-MM-DD or relative like 3.weeks.ago) instead of the last -m periods")
+	untilStr := flag.String("until", "", "Analyze up to and including this date (YYYY-MM-DD or relative like 3.weeks.ago) instead of the last -m periods")
+	allReposPtr := flag.Bool("a", false, "Analyze all repositories in subdirectories")
+	depthPtr := flag.Int("depth", 3, "How many directory levels below -p to search for repositories (with -a, -1 for no limit)")
+	flag.IntVar(depthPtr, "max-depth", 3, "Same as -depth")
+	var skipDirs multiFlag
+	flag.Var(&skipDirs, "skip-dir", "Never search directories whose name or path below -p matches this glob (with -a), comma-separated or repeatable")
+	baseDirStr := flag.String("p", ".", "Path for analysis ( . by default)")
+	var repos multiFlag
+	flag.Var(&repos, "repo", "Analyze this repository instead of -p, comma-separated or repeatable, e.g. a repo list in the config file")
+	var gitDirs multiFlag
+	flag.Var(&gitDirs, "git-dir", "Analyze this git directory instead of -p, such as a bare repository (repo.git) or the .git of a clone, comma-separated or repeatable")
+	recurseSubmodulesPtr := flag.Bool("recurse-submodules", false, "Also analyze the checked-out submodules of the repositories, recursively")
+	submoduleStatsStr := flag.String("submodule-stats", "separate", "Where -by-repo lists the stats of submodules: separate (as their own repositories) or parent (in their top-level repository)")
+	reposFileStr := flag.String("repos", "", "File listing the clone URLs of the repositories to analyze, one per line: missing ones are cloned into -workspace, the others updated")
+	workspaceStr := flag.String("workspace", "", "Directory of the clones of -repos (default gitstats/repos in the user cache directory)")
+	var githubRepos, githubOrgs multiFlag
+	flag.Var(&githubRepos, "github", "Analyze this GitHub repository (owner/name) through the API instead of a clone, comma-separated or repeatable. The API only reports the totals of each commit, so -ext, -path, -path-include and -exclude fail")
+	flag.Var(&githubOrgs, "github-org", "Analyze every repository of this GitHub organization through the API, comma-separated or repeatable")
+	githubTokenStr := flag.String("github-token", "", "GitHub token of -github and -github-org (default $GITHUB_TOKEN)")
+	var gitlabProjects, gitlabGroups multiFlag
+	flag.Var(&gitlabProjects, "gitlab", "Analyze this GitLab project (group/name) through the API instead of a clone, comma-separated or repeatable. As with -github, -ext, -path, -path-include and -exclude fail")
+	flag.Var(&gitlabGroups, "gitlab-group", "Analyze every project of this GitLab group and its subgroups through the API, comma-separated or repeatable")
+	gitlabTokenStr := flag.String("gitlab-token", "", "GitLab token of -gitlab and -gitlab-group (default $GITLAB_TOKEN)")
+	gitlabURLStr := flag.String("gitlab-url", "https://gitlab.com", "GitLab instance of -gitlab and -gitlab-group")
+	activeDaysPtr := flag.Bool("active-days", false, "Add the days each author committed on and their commits per active day to the developer table")
+	sharesPtr := flag.Bool("shares", false, "Add each author's share of commits to the monthly and developer tables, and below each the concentration of insertions: the top 3 share and the Gini coefficient")
+	commitSizesPtr := flag.Bool("commit-sizes", false, "Add the mean and median lines changed (insertions plus deletions) per commit to the developer table")
+	dedupePtr := flag.Bool("dedupe", true, "With -a, count the commits of forks and mirrors once, in the first repository having them, and report the duplicates skipped (use -dedupe=false to count them in every repository)")
+	breadthPtr := flag.Bool("breadth", false, "Report the distinct files and directories each author changed per month and overall (text, markdown and json formats)")
+	heatmapPtr := flag.Bool("heatmap", false, "Report commits by weekday and hour of the day as a heatmap (text, json and html formats)")
+	heatmapByAuthorPtr := flag.Bool("heatmap-by-author", false, "Also report the heatmap of each author (implies -heatmap)")
+	activityGapPtr := flag.Bool("activity-gap", false, "Report days since the last commit per author")
+	gapDaysPtr := flag.Int("gap-days", 30, "Flag authors inactive for more than N days (with -activity-gap)")
+	tenurePtr := flag.Bool("tenure", false, "Report each author's first and last commit in the analyzed history, flagging new and inactive contributors")
+	inactiveMonthsPtr := flag.Int("inactive-months", 3, "Flag authors without commits for N months as inactive (with -tenure)")
+	anonymizePtr := flag.Bool("anonymize", false, "Replace the authors with stable pseudonyms such as Dev-07 to share the report outside the team")
+	anonymizeMapStr := flag.String("anonymize-map", "", "Keep the pseudonyms of -anonymize in this file, so they stay the same across reports")
+	tagsPtr := flag.Bool("tags", false, "Report the number of tags created per person")
+	tagsPatternPtr := flag.String("tags-pattern", "", "Only count tags whose name matches the glob (with -tags)")
+	branchStr := flag.String("branch", "", "Analyze this ref in every repository instead of the checked-out branch")
+	flag.StringVar(branchStr, "ref", "", "Same as -branch")
+	allRefsPtr := flag.Bool("all-refs", false, "Analyze the commits of every branch and tag (git log --all)")
+	mailmapStr := flag.String("mailmap", "", "Additional mailmap file applied to every repository, on top of each repo's .mailmap")
+	identitiesStr := flag.String("identities", "", "YAML file merging the emails and names of each person into one canonical author")
+	teamsStr := flag.String("teams", "", "YAML file listing the member emails, globs or /regexps/ of each team (with -by-team)")
+	byTeamPtr := flag.Bool("by-team", false, "Report the changes per team of -teams instead of per author")
+	mergeByNamePtr := flag.Bool("merge-by-name", false, "Merge authors sharing the same display name but different emails")
+	noMergesPtr := flag.Bool("no-merges", true, "Skip merge commits (use -no-merges=false to count them)")
+	maxCommitsPtr := flag.Int("max-commits", 0, "Examine at most the N most recent commits per repository and period (0 = all)")
+	maxCommitLinesPtr := flag.Int("max-commit-lines", 0, "Treat commits changing more than N lines as outliers, listed separately and handled per -outliers (0 = no limit)")
+	outliersStr := flag.String("outliers", "exclude", "Outliers of -max-commit-lines: exclude them, or cap their lines at the limit")
+	netOnlyPtr := flag.Bool("net-only", false, "Show only net lines (insertions minus deletions) in the text report")
+	allFilesPtr := flag.Bool("all-files", false, "Analyze every file, even with -ext or -auto-ext (e.g. set in the config file)")
+	autoExtPtr := flag.Bool("auto-ext", false, "Only analyze the dominant file extensions of each repository")
+	autoExtSkipStr := flag.String("auto-ext-skip", gitstats.DefaultAutoExtSkip, "Comma-separated extensions and file names never picked by -auto-ext")
+	compareStr := flag.String("compare", "", "Compare the analyzed window with the one before it (previous) or a year earlier (year), per author and repository")
+	tuiPtr := flag.Bool("tui", false, "Browse the report interactively: arrow keys pick a month and an author, enter shows their repositories and file types")
+	churnDaysPtr := flag.Int("churn", 0, "Also report the lines each author added that were deleted again within N days, e.g. 21 (0 = off)")
+	prsPtr := flag.Bool("prs", false, "Report contributions grouped by GitHub pull request, parsed from merge commit messages")
+	reviewsPtr := flag.Bool("reviews", false, "With -github or -gitlab, also report the pull requests each person opened and got merged, and the reviews, approvals and review comments they gave, per month")
+	byTicketPtr := flag.Bool("by-ticket", false, "Report the lines and commits per ticket ID found in the commit messages, and the authors of each")
+	byOwnerPtr := flag.Bool("by-owner", false, "Report the lines and commits per owner of the CODEOWNERS file of each repository, and the authors of each")
+	var metricNames multiFlag
+	flag.Var(&metricNames, "metric", "Also report this metric per author: insertions, deletions, commits, files or one registered by a build of gitstats; comma-separated or repeatable")
+	var failIf multiFlag
+	flag.Var(&failIf, "fail-if", "Exit with status 1 after the report when this condition holds, e.g. 'total_insertions < 1000' or 'authors < 3' (repeatable, any may fail)")
+	ticketPatternStr := flag.String("ticket-pattern", gitstats.DefaultTicketPattern, "Regexp matching the ticket IDs of -by-ticket, e.g. 'PROJ-[0-9]+' or '#[0-9]+'")
+	var authors multiFlag
+	flag.Var(&authors, "author", "Only count authors whose email matches this glob, substring or /regexp/, comma-separated or repeatable")
+	flag.Var(&authors, "only-author", "Same as -author")
+	var excludeAuthors multiFlag
+	flag.Var(&excludeAuthors, "exclude-author", "Never count authors whose email matches this glob, substring or /regexp/, comma-separated or repeatable")
+	caseSensitiveEmailsPtr := flag.Bool("case-sensitive-emails", false, "Keep authors whose emails differ only in case apart")
+	excludeBotsPtr := flag.Bool("exclude-bots", true, "Leave out bot accounts such as dependabot[bot] and github-actions[bot] (use -exclude-bots=false to count them)")
+	var grep multiFlag
+	flag.Var(&grep, "grep", "Only count commits whose message matches this extended regexp, e.g. EPIC-42 (repeatable, any may match)")
+	invertGrepPtr := flag.Bool("invert-grep", false, "Only count the commits whose message matches none of -grep, e.g. -grep '^(chore|Revert)' -invert-grep")
+	var excludes multiFlag
+	flag.Var(&excludes, "exclude", "Exclude paths matching this gitignore-style pattern (repeatable)")
+	flag.Var(&excludes, "path-exclude", "Same as -exclude")
+	var includes multiFlag
+	flag.Var(&includes, "path-include", "Only analyze paths matching this gitignore-style pattern, e.g. 'src/**' (repeatable, any may match)")
+	excludeGeneratedPtr := flag.Bool("exclude-generated", true, "Exclude vendored, generated and lock files such as vendor/, *.pb.go and go.sum (use -exclude-generated=false to count them)")
+	var paths multiFlag
+	flag.Var(&paths, "path", "Only analyze files below this path (repeatable)")
+	var exts multiFlag
+	flag.Var(&exts, "ext", "Only analyze files with this extension, repeatable or comma-separated (default: all files)")
+	byLanguagePtr := flag.Bool("by-language", false, "Also report each author's insertions and deletions per file extension")
+	byPathStr := flag.String("by-path", "", "Also report the changes per directory: depth=N for the directories N levels deep, or comma-separated path prefixes such as services/api,services/web")
+	byFiletypePtr := flag.Bool("by-filetype", false, "Same as -by-language -numstat: split the changes by the extension of every changed file in a single pass")
+	pathStatsStr := flag.String("path-stats", "", "Report who changed a single file or directory, per month")
+	sortStr := flag.String("sort", "net", "Sort authors by net, insertions, deletions, commits or author")
+	reversePtr := flag.Bool("reverse", false, "Reverse the -sort order of the authors")
+	borderStr := flag.String("border", "none", "Table style of the text report: none, ascii or unicode-box")
+	rollingPtr := flag.Int("rolling", 0, "Also report an N-month rolling average of insertions")
+	trendPtr := flag.Bool("trend", false, "Also report the trend of insertions per month of each author, with a forecast of the next month")
+	chartsStr := flag.String("charts", "", "Also write SVG charts of the months, top contributors and repositories to this directory")
+	barsPtr := flag.Bool("bars", false, "Draw a bar of each author's insertions in the developer table and a sparkline of the monthly totals")
+	chartPtr := flag.Bool("chart", false, "Render an ASCII chart of total insertions per month")
+	formatStr := flag.String("format", "text", "Output format: text, json, delimited, csv, markdown, html, sql or prometheus")
+	templateStr := flag.String("template", "", "Render the report with this Go text/template file instead of -format, executed on a gitstats.Summary like -slack-template")
+	maxAuthorsPtr := flag.Int("json-max-authors", 0, "Keep the top N authors by -sort in machine-readable output and sum the rest into \"others\" (0 = all)")
+	delimiterStr := flag.String("delimiter", "|", "Field delimiter for -format=delimited")
+	headerPtr := flag.Bool("header", true, "Print a header record for -format=delimited and -format=csv")
+	coAuthorsStr := flag.String("co-authors", "off", "Credit the Co-authored-by trailers of commits: off, split (share the lines) or duplicate (each gets all of them)")
+	squashMergesStr := flag.String("squash-merges", "include", "Commits that look like squash merges: include, exclude or separate")
+	languageProfilePtr := flag.Bool("language-profile", false, "Report each author's share of lines changed per language (e.g. 60% Kotlin, 30% YAML), from the per-file counts like -by-filetype")
+	numstatPtr := flag.Bool("numstat", false, "Split -by-language from the per-file counts of the main git log pass instead of one pass per extension")
+	countBinaryPtr := flag.Bool("count-binary", false, "Report binary files changed per person")
+	findRenamesPtr := flag.Bool("find-renames", true, "Count a renamed file by its edits instead of as deleted and added (use -find-renames=false to disable)")
+	renameThresholdPtr := flag.Int("rename-threshold", 0, "Similarity in percent a file must keep to count as renamed or copied, e.g. 30 to catch renames with larger edits (0 = git's 50)")
+	findCopiesPtr := flag.Bool("find-copies", false, "Also count a file copied from one changed in the same commit by its edits instead of as added (git -C)")
+	findCopiesHarderPtr := flag.Bool("find-copies-harder", false, "Like -find-copies, but look for the source among every file of the commit's parent; slow on large repositories")
+	ignoreWhitespacePtr := flag.Bool("ignore-whitespace", false, "Ignore whitespace-only changes when counting lines (git -w)")
+	outputStr := flag.String("o", "", "Write the report to this file instead of stdout, creating its directory as needed")
+	flag.StringVar(outputStr, "output", "", "Same as -o")
+	colorStr := flag.String("color", "auto", "ANSI colors: auto (only on a terminal, unless NO_COLOR is set), always or never")
+	noColorPtr := flag.Bool("no-color", false, "Same as -color=never")
+	topPtr := flag.Int("top", 0, "Only list the first N authors of the developer table and collapse the rest (0 = all)")
+	topMonthsPtr := flag.Bool("top-months", false, "Apply -top to the per-month tables as well")
+	jobsPtr := flag.Int("j", runtime.GOMAXPROCS(0), "Number of repositories processed concurrently")
+	flag.IntVar(jobsPtr, "jobs", runtime.GOMAXPROCS(0), "Same as -j")
+	byRepoPtr := flag.Bool("by-repo", false, "Report each repository separately before the totals across all repositories")
+	htmlStr := flag.String("html", "", "Also write a standalone HTML report with bar charts to this file")
+	xlsxStr := flag.String("xlsx", "", "Also write an Excel workbook with a summary sheet and a sheet per month to this file")
+	sqliteStr := flag.String("sqlite", "", "Also upsert the changes per repository, author and period into this SQLite database, using the sqlite3 command")
+	slackWebhookStr := flag.String("slack-webhook", "", "Also post a summary of the report to this Slack incoming webhook URL")
+	slackTemplateStr := flag.String("slack-template", "", "text/template file of the -slack-webhook message (default: totals and top five authors per period)")
+	var mailTo multiFlag
+	flag.Var(&mailTo, "mail-to", "Also mail the report to this address, comma-separated or repeatable (with -smtp-server)")
+	smtpServerStr := flag.String("smtp-server", "", "SMTP server (host:port) of -mail-to, authenticating with SMTP_USERNAME and SMTP_PASSWORD when set")
+	mailFromStr := flag.String("mail-from", "", "Sender of -mail-to (default SMTP_USERNAME or gitstats@<hostname>)")
+	mailSubjectStr := flag.String("mail-subject", "Git statistics", "Subject of -mail-to")
+	outDirStr := flag.String("out-dir", "", "Also write one report file per repository into this directory")
+	noMergedPtr := flag.Bool("no-merged", false, "Don't print the merged report to stdout (with -out-dir)")
+	debugPtr := flag.Bool("debug", false, "Write structured key=value diagnostics to stderr")
+	cpuProfileStr := flag.String("cpuprofile", "", "Write a CPU profile to this file")
+	memProfileStr := flag.String("memprofile", "", "Write a heap profile to this file on exit")
+	unshallowPtr := flag.Bool("unshallow", false, "Deepen shallow clones with git fetch to cover the analyzed window before analyzing them")
+	noCachePtr := flag.Bool("no-cache", false, "Don't read or write the cache of per-period results")
+	cacheDirStr := flag.String("cache-dir", "", "Directory of the cache (default gitstats in the user cache directory)")
+	var verbose, quiet bool
+	flag.BoolVar(&verbose, "v", false, "Log every git command run to stderr")
+	flag.BoolVar(&verbose, "verbose", false, "Same as -v")
+	veryVerbosePtr := flag.Bool("vv", false, "Log every git command and the -debug diagnostics to stderr")
+	flag.BoolVar(&quiet, "q", false, "Don't write progress and warnings to stderr, only fatal errors")
+	flag.BoolVar(&quiet, "quiet", false, "Same as -q")
+	listenStr := flag.String("listen", ":9123", "Address serve listens on for the dashboard, /api/stats and /metrics")
+	flag.StringVar(listenStr, "prometheus", ":9123", "Same as -listen")
+	intervalPtr := flag.Duration("interval", 15*time.Minute, "Time between two scans of serve, or two reports of watch without -schedule")
+	scheduleStr := flag.String("schedule", "", "Cron expression of the reports of watch, e.g. '0 7 * * 1-5' or @daily, in local time")
+	configStr := flag.String("config", "", "Read default options from this YAML file (default gitstats.yaml in the working directory, if present)")
+	flag.Parse()
+
+	if err := applyConfig(flag.CommandLine, *configStr); err != nil {
+		fail("%s", err)
+		return
+	}
+
+	if *cpuProfileStr != "" {
+		f, err := os.Create(*cpuProfileStr)
+		if err != nil {
+			fail("Failed to create CPU profile: %s", err)
+			return
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fail("Failed to start CPU profile: %s", err)
+			return
+		}
+		defer pprof.StopCPUProfile()
+	}
+	if *memProfileStr != "" {
+		defer func() {
+			f, err := os.Create(*memProfileStr)
+			if err != nil {
+				fail("Failed to create memory profile: %s", err)
+				return
+			}
+			defer f.Close()
+			runtime.GC() // Up-to-date statistics
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				fail("Failed to write memory profile: %s", err)
+			}
+		}()
+	}
+
+	var debugLog *slog.Logger
+	if *debugPtr || *veryVerbosePtr {
+		debugLog = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	if *formatStr != "text" && *formatStr != "json" && *formatStr != "delimited" && *formatStr != "csv" && *formatStr != "markdown" && *formatStr != "html" && *formatStr != "sql" && *formatStr != "prometheus" {
+		fail("Unknown format: %s", *formatStr)
+		return
+	}
+	var reportTemplate *template.Template
+	if *templateStr != "" {
+		text, err := os.ReadFile(*templateStr)
+		if err != nil {
+			fail("Failed to read report template: %s", err)
+			return
+		}
+		if reportTemplate, err = gitstats.ParseTemplate(filepath.Base(*templateStr), string(text)); err != nil {
+			fail("%s", err)
+			return
+		}
+		*formatStr = "template"
+	}
+	// The git commands are only logged with -v or -vv, progress unless -q
+	var commandLog, progressLog *log.Logger
+	if verbose || *veryVerbosePtr {
+		commandLog = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	if !quiet {
+		progressLog = log.Default()
+	}
+	// The progress bar would be torn apart by the logged commands
+	var onProgress func(done, total int)
+	if !quiet && commandLog == nil && isTerminal(os.Stderr) {
+		onProgress = progressBar(os.Stderr)
+	}
+	switch *sortStr {
+	case "net", "insertions", "deletions", "commits", "author":
+	default:
+		fail("Unknown sort column: %s", *sortStr)
+		return
+	}
+	if *borderStr != "none" && *borderStr != "ascii" && *borderStr != "unicode-box" {
+		fail("Unknown border style: %s", *borderStr)
+		return
+	}
+	if *outliersStr != "exclude" && *outliersStr != "cap" {
+		fail("Unknown -outliers mode: %s", *outliersStr)
+		return
+	}
+	if *squashMergesStr != "include" && *squashMergesStr != "exclude" && *squashMergesStr != "separate" {
+		fail("Unknown -squash-merges mode: %s", *squashMergesStr)
+		return
+	}
+	if *renameThresholdPtr < 0 || *renameThresholdPtr > 100 {
+		fail("Invalid -rename-threshold: %d, expected a percentage", *renameThresholdPtr)
+		return
+	}
+	if *submoduleStatsStr != "separate" && *submoduleStatsStr != "parent" {
+		fail("Unknown -submodule-stats mode: %s", *submoduleStatsStr)
+		return
+	}
+	var metrics []gitstats.Metric
+	for _, name := range splitList(metricNames) {
+		metric, err := gitstats.LookupMetric(name)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		metrics = append(metrics, metric)
+	}
+	var conditions []gitstats.Condition
+	for _, expr := range failIf {
+		condition, err := gitstats.ParseCondition(expr)
+		if err != nil {
+			fail("Invalid -fail-if: %s", err)
+			return
+		}
+		conditions = append(conditions, condition)
+	}
+	var pathDepth int
+	var pathPrefixes []string
+	if depth, ok := strings.CutPrefix(*byPathStr, "depth="); ok {
+		var err error
+		if pathDepth, err = strconv.Atoi(depth); err != nil || pathDepth < 1 {
+			fail("Invalid -by-path depth: %s", depth)
+			return
+		}
+	} else if *byPathStr != "" {
+		for _, prefix := range strings.Split(*byPathStr, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				pathPrefixes = append(pathPrefixes, prefix)
+			}
+		}
+	}
+	if *anonymizeMapStr != "" && !*anonymizePtr {
+		fail("-anonymize-map needs -anonymize")
+		return
+	}
+	// The pseudonyms given so far, saved again once the new authors have theirs
+	var pseudonyms map[string]string
+	if *anonymizePtr {
+		pseudonyms = make(map[string]string)
+		if *anonymizeMapStr != "" {
+			var err error
+			if pseudonyms, err = readPseudonyms(*anonymizeMapStr); err != nil {
+				fail("%s", err)
+				return
+			}
+		}
+	}
+	anonymize := func(gb gitstats.GlobalStats) (gitstats.GlobalStats, error) {
+		if pseudonyms == nil {
+			return gb, nil
+		}
+		gb = *gitstats.Anonymize(&gb, pseudonyms)
+		if *anonymizeMapStr != "" {
+			return gb, writePseudonyms(*anonymizeMapStr, pseudonyms)
+		}
+		return gb, nil
+	}
+	if *invertGrepPtr && len(grep) == 0 {
+		fail("-invert-grep needs a -grep pattern")
+		return
+	}
+	coAuthors := *coAuthorsStr
+	if coAuthors == "off" {
+		coAuthors = ""
+	} else if coAuthors != "split" && coAuthors != "duplicate" {
+		fail("Unknown -co-authors mode: %s", coAuthors)
+		return
+	}
+	colorMode := *colorStr
+	if *noColorPtr {
+		colorMode = "never"
+	}
+	if colorMode != "auto" && colorMode != "always" && colorMode != "never" {
+		fail("Unknown -color mode: %s", colorMode)
+		return
+	}
+	if *byTeamPtr && *teamsStr == "" {
+		fail("-by-team needs -teams")
+		return
+	}
+	if len(mailTo) > 0 && *smtpServerStr == "" {
+		fail("-mail-to needs -smtp-server")
+		return
+	}
+	if *sqliteStr != "" {
+		// Checked before the scan rather than after it
+		if _, err := exec.LookPath("sqlite3"); err != nil {
+			fail("-sqlite needs the sqlite3 command: sqlite3 not found in PATH")
+			return
+		}
+	}
+	if *allRefsPtr && *branchStr != "" {
+		fail("-all-refs and -branch are mutually exclusive")
+		return
+	}
+
+	// periods returns the date ranges analyzed at now, each reported as its own bucket
+	periods := func(now time.Time) ([]gitstats.Period, error) {
+		if *sinceStr != "" || *untilStr != "" {
+			return gitstats.RangePeriodsFrom(*granularityStr, *periodStartDayPtr, *sinceStr, *untilStr, now)
+		}
+		return gitstats.PeriodsFrom(*granularityStr, *periodStartDayPtr, *monthsBackPtr, now)
+	}
+	initialPeriods, err := periods(time.Now())
+	if err != nil {
+		fail("%s", err)
+		return
+	}
+
+	cacheDir := *cacheDirStr
+	if *noCachePtr {
+		cacheDir = ""
+	} else if cacheDir == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			cacheDir = filepath.Join(dir, "gitstats")
+		}
+	}
+
+	location, err := parseLocation(*tzStr)
+	if err != nil {
+		fail("%s", err)
+		return
+	}
+
+	var identities []gitstats.Identity
+	if *identitiesStr != "" {
+		if identities, err = readIdentities(*identitiesStr); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	var teams []gitstats.Team
+	if *byTeamPtr {
+		if teams, err = readTeams(*teamsStr); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+
+	options := gitstats.Options{
+		Path:                *baseDirStr,
+		Repos:               append(splitList(repos), splitList(gitDirs)...),
+		All:                 *allReposPtr,
+		Depth:               *depthPtr,
+		SkipDirs:            splitList(skipDirs),
+		Periods:             initialPeriods,
+		Location:            location,
+		Branch:              *branchStr,
+		AllRefs:             *allRefsPtr,
+		Mailmap:             *mailmapStr,
+		NoMerges:            *noMergesPtr,
+		MaxCommits:          *maxCommitsPtr,
+		MaxCommitLines:      *maxCommitLinesPtr,
+		CapCommitLines:      *outliersStr == "cap",
+		IgnoreWhitespace:    *ignoreWhitespacePtr,
+		NoRenames:           !*findRenamesPtr,
+		RenameThreshold:     *renameThresholdPtr,
+		FindCopies:          *findCopiesPtr,
+		FindCopiesHarder:    *findCopiesHarderPtr,
+		Numstat:             *numstatPtr || *byFiletypePtr || *languageProfilePtr,
+		SquashMerges:        *squashMergesStr,
+		Grep:                grep,
+		InvertGrep:          *invertGrepPtr,
+		Extensions:          extensions(exts),
+		AllFiles:            *allFilesPtr,
+		AutoExt:             *autoExtPtr,
+		AutoExtSkip:         strings.Split(*autoExtSkipStr, ","),
+		Excludes:            excludes,
+		ExcludeGenerated:    *excludeGeneratedPtr,
+		Paths:               paths,
+		Includes:            includes,
+		Authors:             splitList(authors),
+		ExcludeAuthors:      splitList(excludeAuthors),
+		ExcludeBots:         *excludeBotsPtr,
+		CoAuthors:           coAuthors,
+		CaseSensitiveEmails: *caseSensitiveEmailsPtr,
+		Identities:          identities,
+		PathStats:           *pathStatsStr,
+		ByLanguage:          *byLanguagePtr || *byFiletypePtr || *languageProfilePtr,
+		PathDepth:           pathDepth,
+		PathPrefixes:        pathPrefixes,
+		ByRepo:              *byRepoPtr || *outDirStr != "" || *sqliteStr != "" || *chartsStr != "",
+		MergeByName:         *mergeByNamePtr,
+		Tags:                *tagsPtr,
+		TagsPattern:         *tagsPatternPtr,
+		PullRequests:        *prsPtr,
+		ChurnDays:           *churnDaysPtr,
+		Heatmap:             *heatmapPtr || *heatmapByAuthorPtr,
+		Breadth:             *breadthPtr,
+		Dedupe:              *dedupePtr,
+		Jobs:                *jobsPtr,
+		CacheDir:            cacheDir,
+		Unshallow:           *unshallowPtr,
+		Logger:              commandLog,
+		Progress:            progressLog,
+		Debug:               debugLog,
+		OnProgress:          onProgress,
+	}
+	if *byTicketPtr {
+		options.TicketPattern = *ticketPatternStr
+	}
+	if *recurseSubmodulesPtr {
+		options.Submodules = *submoduleStatsStr
+	}
+	options.CodeOwners = *byOwnerPtr
+	options.Metrics = metrics
+	if subcommand == "watch" {
+		// Every run is a report of its own with the same options and outputs
+		if err := watch(*scheduleStr, *intervalPtr, os.Args[1:], progressLog); err != nil {
+			fail("%s", err)
+		}
+		return
+	}
+	if *reposFileStr != "" {
+		urls, err := readManifest(*reposFileStr)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		workspace := *workspaceStr
+		if workspace == "" {
+			dir, err := os.UserCacheDir()
+			if err != nil {
+				fail("-repos needs a -workspace: %s", err)
+				return
+			}
+			workspace = filepath.Join(dir, "gitstats", "repos")
+		}
+		dirs, warnings := syncWorkspace(workspace, urls, *jobsPtr, progressLog)
+		if !quiet {
+			for _, warning := range warnings {
+				fmt.Fprintln(os.Stderr, warning)
+			}
+		}
+		if len(dirs) == 0 {
+			fail("No repositories of -repos to analyze")
+			return
+		}
+		// Repositories are named by their path in the workspace
+		options.Path = workspace
+		options.Repos = dirs
+		options.All = false
+	}
+	github := len(githubRepos) > 0 || len(githubOrgs) > 0
+	gitlab := len(gitlabProjects) > 0 || len(gitlabGroups) > 0
+	if github && gitlab {
+		fail("-github and -gitlab can't be combined")
+		return
+	}
+	if *reviewsPtr && !github && !gitlab {
+		fail("-reviews needs -github or -gitlab: reviews are read from their API")
+		return
+	}
+	options.Reviews = *reviewsPtr
+	if gitlab {
+		if err := useGitLab(&options, splitList(gitlabProjects), splitList(gitlabGroups), *gitlabTokenStr, *gitlabURLStr); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if github {
+		if err := useGitHub(&options, splitList(githubRepos), splitList(githubOrgs), *githubTokenStr); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if serving {
+		// Series are labeled with their repository
+		options.ByRepo = true
+		scan := func() (gitstats.GlobalStats, []gitstats.Period, error) {
+			scanOptions := options
+			var err error
+			if scanOptions.Periods, err = periods(time.Now()); err != nil {
+				return gitstats.GlobalStats{}, nil, err
+			}
+			gb, err := gitstats.Collect(scanOptions)
+			if err != nil {
+				return gb, nil, err
+			}
+			if !quiet {
+				for _, warning := range gb.Warnings {
+					fmt.Fprintln(os.Stderr, warning)
+				}
+			}
+			if *byTeamPtr {
+				grouped, err := gitstats.GroupByTeam(&gb, teams)
+				if err != nil {
+					return gb, nil, err
+				}
+				gb = *grouped
+			}
+			if gb, err = anonymize(gb); err != nil {
+				return gb, nil, err
+			}
+			return gb, scanOptions.Periods, nil
+		}
+		if err := serve(*listenStr, *intervalPtr, scan, progressLog); err != nil {
+			fail("%s", err)
+		}
+		return
+	}
+
+	if subcommand == "ownership" {
+		if err := printOwnership(options, *outputStr, *formatStr, *borderStr, colorMode, quiet); err != nil {
+			fail("%s", err)
+		}
+		return
+	}
+
+	if *tuiPtr {
+		if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+			fail("-tui needs a terminal")
+			return
+		}
+		// The details of an author need their repositories and file types
+		options.ByRepo = true
+		options.ByLanguage = true
+		options.Numstat = true
+	}
+
+	// -compare collects the analyzed window and the one it is compared with as two periods
+	var previous, current gitstats.Period
+	if *compareStr != "" {
+		if len(options.Periods) == 0 {
+			fail("-compare needs a window to compare: -m must be at least 1")
+			return
+		}
+		window := options.Periods[0]
+		for _, p := range options.Periods[1:] {
+			if p.Since.Before(window.Since) {
+				window.Since = p.Since
+			}
+			if p.Until.After(window.Until) {
+				window.Until = p.Until
+			}
+		}
+		if previous, current, err = gitstats.ComparePeriods(window, *compareStr); err != nil {
+			fail("%s", err)
+			return
+		}
+		options.Periods = []gitstats.Period{current, previous}
+		options.ByRepo = true
+	}
+
+	gb, err := gitstats.Collect(options)
+	if err != nil {
+		fail("%s", err)
+		return
+	}
+	// Warnings are diagnostics like the git commands, never part of the report
+	if !quiet {
+		for _, warning := range gb.Warnings {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+	if *byTeamPtr {
+		grouped, err := gitstats.GroupByTeam(&gb, teams)
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+		gb = *grouped
+	}
+	if gb, err = anonymize(gb); err != nil {
+		fail("%s", err)
+		return
+	}
+	if *tuiPtr {
+		if err := runTUI(gb, *sortStr, *reversePtr); err != nil {
+			fail("%s", err)
+		}
+		return
+	}
+
+	sampleNote := ""
+	if *maxCommitsPtr > 0 {
+		sampleNote = fmt.Sprintf("Sample: at most %d most recent commits per repository and period were examined\n", *maxCommitsPtr)
+	}
+
+	renderOpts := gitstats.RenderOptions{
+		Format:       *formatStr,
+		Delimiter:    *delimiterStr,
+		Header:       *headerPtr,
+		MaxAuthors:   *maxAuthorsPtr,
+		PathStats:    *pathStatsStr,
+		Template:     reportTemplate,
+		NetOnly:      *netOnlyPtr,
+		Sort:         *sortStr,
+		Reverse:      *reversePtr,
+		Border:       *borderStr,
+		Top:          *topPtr,
+		TopAll:       *topMonthsPtr,
+		Rolling:      *rollingPtr,
+		Trend:        *trendPtr,
+		Chart:        *chartPtr,
+		Bars:         *barsPtr,
+		ChartWidth:   terminalWidth(),
+		Tags:         *tagsPtr,
+		Binary:       *countBinaryPtr,
+		PullRequests: *prsPtr,
+		ActivityGap:  *activityGapPtr,
+		GapDays:      *gapDaysPtr,
+		Teams:        *byTeamPtr,
+		Days:         *activeDaysPtr,
+		Sizes:        *commitSizesPtr,
+		Shares:       *sharesPtr,
+
+		HeatmapByAuthor: *heatmapByAuthorPtr,
+		LanguageProfile: *languageProfilePtr,
+
+		Tenure:         *tenurePtr,
+		InactiveMonths: *inactiveMonthsPtr,
+	}
+	if *borderStr == "unicode-box" && (!unicodeTerminal() || colorDisabled(colorMode)) {
+		renderOpts.Border = "ascii"
+	}
+
+	if *outDirStr != "" {
+		if err := writeRepoReports(*outDirStr, gb.Repos, renderOpts); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if *htmlStr != "" {
+		if err := writeHTMLReport(*htmlStr, gb, renderOpts); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if *xlsxStr != "" {
+		if err := writeXLSXReport(*xlsxStr, gb, renderOpts); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if *chartsStr != "" {
+		if err := writeCharts(*chartsStr, gb, renderOpts); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if *sqliteStr != "" {
+		if err := writeSQLite(*sqliteStr, gb); err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if *slackWebhookStr != "" {
+		message, err := slackMessage(*slackTemplateStr, gb, renderOpts)
+		if err == nil {
+			err = postSlack(*slackWebhookStr, message)
+		}
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+	if !*byRepoPtr && *compareStr == "" {
+		// Repos were only collected for the per-repo report files and database rows
+		gb.Repos = nil
+	}
+	if len(mailTo) > 0 {
+		// The mail holds the report of -format, without colors
+		var report bytes.Buffer
+		mailOpts := renderOpts
+		mailOpts.Color = false
+		err := gitstats.Render(&report, gb, mailOpts)
+		if err == nil {
+			err = mailReport(*smtpServerStr, *mailFromStr, splitList(mailTo), *mailSubjectStr, report.Bytes(), *formatStr == "html")
+		}
+		if err != nil {
+			fail("%s", err)
+			return
+		}
+	}
+
+	// -no-merged only leaves out the merged report, the other outputs are written
+	if *outDirStr == "" || !*noMergedPtr {
+		outFile := os.Stdout
+		if *outputStr != "" {
+			f, err := createOutput(*outputStr)
+			if err != nil {
+				fail("%s", err)
+				return
+			}
+			outFile = f
+		}
+
+		// The report is stripped of colors unless they can be displayed
+		renderOpts.Color = colorOutput(outFile, colorMode)
+
+		if *formatStr != "text" {
+			fmt.Fprint(os.Stderr, sampleNote)
+			gitstats.PrintMergedNames(os.Stderr, gb.Merged)
+		} else {
+			fmt.Fprint(outFile, sampleNote)
+			gitstats.PrintMergedNames(outFile, gb.Merged)
+		}
+
+		if *compareStr != "" {
+			err = gitstats.RenderComparison(outFile, gitstats.Compare(gb, previous, current), renderOpts)
+		} else {
+			err = gitstats.Render(outFile, gb, renderOpts)
+		}
+		if err != nil {
+			fail("%s", err)
+		}
+		if outFile != os.Stdout {
+			// A failed close can lose the end of the report, e.g. on a full disk
+			if err := outFile.Close(); err != nil {
+				fail("Failed to write output file: %s", err)
+			}
+		}
+	}
+
+	// Failed conditions are reported after the report, so CI logs show both
+	for _, condition := range conditions {
+		if holds, value := condition.Eval(gb); holds {
+			fmt.Fprintf(os.Stderr, "Condition failed: %s (%s is %d)\n", condition, condition.Variable, value)
+			if exitCode == 0 {
+				exitCode = 1
+			}
+		}
+	}
+}
+
+// writeHTMLReport writes the HTML report of gb to path, next to the report selected
+// by -format.
+func writeHTMLReport(path string, gb gitstats.GlobalStats, opts gitstats.RenderOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create HTML report: %s", err)
+	}
+	opts.Format = "html"
+	if err := gitstats.Render(f, gb, opts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write HTML report: %s", err)
+	}
+	return nil
+}
+
+// writeXLSXReport writes the Excel workbook of gb to path.
+func writeXLSXReport(path string, gb gitstats.GlobalStats, opts gitstats.RenderOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create workbook: %s", err)
+	}
+	if err := gitstats.RenderXLSX(f, gb, opts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write workbook: %s", err)
+	}
+	return nil
+}
+
+// writeCharts writes every chart of gb as an SVG file named after it to dir, which is
+// created when missing.
+func writeCharts(dir string, gb gitstats.GlobalStats, opts gitstats.RenderOptions) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create charts directory: %s", err)
+	}
+	for _, chart := range gitstats.Charts {
+		f, err := os.Create(filepath.Join(dir, chart+".svg"))
+		if err != nil {
+			return fmt.Errorf("failed to create chart: %s", err)
+		}
+		if err := gitstats.RenderChart(f, gb, chart, opts); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write chart: %s", err)
+		}
+	}
+	return nil
+}
+
+// writeSQLite upserts the rows of the sql format of gb into the SQLite database at
+// path by running them through sqlite3, which creates the database when missing.
+func writeSQLite(path string, gb gitstats.GlobalStats) error {
+	var script bytes.Buffer
+	if err := gitstats.Render(&script, gb, gitstats.RenderOptions{Format: "sql"}); err != nil {
+		return err
+	}
+	cmd := exec.Command("sqlite3", "-bail", path)
+	cmd.Stdin = &script
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to write SQLite database: %s %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// createOutput creates the -o file, and its parent directories when missing.
+func createOutput(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %s", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %s", err)
+	}
+	return f, nil
+}
+
+// writeRepoReports writes each repository's report to <outDir>/<repo>.<ext>.
+func writeRepoReports(outDir string, repos map[string]*gitstats.GlobalStats, opts gitstats.RenderOptions) error {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %s", err)
+	}
+
+	ext := "txt"
+	switch opts.Format {
+	case "json":
+		ext = "json"
+	case "delimited":
+		ext = "dsv"
+	case "csv":
+		ext = "csv"
+	case "markdown":
+		ext = "md"
+	case "html":
+		ext = "html"
+	case "sql":
+		ext = "sql"
+	case "prometheus":
+		ext = "prom"
+	}
+
+	// Report files hold only the main report of their repository, without colors
+	fileOpts := gitstats.RenderOptions{
+		Format: opts.Format, Delimiter: opts.Delimiter, Header: opts.Header, MaxAuthors: opts.MaxAuthors,
+		PathStats: opts.PathStats, NetOnly: opts.NetOnly, Sort: opts.Sort, Reverse: opts.Reverse, Border: opts.Border,
+		Top: opts.Top, TopAll: opts.TopAll, Shares: opts.Shares, Template: opts.Template,
+	}
+	if opts.Format == "delimited" {
+		fileOpts.Rolling = opts.Rolling
+	}
+
+	for name, stats := range repos {
+		// Nested repos are flattened into names like team-a_repo
+		f, err := os.Create(filepath.Join(outDir, strings.ReplaceAll(name, "/", "_")+"."+ext))
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %s", err)
+		}
+		if err := gitstats.Render(f, *stats, fileOpts); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write report file: %s", err)
+		}
+	}
+	return nil
+}
+
+// splitList splits comma-separated flag values into their items. A /regexp/ value is
+// kept whole, as its commas belong to the expression.
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); len(value) >= 2 && value[0] == '/' && value[len(value)-1] == '/' {
+			items = append(items, value)
+			continue
+		}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// extensions returns the -ext values without their leading dots.
+func extensions(values []string) []string {
+	var exts []string
+	for _, ext := range splitList(values) {
+		exts = append(exts, strings.TrimPrefix(ext, "."))
+	}
+	return exts
+}
+
+// parseLocation parses the -tz value: Local, UTC, an offset such as +09:00 or -0500,
+// or an IANA time zone name. commit (or an empty value) is nil, each commit's own offset.
+func parseLocation(value string) (*time.Location, error) {
+	switch value {
+	case "", "commit":
+		return nil, nil
+	case "Local", "local":
+		return time.Local, nil
+	}
+	for _, layout := range []string{"-07:00", "-0700", "-07"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			_, offset := t.Zone()
+			return time.FixedZone(value, offset), nil
+		}
+	}
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone: %s", value)
+	}
+	return loc, nil
+}
+
+// terminalWidth returns the width advertised by $COLUMNS, defaulting to 80.
+func terminalWidth() int {
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
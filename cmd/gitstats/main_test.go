@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs gitstats instead of the tests when the test binary is started by
+// runGitstats, as main parses the flags of the process.
+func TestMain(m *testing.M) {
+	if os.Getenv("GITSTATS_TEST_MAIN") == "1" {
+		os.Args = append([]string{"gitstats"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runGitstats runs gitstats with args in dir, returning its stdout, stderr and exit
+// status. NO_COLOR is set unless the test sets it itself.
+func runGitstats(t *testing.T, dir string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(append([]string{"NO_COLOR=1"}, os.Environ()...), "GITSTATS_TEST_MAIN=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+// newRepo creates a git repository in dir with a commit of file by email at date
+// (RFC 3339).
+func newRepo(t *testing.T, dir, email, date, file, content string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=" + email, "-c", "user.email=" + email, "commit", "-q", "-m", "change"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = env
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+}
+
+func TestSQLiteNeedsSqlite3(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	_, stderr, code := runGitstats(t, dir, "-p", dir, "-sqlite", filepath.Join(dir, "stats.db"))
+	if code != 2 || !strings.Contains(stderr, "sqlite3 not found in PATH") {
+		t.Errorf("exit status %d, stderr %q, want sqlite3 reported missing", code, stderr)
+	}
+}
+
+func TestNoMergedWritesOtherOutputs(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	out := t.TempDir()
+	html := filepath.Join(out, "report.html")
+
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31",
+		"-no-cache", "-out-dir", out, "-no-merged", "-html", html)
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if strings.Contains(stdout, "alice@example.com") {
+		t.Errorf("merged report printed with -no-merged:\n%s", stdout)
+	}
+	for _, file := range []string{filepath.Join(out, "api.txt"), html} {
+		if data, err := os.ReadFile(file); err != nil || !bytes.Contains(data, []byte("alice@example.com")) {
+			t.Errorf("%s: %v, want a report of alice", file, err)
+		}
+	}
+}
+
+func TestDelimitedFormat(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\ntwo\n")
+
+	args := []string{"-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-format", "delimited", "-delimiter", ";"}
+	for header, want := range map[string]string{
+		"true":  "author;month;insertions;deletions;commits\nalice@example.com;(2024-03) March 2024;2;0;1\n",
+		"false": "alice@example.com;(2024-03) March 2024;2;0;1\n",
+	} {
+		stdout, stderr, code := runGitstats(t, base, append(args, "-header="+header)...)
+		if code != 0 {
+			t.Fatalf("exit status %d: %s", code, stderr)
+		}
+		if stdout != want {
+			t.Errorf("-header=%s: got %q, want %q", header, stdout, want)
+		}
+	}
+}
+
+func TestMaxCommitsLabelsSample(t *testing.T) {
+	base := t.TempDir()
+	repo := filepath.Join(base, "api")
+	newRepo(t, repo, "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	newRepo(t, repo, "alice@example.com", "2024-03-06T12:00:00Z", "a.md", "one\ntwo\n")
+	newRepo(t, repo, "alice@example.com", "2024-03-07T12:00:00Z", "a.md", "one\ntwo\nthree\n")
+	note := "Sample: at most 2 most recent commits per repository and period were examined\n"
+
+	args := []string{"-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-max-commits", "2"}
+	stdout, stderr, code := runGitstats(t, base, args...)
+	if code != 0 || !strings.HasPrefix(stdout, note) {
+		t.Errorf("exit status %d, stderr %q, want the text report to start with the sample note:\n%s", code, stderr, stdout)
+	}
+
+	// Machine-readable output keeps the note out of the way, on stderr
+	stdout, stderr, code = runGitstats(t, base, append(args, "-format", "csv", "-header=false")...)
+	if code != 0 || stderr != note {
+		t.Errorf("exit status %d, stderr %q, want the sample note", code, stderr)
+	}
+	if want := "alice@example.com,(2024-03) March 2024,2,0,2\n"; stdout != want {
+		t.Errorf("got %q, want the 2 latest commits: %q", stdout, want)
+	}
+}
+
+func TestDebugWritesDiagnosticsToStderr(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-debug")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	var processed string
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.Contains(line, `msg="repo processed"`) {
+			processed = line
+		}
+	}
+	for _, want := range []string{"level=DEBUG", "repo=" + filepath.Join(base, "api"), "branch=", "commits=1", "authors=1", "elapsed="} {
+		if !strings.Contains(processed, want) {
+			t.Errorf("missing %s in the repo diagnostics %q", want, processed)
+		}
+	}
+	if strings.Contains(stdout, "level=DEBUG") || !strings.Contains(stdout, "alice@example.com") {
+		t.Errorf("want only the report on stdout:\n%s", stdout)
+	}
+
+	_, stderr, _ = runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache")
+	if strings.Contains(stderr, "level=DEBUG") {
+		t.Errorf("diagnostics without -debug:\n%s", stderr)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	out := t.TempDir()
+	cpu, mem := filepath.Join(out, "cpu.pprof"), filepath.Join(out, "mem.pprof")
+
+	_, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache",
+		"-cpuprofile", cpu, "-memprofile", mem)
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	for _, file := range []string{cpu, mem} {
+		// pprof profiles are gzipped protocol buffers
+		if data, err := os.ReadFile(file); err != nil || !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+			t.Errorf("%s: %v, want a profile", file, err)
+		}
+	}
+
+	_, stderr, code = runGitstats(t, base, "-p", base, "-cpuprofile", filepath.Join(out, "missing", "cpu.pprof"))
+	if code != 2 || stderr == "" {
+		t.Errorf("unwritable profile: exit status %d, stderr %q, want a failure", code, stderr)
+	}
+}
+
+func TestUnicodeBorderFallsBackToASCII(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+
+	// runGitstats sets NO_COLOR, so box drawing is not relied on
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-border", "unicode-box")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	if strings.Contains(stdout, "│") || !strings.Contains(stdout, "| alice@example.com |") {
+		t.Errorf("want ascii borders:\n%s", stdout)
+	}
+}
+
+func TestJSONFormatKeepsStdoutPure(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\ntwo\n")
+
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-format", "json", "-v")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	var report struct {
+		Authors map[string]map[string]struct {
+			Insertions, Deletions, Commits int
+		} `json:"authors"`
+		TotalInsertions int `json:"totalInsertions"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatalf("stdout is not JSON: %s\n%s", err, stdout)
+	}
+	if got := report.Authors["alice@example.com"]["(2024-03) March 2024"]; got.Insertions != 2 || got.Commits != 1 || report.TotalInsertions != 2 {
+		t.Errorf("report = %+v, want alice's 2 insertions in March", report)
+	}
+	if !strings.Contains(stderr, " log --") {
+		t.Errorf("want the git commands of -v on stderr, got %q", stderr)
+	}
+}
+
+func TestMergesSkippedByDefault(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "api")
+	newRepo(t, dir, "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+dir,
+			"GIT_AUTHOR_DATE=2024-03-07T12:00:00Z", "GIT_COMMITTER_DATE=2024-03-07T12:00:00Z")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+	git("checkout", "-q", "-b", "feature")
+	for file, content := range map[string]string{"b.md": "two\nthree\n", "b.go": "package b\n"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", "-A")
+	git("-c", "user.name=bob", "-c", "user.email=bob@example.com", "commit", "-q", "-m", "feature")
+	git("checkout", "-q", "-")
+	git("-c", "user.name=merger", "-c", "user.email=merger@example.com", "merge", "-q", "--no-ff", "-m", "merge", "feature")
+
+	args := []string{"-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-format", "csv", "-header=false"}
+	for _, tt := range []struct {
+		flags []string
+		want  string
+	}{
+		{nil, "alice@example.com,(2024-03) March 2024,1,0,1\nbob@example.com,(2024-03) March 2024,3,0,1\n"},
+		{[]string{"-ext", "md"}, "alice@example.com,(2024-03) March 2024,1,0,1\nbob@example.com,(2024-03) March 2024,2,0,1\n"},
+		// git log shows no diff for merges, so a counted merge only adds a commit
+		{[]string{"-no-merges=false"}, "alice@example.com,(2024-03) March 2024,1,0,1\nbob@example.com,(2024-03) March 2024,3,0,1\nmerger@example.com,(2024-03) March 2024,0,0,1\n"},
+	} {
+		stdout, stderr, code := runGitstats(t, base, append(args, tt.flags...)...)
+		if code != 0 {
+			t.Fatalf("%v: exit status %d: %s", tt.flags, code, stderr)
+		}
+		if stdout != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.flags, stdout, tt.want)
+		}
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	report := filepath.Join(t.TempDir(), "reports", "march.txt")
+
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-o", report, "-v")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("alice@example.com")) || bytes.Contains(data, []byte(" log --")) {
+		t.Errorf("want the report without the git commands in %s:\n%s", report, data)
+	}
+	if stdout != "" || !strings.Contains(stderr, " log --") {
+		t.Errorf("stdout %q, stderr %q, want the git commands of -v on stderr only", stdout, stderr)
+	}
+}
+
+func TestQuietAndVerbose(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	args := []string{"-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache"}
+
+	if _, stderr, code := runGitstats(t, base, args...); code != 0 || stderr != "" {
+		t.Errorf("default run: exit status %d, stderr %q, want nothing on stderr", code, stderr)
+	}
+	if _, stderr, _ := runGitstats(t, base, append(args, "-auto-ext")...); !strings.Contains(stderr, "Auto-detected extensions") {
+		t.Errorf("stderr %q, want the progress of -auto-ext", stderr)
+	}
+	if _, stderr, _ := runGitstats(t, base, append(args, "-auto-ext", "-q")...); stderr != "" {
+		t.Errorf("-q: stderr %q, want no progress", stderr)
+	}
+	if _, stderr, code := runGitstats(t, base, "-p", filepath.Join(base, "missing"), "-q"); code != 2 || stderr == "" {
+		t.Errorf("-q: exit status %d, stderr %q, want the error reported", code, stderr)
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\ntwo\n")
+	html := filepath.Join(t.TempDir(), "report.html")
+
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-html", html)
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<!DOCTYPE html>", "<style>",
+		"<h2>(2024-03) March 2024</h2>",
+		`<tr><td>alice@example.com</td><td class="num">1</td><td class="num ins">2</td>`,
+		`<td class="bar"><div style="width: 100%"></div></td>`,
+		"<h2>Total lines by developer</h2>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("missing %s in %s:\n%s", want, html, page)
+		}
+	}
+	// The page is self-contained, to be attached to a mail
+	if strings.Contains(page, "http") {
+		t.Errorf("%s links to external resources", html)
+	}
+	if !strings.Contains(stdout, "Total lines by developer") {
+		t.Errorf("want the text report on stdout as well:\n%s", stdout)
+	}
+}
+
+func TestBucketGranularity(t *testing.T) {
+	base := t.TempDir()
+	repo := filepath.Join(base, "api")
+	newRepo(t, repo, "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	newRepo(t, repo, "alice@example.com", "2024-03-07T12:00:00Z", "a.md", "one\ntwo\n")
+	newRepo(t, repo, "alice@example.com", "2024-03-12T12:00:00Z", "a.md", "one\ntwo\nthree\n")
+
+	args := []string{"-p", base, "-a", "-since", "2024-03-04", "-until", "2024-03-17", "-no-cache", "-format", "csv", "-header=false"}
+	for bucket, want := range map[string]string{
+		"week": "alice@example.com,(2024-W10) 4 Mar 2024,2,0,2\n" +
+			"alice@example.com,(2024-W11) 11 Mar 2024,1,0,1\n",
+		"day": "alice@example.com,(2024-03-05) Tuesday,1,0,1\n" +
+			"alice@example.com,(2024-03-07) Thursday,1,0,1\n" +
+			"alice@example.com,(2024-03-12) Tuesday,1,0,1\n",
+	} {
+		stdout, stderr, code := runGitstats(t, base, append(args, "-bucket", bucket)...)
+		if code != 0 {
+			t.Fatalf("-bucket %s: exit status %d: %s", bucket, code, stderr)
+		}
+		if stdout != want {
+			t.Errorf("-bucket %s: got %q, want %q", bucket, stdout, want)
+		}
+	}
+}
+
+func TestRecursiveDiscovery(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "org", "team-a", "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	newRepo(t, filepath.Join(base, "org", "team-b", "archive", "old"), "bob@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	newRepo(t, filepath.Join(base, "org", "team-c", "deep", "er", "web"), "carol@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+
+	args := []string{"-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-format", "csv", "-header=false"}
+	for _, tt := range []struct {
+		flags []string
+		want  string
+	}{
+		// The default depth of 3 only reaches team-a/api
+		{nil, "alice@example.com"},
+		{[]string{"-max-depth", "4"}, "alice@example.com bob@example.com"},
+		{[]string{"-max-depth", "-1"}, "alice@example.com bob@example.com carol@example.com"},
+		{[]string{"-max-depth", "-1", "-skip-dir", "archive"}, "alice@example.com carol@example.com"},
+		{[]string{"-depth", "2"}, ""},
+	} {
+		stdout, stderr, code := runGitstats(t, base, append(args, tt.flags...)...)
+		if code != 0 {
+			t.Fatalf("%v: exit status %d: %s", tt.flags, code, stderr)
+		}
+		var authors []string
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+			if author, _, ok := strings.Cut(line, ","); ok {
+				authors = append(authors, author)
+			}
+		}
+		if got := strings.Join(authors, " "); got != tt.want {
+			t.Errorf("%v: found %q, want %q", tt.flags, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownFormat(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\ntwo\n")
+
+	// Markdown is pasted as it is, so it never carries colors
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-format", "markdown", "-color", "always")
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	want := "\n### (2024-03) March 2024\n\n" +
+		"| Author            | Commits | Insertions |  Ins % | Deletions | Net |  Net % |\n" +
+		"| ----------------- | ------: | ---------: | -----: | --------: | --: | -----: |\n" +
+		"| alice@example.com |       1 |          2 | 100.0% |         0 |  +2 | 100.0% |\n"
+	if !strings.HasPrefix(stdout, want) || !strings.Contains(stdout, "### Total lines by developer\n") || strings.Contains(stdout, "\033[") {
+		t.Errorf("got:\n%s\nwant it to start with:\n%s", stdout, want)
+	}
+}
+
+func TestVeryVerbose(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	args := []string{"-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache"}
+
+	// -v logs the git commands, -vv the -debug diagnostics as well
+	for level, want := range map[string][2]bool{"-v": {true, false}, "-vv": {true, true}} {
+		stdout, stderr, code := runGitstats(t, base, append(args, level)...)
+		if code != 0 {
+			t.Fatalf("%s: exit status %d: %s", level, code, stderr)
+		}
+		if got := [2]bool{strings.Contains(stderr, " log --"), strings.Contains(stderr, `msg="repo processed"`)}; got != want {
+			t.Errorf("%s: commands and diagnostics logged %v, want %v:\n%s", level, got, want, stderr)
+		}
+		// Without a terminal there is no progress bar, and the report stays on stdout
+		if strings.Contains(stderr, "Scanning repositories") || !strings.Contains(stdout, "alice@example.com") {
+			t.Errorf("%s: stdout %q, stderr %q", level, stdout, stderr)
+		}
+	}
+}
+
+func TestOutputFileHasNoColors(t *testing.T) {
+	base := t.TempDir()
+	newRepo(t, filepath.Join(base, "api"), "alice@example.com", "2024-03-05T12:00:00Z", "a.md", "one\n")
+	report := filepath.Join(t.TempDir(), "2024", "03", "report.txt")
+
+	// Colors are only left out because the report goes to a file
+	t.Setenv("NO_COLOR", "")
+	stdout, stderr, code := runGitstats(t, base, "-p", base, "-a", "-since", "2024-03-01", "-until", "2024-03-31", "-no-cache", "-output", report)
+	if code != 0 {
+		t.Fatalf("exit status %d: %s", code, stderr)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("\033[")) || !bytes.Contains(data, []byte("Total lines by developer")) {
+		t.Errorf("want the report without colors in %s:\n%q", report, data)
+	}
+	if stdout != "" {
+		t.Errorf("stdout %q, want the report in the file only", stdout)
+	}
+}
+
+func TestCompareNeedsAPeriod(t *testing.T) {
+	dir := t.TempDir()
+	newRepo(t, dir, "alice@example.com", "2024-03-05T10:00:00Z", "main.go", "package main\n")
+	_, stderr, code := runGitstats(t, dir, "-p", dir, "-m", "0", "-compare", "previous", "-no-cache")
+	if code != 2 || !strings.Contains(stderr, "-compare needs a window to compare") {
+		t.Errorf("exit status %d, stderr %q, want -m 0 rejected", code, stderr)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// printOwnership writes the ownership report of the repositories of options to output,
+// or to stdout when empty.
+func printOwnership(options gitstats.Options, output, format, border, colorMode string, quiet bool) error {
+	ownership, err := gitstats.NewCollector(options).Ownership()
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		for _, warning := range ownership.Warnings {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	}
+
+	outFile := os.Stdout
+	if output != "" {
+		if outFile, err = createOutput(output); err != nil {
+			return err
+		}
+		defer outFile.Close()
+	}
+	if border == "unicode-box" && (!unicodeTerminal() || colorDisabled(colorMode)) {
+		border = "ascii"
+	}
+	opts := gitstats.RenderOptions{Format: format, Border: border, Color: colorOutput(outFile, colorMode)}
+	return gitstats.RenderOwnership(outFile, ownership, opts)
+}
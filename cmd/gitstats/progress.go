@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// progressBarWidth is the number of cells of the progress bar.
+const progressBarWidth = 30
+
+// progressBar returns an Options.OnProgress drawing the repositories scanned so far as
+// a bar on one terminal line of w, cleared once all are done. A single repository
+// draws nothing.
+func progressBar(w io.Writer) func(done, total int) {
+	return func(done, total int) {
+		if total < 2 {
+			return
+		}
+		filled := done * progressBarWidth / total
+		fmt.Fprintf(w, "\rScanning repositories [%s%s] %d/%d", strings.Repeat("=", filled),
+			strings.Repeat(" ", progressBarWidth-filled), done, total)
+		if done == total {
+			// Clear the line for the warnings and the report
+			fmt.Fprint(w, "\r\033[K")
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProgressBar(t *testing.T) {
+	var out strings.Builder
+	bar := progressBar(&out)
+	for done := 0; done <= 3; done++ {
+		bar(done, 3)
+	}
+	want := "\rScanning repositories [                              ] 0/3" +
+		"\rScanning repositories [==========                    ] 1/3" +
+		"\rScanning repositories [====================          ] 2/3" +
+		"\rScanning repositories [==============================] 3/3\r\033[K"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	bar(1, 1)
+	if out.String() != "" {
+		t.Errorf("single repository: got %q, want no bar", out.String())
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// useGitHub points options at the GitHub repositories named owner/name and those of
+// the organizations orgs, read through the API with token or $GITHUB_TOKEN.
+func useGitHub(options *gitstats.Options, repos, orgs []string, token string) error {
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("-github and -github-org need a token: set -github-token or GITHUB_TOKEN")
+	}
+	github := gitstats.GitHubGit{Token: token}
+	for _, org := range orgs {
+		orgRepos, err := github.OrgRepos(org)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, orgRepos...)
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("no GitHub repositories to analyze")
+	}
+	return remote(options, github, repos)
+}
+
+// useGitLab points options at the GitLab projects named by their path and those of the
+// groups, with their subgroups, read through the API of the instance at url with token
+// or $GITLAB_TOKEN.
+func useGitLab(options *gitstats.Options, projects, groups []string, token, url string) error {
+	if token == "" {
+		token = os.Getenv("GITLAB_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("-gitlab and -gitlab-group need a token: set -gitlab-token or GITLAB_TOKEN")
+	}
+	gitlab := gitstats.GitLabGit{Token: token, URL: url}
+	for _, group := range groups {
+		groupProjects, err := gitlab.GroupProjects(group)
+		if err != nil {
+			return err
+		}
+		projects = append(projects, groupProjects...)
+	}
+	if len(projects) == 0 {
+		return fmt.Errorf("no GitLab projects to analyze")
+	}
+	return remote(options, gitlab, projects)
+}
+
+// remote selects repos read by git in options, dropping the options that need a clone.
+// File selections fail, as the API only reports the totals of each commit.
+func remote(options *gitstats.Options, git gitstats.Git, repos []string) error {
+	if len(options.Extensions) > 0 || options.AutoExt || len(options.Paths) > 0 || len(options.Includes) > 0 || len(options.Excludes) > 0 {
+		return fmt.Errorf("-ext, -auto-ext, -path, -path-include, -path-exclude and -exclude are not available with -github and -gitlab: the API only reports the totals of each commit")
+	}
+	options.Git = git
+	options.Repos = repos
+	options.Path = "."
+	options.All = false
+	// The cache checks the refs of the clone, and the generated files are only known
+	// from the paths of a clone
+	options.CacheDir = ""
+	options.ExcludeGenerated = false
+	return nil
+}
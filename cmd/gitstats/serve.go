@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// scanFunc collects the stats of the configured repositories, with the periods they
+// were collected for.
+type scanFunc func() (gitstats.GlobalStats, []gitstats.Period, error)
+
+// server holds the results of the latest scan of serve.
+type server struct {
+	mu      sync.Mutex
+	gb      *gitstats.GlobalStats
+	periods []gitstats.Period
+	metrics []byte
+}
+
+// serve serves the dashboard at /, the stats of the latest scan as JSON at /api/stats
+// and as Prometheus metrics at /metrics on addr, scanning again every interval. A failed
+// scan is logged and the results of the previous one are kept; until the first scan
+// completes the endpoints answer 503.
+func serve(addr string, interval time.Duration, scan scanFunc, progress *log.Logger) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid -interval: %s", interval)
+	}
+
+	s := &server{}
+	go func() {
+		for {
+			if err := s.update(scan); err != nil && progress != nil {
+				progress.Printf("Scan failed: %s", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleDashboard)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	if progress != nil {
+		progress.Printf("Serving the dashboard on %s", addr)
+	}
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		return fmt.Errorf("failed to serve: %s", err)
+	}
+	return nil
+}
+
+// update runs scan and replaces the results on success.
+func (s *server) update(scan scanFunc) error {
+	gb, periods, err := scan()
+	if err != nil {
+		return err
+	}
+	var metrics bytes.Buffer
+	if err := gitstats.Render(&metrics, gb, gitstats.RenderOptions{Format: "prometheus"}); err != nil {
+		return err
+	}
+	fmt.Fprintf(&metrics, "# HELP gitstats_last_scan_timestamp_seconds Time the metrics were computed.\n"+
+		"# TYPE gitstats_last_scan_timestamp_seconds gauge\ngitstats_last_scan_timestamp_seconds %d\n", time.Now().Unix())
+
+	s.mu.Lock()
+	s.gb, s.periods, s.metrics = &gb, periods, metrics.Bytes()
+	s.mu.Unlock()
+	return nil
+}
+
+// latest returns the results of the latest scan, with a nil gb before the first one.
+func (s *server) latest() (*gitstats.GlobalStats, []gitstats.Period, []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gb, s.periods, s.metrics
+}
+
+func (s *server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, dashboardHTML)
+}
+
+// handleStats writes the -format=json report of the latest scan, keeping the periods
+// overlapping the optional since and until dates of the query.
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request) {
+	gb, periods, _ := s.latest()
+	if gb == nil {
+		http.Error(w, "first scan in progress", http.StatusServiceUnavailable)
+		return
+	}
+
+	now := time.Now()
+	var since, until time.Time
+	var err error
+	if value := r.URL.Query().Get("since"); value != "" {
+		if since, err = gitstats.ParseDate(value, now); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("until"); value != "" {
+		if until, err = gitstats.ParseDate(value, now); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if !since.IsZero() || !until.IsZero() {
+		labels := make(map[string]bool)
+		for _, p := range periods {
+			if (since.IsZero() || !p.Until.Before(since)) && (until.IsZero() || !p.Since.After(until)) {
+				labels[p.Label] = true
+			}
+		}
+		gb = gitstats.KeepPeriods(gb, labels)
+	}
+
+	var buf bytes.Buffer
+	if err := gitstats.Render(&buf, *gb, gitstats.RenderOptions{Format: "json"}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	_, _, metrics := s.latest()
+	if metrics == nil {
+		http.Error(w, "first scan in progress", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(metrics)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// defaultSlackTemplate is the message of -slack-webhook without -slack-template, in
+// Slack's mrkdwn: the totals of each period and its five most active authors.
+const defaultSlackTemplate = `*Git statistics*
+{{range .Periods}}
+*{{.Label}}*: {{.Commits}} commits, +{{.Insertions}} / -{{.Deletions}} ({{.Net}} net)
+{{range $i, $a := .Authors}}{{if lt $i 5}}{{add $i 1}}. {{if .Name}}{{.Name}}{{else}}{{.Author}}{{end}}: {{.Commits}} commits, {{.Net}} net
+{{end}}{{end}}{{if gt (len .Authors) 5}}… and {{sub (len .Authors) 5}} others
+{{end}}{{end}}`
+
+// slackMessage executes the template in the file templatePath, or the default
+// template when empty, on the summary of gb.
+func slackMessage(templatePath string, gb gitstats.GlobalStats, opts gitstats.RenderOptions) (string, error) {
+	text := defaultSlackTemplate
+	if templatePath != "" {
+		data, err := os.ReadFile(templatePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read Slack template: %s", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("slack").Funcs(gitstats.TemplateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Slack template: %s", err)
+	}
+	var message strings.Builder
+	if err := tmpl.Execute(&message, gitstats.NewSummary(gb, opts.Sort, opts.Reverse)); err != nil {
+		return "", fmt.Errorf("failed to execute Slack template: %s", err)
+	}
+	return message.String(), nil
+}
+
+// postSlack posts message to the Slack incoming webhook at url.
+func postSlack(url, message string) error {
+	payload, err := json.Marshal(map[string]string{"text": message})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to post to Slack: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to post to Slack: %s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// readTeams reads the team file at path. It uses the YAML subset of the config file,
+// with one team per key and the emails, globs or /regexps/ of its members as the value:
+//
+//	Backend: ["*@backend.corp.com", alice@corp.com]
+//	Frontend:
+//	  - '/^(carol|dave)@corp\.com$/'
+//
+// An author matching several teams belongs to the first.
+func readTeams(path string) ([]gitstats.Team, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read teams: %s", err)
+	}
+	settings, err := parseConfig(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	var teams []gitstats.Team
+	for _, setting := range settings {
+		if len(setting.values) == 0 {
+			return nil, fmt.Errorf("%s:%d: team %s has no members", path, setting.line, setting.name)
+		}
+		teams = append(teams, gitstats.Team{Name: setting.name, Members: setting.values})
+	}
+	return teams, nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// tuiSortColumns are the -sort columns the s key cycles through.
+var tuiSortColumns = []string{"net", "insertions", "deletions", "commits", "author"}
+
+// tuiState is what the -tui screen shows: a month (or all of them) of the leaderboard,
+// or the breakdown of one author.
+type tuiState struct {
+	gb        gitstats.GlobalStats
+	sort      string
+	reverse   bool
+	summary   gitstats.Summary
+	period    int // Index in summary.Periods, len(summary.Periods) for all months
+	selected  int // Row of the leaderboard
+	author    string
+	filter    string
+	filtering bool // Typing the filter
+}
+
+// runTUI browses gb interactively on the terminal until q is pressed. The terminal is
+// switched to raw mode with stty and restored on return.
+func runTUI(gb gitstats.GlobalStats, sortColumn string, reverse bool) error {
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("failed to read the terminal settings: %s", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return fmt.Errorf("failed to switch the terminal to raw mode: %s", err)
+	}
+	// Hide the cursor on the alternate screen, and bring both back on exit
+	fmt.Print("\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		stty(strings.TrimSpace(saved))
+	}()
+
+	s := &tuiState{gb: gb, sort: sortColumn, reverse: reverse}
+	s.summarize()
+	s.period = len(s.summary.Periods)
+	key := make([]byte, 8)
+	for {
+		s.draw()
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return err
+		}
+		for _, k := range splitKeys(string(key[:n])) {
+			if !s.handle(k) {
+				return nil
+			}
+		}
+	}
+}
+
+// splitKeys splits what one read of the terminal returned into keys, as typing fast or
+// pasting delivers several at once: arrow keys are ESC [ and a letter, others a byte.
+func splitKeys(input string) []string {
+	var keys []string
+	for input != "" {
+		n := 1
+		if strings.HasPrefix(input, "\033[") && len(input) >= 3 {
+			n = 3
+		}
+		keys = append(keys, input[:n])
+		input = input[n:]
+	}
+	return keys
+}
+
+// stty runs stty on the terminal of stdin and returns its output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+func (s *tuiState) summarize() {
+	s.summary = gitstats.NewSummary(s.gb, s.sort, s.reverse)
+}
+
+// current returns the selected period of the summary.
+func (s *tuiState) current() gitstats.SummaryPeriod {
+	if s.period < len(s.summary.Periods) {
+		return s.summary.Periods[s.period]
+	}
+	return s.summary.Total
+}
+
+// rows returns the authors of the selected period matching the filter.
+func (s *tuiState) rows() []gitstats.SummaryAuthor {
+	var rows []gitstats.SummaryAuthor
+	filter := strings.ToLower(s.filter)
+	for _, author := range s.current().Authors {
+		if filter == "" || strings.Contains(strings.ToLower(author.Author), filter) || strings.Contains(strings.ToLower(author.Name), filter) {
+			rows = append(rows, author)
+		}
+	}
+	return rows
+}
+
+// handle applies key and reports whether to go on.
+func (s *tuiState) handle(key string) bool {
+	if s.filtering {
+		switch key {
+		case "\r", "\n":
+			s.filtering = false
+		case "\033":
+			s.filtering, s.filter = false, ""
+		case "\x7f", "\b":
+			if s.filter != "" {
+				s.filter = s.filter[:len(s.filter)-1]
+			}
+		default:
+			if len(key) == 1 && key[0] >= ' ' {
+				s.filter += key
+			}
+		}
+		s.selected = 0
+		return true
+	}
+
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\033":
+		if s.author == "" {
+			s.filter = ""
+		}
+		s.author = ""
+	case "\x7f", "\b":
+		s.author = ""
+	case "\033[D", "h":
+		s.period = (s.period + len(s.summary.Periods)) % (len(s.summary.Periods) + 1)
+		s.selected = 0
+	case "\033[C", "l":
+		s.period = (s.period + 1) % (len(s.summary.Periods) + 1)
+		s.selected = 0
+	case "\033[A", "k":
+		s.selected = max(s.selected-1, 0)
+	case "\033[B", "j":
+		s.selected = min(s.selected+1, max(len(s.rows())-1, 0))
+	case "\r", "\n":
+		if rows := s.rows(); s.author == "" && s.selected < len(rows) {
+			s.author = rows[s.selected].Author
+		}
+	case "s":
+		for i, column := range tuiSortColumns {
+			if column == s.sort || s.sort == "" && column == "net" {
+				s.sort = tuiSortColumns[(i+1)%len(tuiSortColumns)]
+				break
+			}
+		}
+		s.summarize()
+	case "r":
+		s.reverse = !s.reverse
+		s.summarize()
+	case "/":
+		s.filtering, s.author = true, ""
+	}
+	return true
+}
+
+// draw paints the screen; lines end in \r\n as the terminal is in raw mode.
+func (s *tuiState) draw() {
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, format+"\033[K\r\n", args...)
+	}
+	b.WriteString("\033[H")
+	period := s.current()
+	label := period.Label
+	if s.period == len(s.summary.Periods) {
+		label = "All months"
+	}
+	sortColumn := s.sort
+	if sortColumn == "" {
+		sortColumn = "net"
+	}
+	if s.reverse {
+		sortColumn += " (reversed)"
+	}
+	line("\033[1m%s\033[0m   ←/→ month  ↑/↓ select  enter details  s sort: %s  r reverse  / filter  q quit", label, sortColumn)
+	if s.filtering || s.filter != "" {
+		cursor := ""
+		if s.filtering {
+			cursor = "_"
+		}
+		line("Filter: %s%s", s.filter, cursor)
+	}
+	line("")
+
+	if s.author != "" {
+		s.drawAuthor(line, period)
+	} else {
+		line("\033[94m  %-4s %-40s %8s %10s %10s %8s\033[0m", "#", "Author", "Commits", "Insertions", "Deletions", "Net")
+		for i, row := range s.rows() {
+			marker := "  "
+			if i == s.selected {
+				marker = "\033[7m> "
+			}
+			line("%s%-4d %-40s %8d %10d %10d %+8d\033[0m", marker, i+1, row.Author, row.Commits, row.Insertions, row.Deletions, row.Net)
+		}
+		line("  %-4s %-40s %8d %10d %10d %+8d", "", "Total", period.Commits, period.Insertions, period.Deletions, period.Net)
+	}
+	b.WriteString("\033[J")
+	fmt.Print(b.String())
+}
+
+// drawAuthor paints the breakdown of the selected author per repository in the period,
+// and per file type over all months.
+func (s *tuiState) drawAuthor(line func(string, ...any), period gitstats.SummaryPeriod) {
+	name := s.author
+	if display := s.gb.Names[s.author]; display != "" {
+		name = display + " <" + s.author + ">"
+	}
+	line("\033[1m%s\033[0m   esc back", name)
+	line("")
+
+	months := func(stats map[string]gitstats.ChangesStats) gitstats.ChangesStats {
+		var total gitstats.ChangesStats
+		for month, changes := range stats {
+			if s.period == len(s.summary.Periods) || month == period.Label {
+				total.Insertions += changes.Insertions
+				total.Deletions += changes.Deletions
+				total.Commits += changes.Commits
+			}
+		}
+		return total
+	}
+	table := func(title string, stats map[string]gitstats.ChangesStats) {
+		line("\033[94m%-40s %8s %10s %10s %8s\033[0m", title, "Commits", "Insertions", "Deletions", "Net")
+		var keys []string
+		for key, changes := range stats {
+			if changes.Commits > 0 || changes.Insertions > 0 || changes.Deletions > 0 {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			ni := stats[keys[i]].Insertions - stats[keys[i]].Deletions
+			nj := stats[keys[j]].Insertions - stats[keys[j]].Deletions
+			if ni != nj {
+				return ni > nj
+			}
+			return keys[i] < keys[j]
+		})
+		for _, key := range keys {
+			changes := stats[key]
+			line("%-40s %8d %10d %10d %+8d", key, changes.Commits, changes.Insertions, changes.Deletions, changes.Insertions-changes.Deletions)
+		}
+		if len(keys) == 0 {
+			line("(none)")
+		}
+		line("")
+	}
+
+	repos := make(map[string]gitstats.ChangesStats)
+	for repo, stats := range s.gb.Repos {
+		repos[repo] = months(stats.Stats[s.author])
+	}
+	table("Repository", repos)
+	table("File type (all months)", s.gb.Languages[s.author])
+}
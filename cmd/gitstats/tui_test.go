@@ -0,0 +1,94 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+func TestSplitKeys(t *testing.T) {
+	if got, want := splitKeys("j\033[Bq\033"), []string{"j", "\033[B", "q", "\033"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTUIKeys(t *testing.T) {
+	gb := gitstats.NewGlobalStats()
+	gb.Add(gitstats.LogResult{Stats: map[string]gitstats.ChangesStats{
+		"alice@example.com": {Insertions: 10, Commits: 1},
+		"bob@example.com":   {Insertions: 2, Deletions: 8, Commits: 2},
+	}}, "(2024-02) February 2024")
+	gb.Add(gitstats.LogResult{Stats: map[string]gitstats.ChangesStats{
+		"bob@example.com": {Insertions: 30, Commits: 1},
+	}}, "(2024-03) March 2024")
+	s := &tuiState{gb: *gb}
+	s.summarize()
+	s.period = len(s.summary.Periods)
+
+	authors := func() []string {
+		var authors []string
+		for _, row := range s.rows() {
+			authors = append(authors, row.Author)
+		}
+		return authors
+	}
+	press := func(keys string) {
+		t.Helper()
+		for _, key := range splitKeys(keys) {
+			if !s.handle(key) {
+				t.Fatalf("%q quit", key)
+			}
+		}
+	}
+
+	// All months, by net lines: bob +24, alice +10
+	if got := authors(); !reflect.DeepEqual(got, []string{"bob@example.com", "alice@example.com"}) {
+		t.Errorf("all months = %v", got)
+	}
+	// Right wraps around to the first month
+	press("\033[C")
+	if s.current().Label != "(2024-02) February 2024" || !reflect.DeepEqual(authors(), []string{"alice@example.com", "bob@example.com"}) {
+		t.Errorf("first month = %s %v", s.current().Label, authors())
+	}
+	press("\033[D\033[D")
+	if s.current().Label != "(2024-03) March 2024" {
+		t.Errorf("left twice = %s, want March", s.current().Label)
+	}
+
+	// Back to all months, where the selection stops at the last row
+	press("l\033[B\033[B\r")
+	if s.author != "alice@example.com" {
+		t.Errorf("selected %q, want alice in the last row", s.author)
+	}
+	press("\033")
+	if s.author != "" {
+		t.Errorf("esc kept %q open", s.author)
+	}
+
+	press("/ALI\r")
+	if got := authors(); s.filtering || !reflect.DeepEqual(got, []string{"alice@example.com"}) {
+		t.Errorf("filter %q = %v, want alice", s.filter, got)
+	}
+	press("\033")
+	if s.filter != "" {
+		t.Errorf("esc kept the filter %q", s.filter)
+	}
+
+	press("s")
+	if s.sort != "insertions" {
+		t.Errorf("sort = %q, want insertions after net", s.sort)
+	}
+	press("ssss")
+	if s.sort != "net" {
+		t.Errorf("sort = %q, want back to net", s.sort)
+	}
+	press("r")
+	if got := authors(); !reflect.DeepEqual(got, []string{"alice@example.com", "bob@example.com"}) {
+		t.Errorf("reversed = %v, want the least net lines first", got)
+	}
+
+	if s.handle("q") {
+		t.Error("q did not quit")
+	}
+}
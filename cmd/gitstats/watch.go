@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression: minute, hour, day of month,
+// month and day of week (0 or 7 is Sunday).
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	anyDay, anyWeekday                     bool // The day fields start with "*", like */2
+}
+
+// cronMacros are the shorthands of common schedules.
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// parseCron parses a cron expression such as "0 7 * * 1-5" or "*/30 * * * *", or one
+// of cronMacros. Fields are "*", numbers, ranges and comma-separated lists of them,
+// each with an optional /step.
+func parseCron(expr string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.TrimSpace(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected minute, hour, day of month, month and day of week", expr)
+	}
+	s := &cronSchedule{anyDay: strings.HasPrefix(fields[2], "*"), anyWeekday: strings.HasPrefix(fields[4], "*")}
+	var err error
+	for i, field := range []struct {
+		set      *map[int]bool
+		min, max int
+	}{{&s.minutes, 0, 59}, {&s.hours, 0, 23}, {&s.days, 1, 31}, {&s.months, 1, 12}, {&s.weekdays, 0, 7}} {
+		if *field.set, err = parseCronField(fields[i], field.min, field.max); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %s", expr, err)
+		}
+	}
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+	return s, nil
+}
+
+// parseCronField returns the values of one cron field between min and max.
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		part, stepStr, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+		low, high := min, max
+		if part != "*" {
+			lowStr, highStr, isRange := strings.Cut(part, "-")
+			var err error
+			if low, err = strconv.Atoi(lowStr); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highStr); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if stepped {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// next returns the first minute after t matching the schedule. As in cron, a day must
+// match both day fields when one of them starts with "*", and either of them otherwise,
+// so "0 0 1,15 * 1" runs on the 1st, the 15th and every Monday.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Every schedule matches within a few years, e.g. February 29
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); t = t.Add(time.Minute) {
+		if !s.months[int(t.Month())] || !s.hours[t.Hour()] || !s.minutes[t.Minute()] {
+			continue
+		}
+		day, weekday := s.days[t.Day()], s.weekdays[int(t.Weekday())]
+		if s.anyDay || s.anyWeekday {
+			if day && weekday {
+				return t
+			}
+		} else if day || weekday {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// watch runs gitstats with args, the options of the report and its outputs, on
+// schedule, a cron expression, or else right away and then every interval. Each run is
+// a process of its own, so a failed run is logged and the next one still happens.
+func watch(schedule string, interval time.Duration, args []string, progress *log.Logger) error {
+	var cron *cronSchedule
+	if schedule != "" {
+		var err error
+		if cron, err = parseCron(schedule); err != nil {
+			return err
+		}
+	} else if interval <= 0 {
+		return fmt.Errorf("invalid -interval: %s", interval)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the gitstats binary: %s", err)
+	}
+
+	next := time.Now()
+	for {
+		if cron != nil {
+			if next = cron.next(time.Now()); next.IsZero() {
+				return fmt.Errorf("cron expression %q never matches", schedule)
+			}
+		}
+		if progress != nil {
+			progress.Printf("Next report at %s", next.Format("2006-01-02 15:04:05"))
+		}
+		time.Sleep(time.Until(next))
+
+		started := time.Now()
+		cmd := exec.Command(self, args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil && progress != nil {
+			progress.Printf("Report failed: %s", err)
+		} else if progress != nil {
+			progress.Printf("Report done in %s", time.Since(started).Round(time.Second))
+		}
+		next = started.Add(interval)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		want     []int
+	}{
+		{"*", 1, 5, []int{1, 2, 3, 4, 5}},
+		{"7", 0, 59, []int{7}},
+		{"1-4", 0, 59, []int{1, 2, 3, 4}},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}},
+		{"10-20/5", 0, 59, []int{10, 15, 20}},
+		{"50/3", 0, 59, []int{50, 53, 56, 59}},
+		{"1,3,5-6", 0, 7, []int{1, 3, 5, 6}},
+		{"0,*/20", 0, 59, []int{0, 20, 40}},
+	}
+	for _, tt := range tests {
+		got, err := parseCronField(tt.field, tt.min, tt.max)
+		if err != nil {
+			t.Errorf("%q: %s", tt.field, err)
+			continue
+		}
+		var values []int
+		for v := range got {
+			values = append(values, v)
+		}
+		sort.Ints(values)
+		if len(values) != len(tt.want) {
+			t.Errorf("%q = %v, want %v", tt.field, values, tt.want)
+			continue
+		}
+		for i := range values {
+			if values[i] != tt.want[i] {
+				t.Errorf("%q = %v, want %v", tt.field, values, tt.want)
+				break
+			}
+		}
+	}
+
+	for _, field := range []string{"", "x", "5-1", "60", "*/0", "1-", "*/x"} {
+		if _, err := parseCronField(field, 0, 59); err == nil {
+			t.Errorf("%q accepted, want an error", field)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "@yearly"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("%q accepted, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2024-03-15 is a Friday
+	from := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name, expr string
+		from, want time.Time
+	}{
+		{"every 30 minutes", "*/30 * * * *", from, time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"the next minute, not the current one", "* * * * *", from.Add(20 * time.Second), from.Add(time.Minute)},
+		{"weekday range skips the weekend", "0 7 * * 1-5", from, time.Date(2024, 3, 18, 7, 0, 0, 0, time.UTC)},
+		{"hour list", "0 9,17 * * *", from, time.Date(2024, 3, 15, 17, 0, 0, 0, time.UTC)},
+		{"7 is Sunday", "0 0 * * 7", from, time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"macro", "@monthly", from, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		// Either restricted day field is enough: Monday the 18th comes before the 1st
+		{"day OR rule", "0 0 1 * 1", from, time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"day OR rule, day of month first", "0 0 16 * 1", from, time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		// A day field starting with * restricts the days together with the other one:
+		// the odd days that are Mondays, and the 18ths on Sunday, Tuesday, Thursday or Saturday
+		{"stepped day of month and day of week", "0 0 */2 * 1", from, time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)},
+		{"stepped day of week and day of month", "0 0 18 * */2", from, time.Date(2024, 4, 18, 0, 0, 0, 0, time.UTC)},
+		{"stepped day of week alone", "0 0 * * */3", from, time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		// Months without a 31st are skipped
+		{"month end rollover", "0 0 31 * *", time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"year end rollover", "59 23 31 12 *", time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("next(%s) of %q = %s, want %s", tt.from.Format(time.DateTime), tt.expr, got.Format(time.DateTime), tt.want.Format(time.DateTime))
+			}
+		})
+	}
+
+	s, err := parseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.next(from); !got.IsZero() {
+		t.Errorf("February 30 = %s, want no match", got)
+	}
+}
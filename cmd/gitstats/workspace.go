@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// readManifest returns the clone URLs listed in the -repos file at path, one per line;
+// blank lines and # comments are skipped.
+func readManifest(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository list: %s", err)
+	}
+	defer f.Close()
+
+	var urls []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line != "" {
+			urls = append(urls, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repository list: %s", err)
+	}
+	return urls, nil
+}
+
+// cloneDir returns the directory of the clone of url below the workspace: the path of
+// the URL without its host and .git suffix, e.g. acme/api for
+// https://github.com/acme/api.git and git@github.com:acme/api.git.
+func cloneDir(url string) string {
+	path := strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+	if _, rest, ok := strings.Cut(path, "://"); ok {
+		// scheme://[user@]host[:port]/path
+		_, path, _ = strings.Cut(rest, "/")
+	} else if i := strings.Index(path, ":"); i >= 0 && !strings.Contains(path[:i], "/") {
+		// [user@]host:path
+		path = path[i+1:]
+	}
+	return filepath.FromSlash(strings.Trim(path, "/"))
+}
+
+// syncWorkspace clones the repositories of urls missing from workspace and updates
+// the others, jobs at a time, and returns their directories in the order of urls.
+// Repositories that fail to clone are left out with a warning; those failing to update
+// are analyzed as they are.
+func syncWorkspace(workspace string, urls []string, jobs int, progress *log.Logger) (dirs []string, warnings []string) {
+	if progress == nil {
+		progress = log.New(io.Discard, "", 0)
+	}
+	results := make([]error, len(urls))
+	all := make([]string, len(urls))
+	present := make([]bool, len(urls))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(jobs, 1), max(len(urls), 1)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				dir := filepath.Join(workspace, cloneDir(urls[i]))
+				all[i] = dir
+				if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+					progress.Printf("Updating %s", dir)
+					results[i] = runGit("-C", dir, "pull", "--ff-only", "--prune", "--quiet")
+					present[i] = true
+					continue
+				}
+				progress.Printf("Cloning %s into %s", urls[i], dir)
+				if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+					results[i] = fmt.Errorf("failed to create directory: %s", err)
+					continue
+				}
+				results[i] = runGit("clone", "--quiet", urls[i], dir)
+				present[i] = results[i] == nil
+			}
+		}()
+	}
+	for i := range urls {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, url := range urls {
+		if results[i] != nil {
+			action := "clone"
+			if present[i] {
+				action = "update"
+			}
+			warnings = append(warnings, fmt.Sprintf("Failed to %s %s: %s", action, url, results[i]))
+		}
+		if present[i] {
+			dirs = append(dirs, all[i])
+		}
+	}
+	return dirs, warnings
+}
+
+// runGit runs a git command that may reach a remote, without prompting for credentials.
+func runGit(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "LC_ALL=C")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("%s: %s", err, message)
+		}
+		return err
+	}
+	return nil
+}
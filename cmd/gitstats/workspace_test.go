@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repos.txt")
+	manifest := "# team repositories\n\nhttps://github.com/acme/api.git\n  git@github.com:acme/web.git  # the front end\n\n"
+	if err := os.WriteFile(path, []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	urls, err := readManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://github.com/acme/api.git", "git@github.com:acme/web.git"}; !slices.Equal(urls, want) {
+		t.Errorf("readManifest = %q, want %q", urls, want)
+	}
+
+	if _, err := readManifest(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("missing manifest accepted, want an error")
+	}
+}
+
+func TestCloneDir(t *testing.T) {
+	tests := []struct{ url, want string }{
+		{"https://github.com/acme/api.git", "acme/api"},
+		{"https://user@gitlab.example.com:8443/group/sub/api/", "group/sub/api"},
+		{"ssh://git@github.com/acme/api.git", "acme/api"},
+		{"git@github.com:acme/api.git", "acme/api"},
+		{"file:///srv/git/api.git", "srv/git/api"},
+		{"../mirrors/api", "../mirrors/api"},
+	}
+	for _, tt := range tests {
+		if got := cloneDir(tt.url); got != filepath.FromSlash(tt.want) {
+			t.Errorf("cloneDir(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSyncWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	origin := filepath.Join(dir, "origin", "acme")
+	newRepo(t, filepath.Join(origin, "api"), "alice@example.com", "2024-03-05T10:00:00Z", "main.go", "package main\n")
+	newRepo(t, filepath.Join(origin, "web"), "bob@example.com", "2024-03-06T10:00:00Z", "index.html", "<html>\n")
+	urls := []string{
+		"file://" + filepath.ToSlash(filepath.Join(origin, "api")),
+		"file://" + filepath.ToSlash(filepath.Join(origin, "missing")),
+		"file://" + filepath.ToSlash(filepath.Join(origin, "web")),
+	}
+	workspace := filepath.Join(dir, "workspace")
+	want := []string{filepath.Join(workspace, cloneDir(urls[0])), filepath.Join(workspace, cloneDir(urls[2]))}
+
+	// The missing repositories are cloned, and those failing to clone left out
+	dirs, warnings := syncWorkspace(workspace, urls, 2, nil)
+	if !slices.Equal(dirs, want) {
+		t.Errorf("dirs = %q, want %q", dirs, want)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "Failed to clone "+urls[1]+": ") {
+		t.Errorf("warnings = %q, want the missing repository failing to clone", warnings)
+	}
+	if _, err := os.Stat(filepath.Join(want[1], "index.html")); err != nil {
+		t.Errorf("web not cloned: %s", err)
+	}
+
+	// The repositories already cloned are updated
+	newRepo(t, filepath.Join(origin, "api"), "alice@example.com", "2024-03-07T10:00:00Z", "api.go", "package main\n")
+	dirs, warnings = syncWorkspace(workspace, urls[:1], 2, nil)
+	if !slices.Equal(dirs, want[:1]) || len(warnings) != 0 {
+		t.Errorf("dirs = %q, warnings = %q, want %q updated", dirs, warnings, want[:1])
+	}
+	if _, err := os.Stat(filepath.Join(want[0], "api.go")); err != nil {
+		t.Errorf("api not updated: %s", err)
+	}
+
+	// A repository failing to update is still analyzed as it is
+	if output, err := exec.Command("git", "-C", want[1], "remote", "set-url", "origin", urls[1]).CombinedOutput(); err != nil {
+		t.Fatalf("git remote set-url: %s\n%s", err, output)
+	}
+	dirs, warnings = syncWorkspace(workspace, urls[2:], 1, nil)
+	if !slices.Equal(dirs, want[1:]) {
+		t.Errorf("dirs = %q, want %q", dirs, want[1:])
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "Failed to update "+urls[2]+": ") {
+		t.Errorf("warnings = %q, want web failing to update", warnings)
+	}
+}
@@ -0,0 +1,116 @@
+package gitstats
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pseudonymPrefix starts the pseudonyms given by Anonymize, followed by a number.
+const pseudonymPrefix = "Dev-"
+
+// Anonymize returns a copy of globalStats, and of its Repos and Squashes, with every
+// author replaced by their pseudonym in pseudonyms, such as "Dev-07", so the report can
+// be shared without personal data. Display names and the emails merged by name are left
+// out. Authors missing from pseudonyms get the next free number in alphabetical order
+// and are added to it: reusing the map keeps the pseudonyms stable across reports.
+func Anonymize(globalStats *GlobalStats, pseudonyms map[string]string) *GlobalStats {
+	next := 1
+	for _, pseudonym := range pseudonyms {
+		if n, err := strconv.Atoi(strings.TrimPrefix(pseudonym, pseudonymPrefix)); err == nil {
+			next = max(next, n+1)
+		}
+	}
+	authors := make(map[string]bool)
+	collectAuthors(globalStats, authors)
+	var missing []string
+	for author := range authors {
+		if _, ok := pseudonyms[author]; !ok {
+			missing = append(missing, author)
+		}
+	}
+	sort.Strings(missing)
+	for _, author := range missing {
+		pseudonyms[author] = fmt.Sprintf("%s%02d", pseudonymPrefix, next)
+		next++
+	}
+	return anonymize(globalStats, func(author string) string { return pseudonyms[author] })
+}
+
+// collectAuthors adds every author of globalStats, its Repos and Squashes to authors.
+func collectAuthors(globalStats *GlobalStats, authors map[string]bool) {
+	// renameAuthors visits every figure keyed by author
+	renameAuthors(globalStats, func(author string) string {
+		authors[author] = true
+		return author
+	})
+	for _, stats := range append([]*GlobalStats{globalStats}, reposAndSquashes(globalStats)...) {
+		for _, pr := range stats.PullRequests {
+			authors[pr.Author] = true
+		}
+		for _, outlier := range stats.Outliers {
+			authors[outlier.Author] = true
+		}
+		for author := range stats.Churn {
+			authors[author] = true
+		}
+		for login := range stats.Reviews {
+			authors[login] = true
+		}
+	}
+}
+
+// reposAndSquashes returns the Repos and Squashes of globalStats, recursively.
+func reposAndSquashes(globalStats *GlobalStats) []*GlobalStats {
+	var nested []*GlobalStats
+	for _, repo := range globalStats.Repos {
+		nested = append(nested, repo)
+		nested = append(nested, reposAndSquashes(repo)...)
+	}
+	if globalStats.Squashes != nil {
+		nested = append(nested, globalStats.Squashes)
+		nested = append(nested, reposAndSquashes(globalStats.Squashes)...)
+	}
+	return nested
+}
+
+func anonymize(globalStats *GlobalStats, pseudonym func(author string) string) *GlobalStats {
+	anonymized := renameAuthors(globalStats, pseudonym)
+	anonymized.Names = make(map[string]string)
+	anonymized.Merged = nil
+
+	anonymized.PullRequests = nil
+	for _, pr := range globalStats.PullRequests {
+		pr.Author = pseudonym(pr.Author)
+		anonymized.PullRequests = append(anonymized.PullRequests, pr)
+	}
+	anonymized.Outliers = nil
+	for _, outlier := range globalStats.Outliers {
+		outlier.Author = pseudonym(outlier.Author)
+		anonymized.Outliers = append(anonymized.Outliers, outlier)
+	}
+	if globalStats.Churn != nil {
+		anonymized.Churn = make(map[string]Churn)
+		for author, churn := range globalStats.Churn {
+			anonymized.Churn[pseudonym(author)] = churn
+		}
+	}
+	if globalStats.Reviews != nil {
+		anonymized.Reviews = make(map[string]map[string]ReviewStats)
+		for login, periods := range globalStats.Reviews {
+			anonymized.Reviews[pseudonym(login)] = periods
+		}
+	}
+
+	if globalStats.Repos != nil {
+		anonymized.Repos = make(map[string]*GlobalStats)
+		for name, repo := range globalStats.Repos {
+			anonymized.Repos[name] = anonymize(repo, pseudonym)
+		}
+	}
+	if globalStats.Squashes != nil {
+		anonymized.Squashes = anonymize(globalStats.Squashes, pseudonym)
+	}
+	return anonymized
+}
@@ -0,0 +1,84 @@
+package gitstats
+
+import (
+	"path"
+	"strconv"
+)
+
+// Breadth is the number of distinct files an author changed and of the directories
+// holding them, a measure of how widespread their changes are next to the line counts.
+type Breadth struct {
+	Files int `json:"files"`
+	Dirs  int `json:"dirs"`
+}
+
+// newBreadth returns the Breadth of a set of changed paths. Files at the root of a
+// repository are in the directory ".".
+func newBreadth(files map[string]bool) Breadth {
+	dirs := make(map[string]bool)
+	for file := range files {
+		dirs[path.Dir(file)] = true
+	}
+	return Breadth{Files: len(files), Dirs: len(dirs)}
+}
+
+// Breadth returns the breadth of the changes of author in period, or over all periods
+// for "". With an empty author, those of every author are combined. It is zero without
+// Options.Breadth.
+func (gb GlobalStats) Breadth(author, period string) Breadth {
+	files := make(map[string]bool)
+	for a, periods := range gb.Files {
+		if author != "" && a != author {
+			continue
+		}
+		for p, paths := range periods {
+			if period == "" || p == period {
+				addPaths(files, paths)
+			}
+		}
+	}
+	return newBreadth(files)
+}
+
+// addPaths adds the paths of from to into.
+func addPaths(into, from map[string]bool) {
+	for file := range from {
+		into[file] = true
+	}
+}
+
+// prefixPaths returns the paths per author of files under the directory prefix.
+func prefixPaths(files map[string]map[string]bool, prefix string) map[string]map[string]bool {
+	prefixed := make(map[string]map[string]bool, len(files))
+	for author, paths := range files {
+		prefixed[author] = make(map[string]bool, len(paths))
+		for file := range paths {
+			prefixed[author][path.Join(prefix, file)] = true
+		}
+	}
+	return prefixed
+}
+
+// addFiles accumulates the paths changed by author in period.
+func (gb *GlobalStats) addFiles(author, period string, files map[string]bool) {
+	if gb.Files == nil {
+		gb.Files = make(map[string]map[string]map[string]bool)
+	}
+	if gb.Files[author] == nil {
+		gb.Files[author] = make(map[string]map[string]bool)
+	}
+	if gb.Files[author][period] == nil {
+		gb.Files[author][period] = make(map[string]bool)
+	}
+	addPaths(gb.Files[author][period], files)
+}
+
+// breadthColumns returns the Files and Dirs cells of author in period, as Breadth
+// selects them, or "-" for an author without paths such as the others row.
+func breadthColumns(globalStats GlobalStats, author, period string) []string {
+	if _, ok := globalStats.Files[author]; author != "" && !ok {
+		return []string{"-", "-"}
+	}
+	breadth := globalStats.Breadth(author, period)
+	return []string{strconv.Itoa(breadth.Files), strconv.Itoa(breadth.Dirs)}
+}
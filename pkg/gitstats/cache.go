@@ -0,0 +1,213 @@
+package gitstats
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheVersion changes whenever the cached results would be computed differently.
+const cacheVersion = 6
+
+// cacheEntry is the result of one period of one repository, with the commit the
+// analyzed ref pointed at when it was computed.
+type cacheEntry struct {
+	Head   string
+	Result LogResult
+}
+
+// cacheKey returns the name of the cache file of period p for the files of dir
+// selected by pathspec. Everything deciding which commits land in p and how they are
+// counted is part of the key; the state of the repository is checked separately
+// against the Head of the entry.
+func (c *collector) cacheKey(dir string, pathspec []string, p Period) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	location := ""
+	if c.opts.Location != nil {
+		location = c.opts.Location.String()
+	}
+	// git reads the .mailmap of the working tree, committed or not
+	var mailmaps []byte
+	for _, file := range []string{filepath.Join(dir, ".mailmap"), c.mailmapFile} {
+		if data, err := os.ReadFile(file); err == nil {
+			mailmaps = append(mailmaps, data...)
+		}
+	}
+	// Commits authored before the window are placed by their committer date, so the
+	// start of the window matters too
+	key, _ := json.Marshal([]any{
+		cacheVersion, abs, mailmaps, c.revArgs(), pathspec, c.diffArgs(),
+		c.opts.Numstat, c.opts.ByLanguage, c.opts.NoMerges, c.opts.MaxCommits, c.opts.MaxCommitLines, c.opts.CapCommitLines, c.opts.CaseSensitiveEmails, c.opts.CoAuthors, c.opts.ExcludeGenerated, location,
+		c.opts.PathDepth, c.opts.PathPrefixes, c.shallow[dir], metricNames(c.opts.Metrics), c.opts.Breadth,
+		p.Since, p.Until, c.window.Since,
+	})
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedScanDir is processDir with the cache: periods whose entry is still valid for
+// the current head are loaded, and one git log pass from the earliest remaining
+// period to the end of the window computes and stores the others.
+func (c *collector) cachedScanDir(dir string, pathspec []string, seen map[string]bool) (map[string]LogResult, error) {
+	head, err := c.cacheHead(dir)
+	if err != nil {
+		// An empty repository has no head to validate entries against
+		return c.scanDir(dir, pathspec, seen, c.window.Since)
+	}
+
+	buckets := make(map[string]LogResult)
+	changed := make(map[string]map[string]bool) // Periods touched since an older head
+	var missing []Period
+	for _, p := range c.opts.Periods {
+		entry, ok := c.readCache(c.cacheKey(dir, pathspec, p))
+		if ok && entry.Head != head {
+			if c.opts.AllRefs {
+				// The refs moved, with no single old head to look for new commits from
+				ok = false
+			} else {
+				if changed[entry.Head] == nil {
+					changed[entry.Head] = c.changedPeriods(dir, entry.Head, head)
+				}
+				ok = !changed[entry.Head][p.Label]
+			}
+		}
+		if ok {
+			buckets[p.Label] = entry.Result
+			continue
+		}
+		missing = append(missing, p)
+	}
+	c.debug.Debug("cache", "repo", dir, "head", head, "cached", len(buckets), "missing", len(missing))
+	if len(missing) == 0 {
+		return buckets, nil
+	}
+
+	since := missing[0].Since
+	for _, p := range missing[1:] {
+		if p.Since.Before(since) {
+			since = p.Since
+		}
+	}
+	scanned, err := c.scanDir(dir, pathspec, seen, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range missing {
+		result, ok := scanned[p.Label]
+		if !ok {
+			result = *newLogResult()
+		}
+		buckets[p.Label] = result
+		c.writeCache(c.cacheKey(dir, pathspec, p), cacheEntry{Head: head, Result: result})
+	}
+	return buckets, nil
+}
+
+// cacheHead returns the state of dir entries are validated against: the commit of the
+// analyzed ref, or with AllRefs a digest of every ref.
+func (c *collector) cacheHead(dir string) (string, error) {
+	if c.opts.AllRefs {
+		output, err := c.git(append(c.gitArgs(dir), "for-each-ref", "--format=%(objectname) %(refname)")...)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(output)
+		return "refs:" + hex.EncodeToString(sum[:]), nil
+	}
+	ref := c.opts.Branch
+	if ref == "" {
+		ref = "HEAD"
+	}
+	output, err := c.git(append(c.gitArgs(dir), "rev-parse", "--verify", "--quiet", ref+"^{commit}")...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// changedPeriods returns the labels of the periods receiving a commit between the
+// heads old and head. Every period counts as changed when old is not an ancestor of
+// head, e.g. after a force push, as commits may have disappeared.
+func (c *collector) changedPeriods(dir, old, head string) map[string]bool {
+	changed := make(map[string]bool)
+	if _, err := c.git(append(c.gitArgs(dir), "merge-base", "--is-ancestor", old, head)...); err != nil {
+		for _, p := range c.opts.Periods {
+			changed[p.Label] = true
+		}
+		return changed
+	}
+
+	output, err := c.git(append(c.gitArgs(dir), "log", "--pretty=%aI%x09%cI", old+".."+head)...)
+	if err != nil {
+		for _, p := range c.opts.Periods {
+			changed[p.Label] = true
+		}
+		return changed
+	}
+	bucket := periodBucket(c.opts.Periods, c.opts.Location)
+	for _, line := range strings.Split(string(output), "\n") {
+		authored, committed, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		if label, ok := bucket(parseCommitTime(authored), parseCommitTime(committed)); ok {
+			changed[label] = true
+		}
+	}
+	return changed
+}
+
+// readCache loads the cache entry named key, reporting whether there is one.
+func (c *collector) readCache(key string) (cacheEntry, bool) {
+	var entry cacheEntry
+	data, err := os.ReadFile(filepath.Join(c.opts.CacheDir, key+".json"))
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		c.debug.Debug("ignoring unreadable cache entry", "key", key, "err", err)
+		return entry, false
+	}
+	return entry, true
+}
+
+// writeCache stores entry under key. The cache only saves time, so failures are only
+// diagnostics.
+func (c *collector) writeCache(key string, entry cacheEntry) {
+	if err := c.writeCacheFile(key, entry); err != nil {
+		c.debug.Debug("failed to write cache entry", "key", key, "err", err)
+	}
+}
+
+// writeCacheFile writes entry to a temporary file renamed to its final name, so
+// concurrent runs never read a partial entry.
+func (c *collector) writeCacheFile(key string, entry cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.opts.CacheDir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(c.opts.CacheDir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(c.opts.CacheDir, key+".json"))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
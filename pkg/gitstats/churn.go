@@ -0,0 +1,120 @@
+package gitstats
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Churn is the lines an author added and how many of them were deleted again soon
+// after, by anyone.
+type Churn struct {
+	Added   int `json:"added"`
+	Churned int `json:"churned"`
+}
+
+// Percent returns the churned lines in percent of the added lines.
+func (c Churn) Percent() float64 {
+	if c.Added == 0 {
+		return 0
+	}
+	return float64(c.Churned) * 100 / float64(c.Added)
+}
+
+// addedLine is a line of a file added by author at a time.
+type addedLine struct {
+	author string
+	time   time.Time
+}
+
+// ParseChurn parses `git log --reverse -p --unified=0 --pretty=%x00%aE%x09%at` output,
+// oldest commit first, and returns the lines each author added up to until (zero for
+// no limit) with those deleted from the same file at most window later. A deleted line
+// is matched with the latest addition of the same content to its file, so moving lines
+// within a file also counts as churn. Blank lines are ignored.
+func ParseChurn(output string, window time.Duration, until time.Time, caseSensitive bool) map[string]Churn {
+	churn := make(map[string]Churn)
+	added := make(map[string]map[string][]addedLine) // Per file and content
+	author := ""
+	var commitTime time.Time
+	file := ""
+	inHeader := false
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\x00"):
+			email, unix, _ := strings.Cut(line[1:], "\t")
+			author = strings.TrimSpace(email)
+			if !caseSensitive {
+				author = strings.ToLower(author)
+			}
+			seconds, _ := strconv.ParseInt(strings.TrimSpace(unix), 10, 64)
+			commitTime = time.Unix(seconds, 0)
+			file = ""
+		case strings.HasPrefix(line, "diff --git "):
+			// "diff --git a/old b/new"; the name after the last " b/" is the file's new name
+			if i := strings.LastIndex(line, " b/"); i >= 0 {
+				file = line[i+3:]
+			}
+			inHeader = true
+		case inHeader && strings.HasPrefix(line, "rename from "):
+			// The rename to line follows and moves the additions over
+			file = strings.TrimPrefix(line, "rename from ")
+		case inHeader && strings.HasPrefix(line, "rename to "):
+			renamed := strings.TrimPrefix(line, "rename to ")
+			if lines, ok := added[file]; ok {
+				added[renamed] = lines
+				delete(added, file)
+			}
+			file = renamed
+		case strings.HasPrefix(line, "@@"):
+			inHeader = false
+		case inHeader || file == "":
+		case strings.HasPrefix(line, "+"):
+			content := line[1:]
+			if strings.TrimSpace(content) == "" || !until.IsZero() && commitTime.After(until) {
+				continue
+			}
+			if added[file] == nil {
+				added[file] = make(map[string][]addedLine)
+			}
+			added[file][content] = append(added[file][content], addedLine{author, commitTime})
+			stats := churn[author]
+			stats.Added++
+			churn[author] = stats
+		case strings.HasPrefix(line, "-"):
+			lines := added[file][line[1:]]
+			if len(lines) == 0 {
+				continue
+			}
+			last := lines[len(lines)-1]
+			added[file][line[1:]] = lines[:len(lines)-1]
+			if commitTime.Sub(last.time) <= window {
+				stats := churn[last.author]
+				stats.Churned++
+				churn[last.author] = stats
+			}
+		}
+	}
+	return churn
+}
+
+// processChurn returns the churn per author of dir over the analyzed window, following
+// the deletions up to Options.ChurnDays past its end.
+func (c *collector) processChurn(dir string) (map[string]Churn, error) {
+	args := append(c.gitArgs(dir), "log", "--reverse", "-p", "--unified=0", "--no-color", "--no-merges",
+		"--pretty=%x00%aE%x09%at")
+	args = append(args, c.diffArgs()...)
+	args = append(args, c.revArgs()...)
+	if !c.window.Since.IsZero() {
+		args = append(args, "--since="+dateArg(c.window.Since, "00:00:00", c.opts.Location))
+	}
+	args = append(args, c.pathspec(dir)...)
+
+	output, err := c.git(args...)
+	if err != nil {
+		return nil, err
+	}
+	window := time.Duration(c.opts.ChurnDays) * 24 * time.Hour
+	return ParseChurn(string(output), window, c.window.Until.AddDate(0, 0, 1), c.opts.CaseSensitiveEmails), nil
+}
@@ -0,0 +1,198 @@
+package gitstats
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Unowned holds the changes to files no CODEOWNERS rule assigns owners to.
+const Unowned = "(unowned)"
+
+// codeownersFiles are the locations of the CODEOWNERS file, in the order GitHub looks
+// for them.
+var codeownersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeownersRule assigns owners, such as "@org/team @alice", to the paths matching a
+// gitignore-style pattern. A rule without owners leaves its paths unowned.
+type codeownersRule struct {
+	pattern *regexp.Regexp
+	owners  string
+}
+
+// parseCodeowners parses a CODEOWNERS file, skipping blank lines and comments.
+func parseCodeowners(content string) []codeownersRule {
+	var rules []codeownersRule
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		owners := fields[1:]
+		for i, owner := range owners {
+			if strings.HasPrefix(owner, "#") {
+				owners = owners[:i]
+				break
+			}
+		}
+		rules = append(rules, codeownersRule{pattern: codeownersPattern(fields[0]), owners: strings.Join(owners, " ")})
+	}
+	return rules
+}
+
+// codeownersPattern compiles a gitignore-style pattern. Patterns without a slash match
+// at any depth, a leading or inner slash anchors it at the repo root, and a pattern
+// matching a directory matches everything below it.
+func codeownersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(/.*)?$")
+	}
+	return regexp.MustCompile(b.String())
+}
+
+// ownersOf returns the owners of file: those of the last matching rule, or Unowned.
+func ownersOf(rules []codeownersRule, file string) string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].pattern.MatchString(file) {
+			if rules[i].owners == "" {
+				return Unowned
+			}
+			return rules[i].owners
+		}
+	}
+	return Unowned
+}
+
+// readCodeowners returns the rules of the CODEOWNERS file of dir, or an error when it
+// has none. A git directory has no checkout and is read at HEAD.
+func (c *collector) readCodeowners(dir string) ([]codeownersRule, error) {
+	for _, name := range codeownersFiles {
+		if isGitDir(dir) {
+			if data, err := c.git(append(c.gitArgs(dir), "show", "HEAD:"+name)...); err == nil {
+				return parseCodeowners(string(data)), nil
+			}
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", name, err)
+		}
+		return parseCodeowners(string(data)), nil
+	}
+	return nil, fmt.Errorf("%s: no CODEOWNERS file", dir)
+}
+
+// parseOwners parses `git log --pretty=%x00%aE --numstat` output and returns the
+// changes per author email and owners of the changed files. A commit counts once for
+// every owners it touches.
+func parseOwners(output string, rules []codeownersRule, caseSensitive bool) map[string]map[string]ChangesStats {
+	owners := make(map[string]map[string]ChangesStats)
+	for _, record := range strings.Split(output, "\x00") {
+		lines := strings.Split(record, "\n")
+		author := strings.TrimSpace(lines[0])
+		if author == "" {
+			continue
+		}
+		if !caseSensitive {
+			author = strings.ToLower(author)
+		}
+		changes := make(map[string]ChangesStats)
+		for _, line := range lines[1:] {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) != 3 || !isNumstatCount(fields[0]) || !isNumstatCount(fields[1]) {
+				continue
+			}
+			// Binary files count as a touch without lines
+			ins, _ := strconv.Atoi(fields[0])
+			del, _ := strconv.Atoi(fields[1])
+			owner := ownersOf(rules, numstatPath(fields[2]))
+			stats := changes[owner]
+			stats.Commits = 1
+			stats.Insertions += ins
+			stats.Deletions += del
+			changes[owner] = stats
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		if owners[author] == nil {
+			owners[author] = make(map[string]ChangesStats)
+		}
+		addChanges(owners[author], changes)
+	}
+	return owners
+}
+
+// processOwners returns the changes per author and CODEOWNERS owners of the commits of
+// dir in the analyzed window, matched against its current CODEOWNERS file.
+func (c *collector) processOwners(dir string) (map[string]map[string]ChangesStats, error) {
+	rules, err := c.readCodeowners(dir)
+	if err != nil {
+		return nil, err
+	}
+	args := append(c.gitArgs(dir), "log", "--pretty=%x00%aE", "--numstat")
+	args = append(args, c.diffArgs()...)
+	args = append(args, c.revArgs()...)
+	args = append(args, c.mergeArgs())
+	args = append(args, c.window.ArgsIn(c.opts.Location)...)
+	args = append(args, c.pathspec(dir)...)
+
+	output, err := c.git(args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseOwners(string(output), rules, c.opts.CaseSensitiveEmails), nil
+}
+
+// addOwners accumulates the changes per owners of author.
+func (gb *GlobalStats) addOwners(author string, owners map[string]ChangesStats) {
+	if gb.Owners == nil {
+		gb.Owners = make(map[string]map[string]ChangesStats)
+	}
+	if gb.Owners[author] == nil {
+		gb.Owners[author] = make(map[string]ChangesStats)
+	}
+	addChanges(gb.Owners[author], owners)
+}
+
+// printOwners prints the changes per CODEOWNERS owners, most net lines first, with the
+// authors who worked on the areas of each.
+func printOwners(w io.Writer, globalStats GlobalStats, style borderStyle) {
+	printBreakdown(w, globalStats.Owners, "Lines by code owner (CODEOWNERS):", "Owners", "No changes", style)
+}
@@ -0,0 +1,1195 @@
+package gitstats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options selects the repositories, periods and commits analyzed by Collect.
+type Options struct {
+	Path     string         // Repository, or the directory searched with All
+	All      bool           // Analyze every repository below Path
+	Repos    []string       // Analyze these repositories instead of Path
+	Depth    int            // Directory levels below Path searched with All; negative for no limit
+	SkipDirs []string       // Globs of directory names or paths below Path never searched with All
+	Periods  []Period       // Date ranges analyzed, each reported as its own bucket
+	Location *time.Location // Time zone of the dates assigning commits to periods; nil keeps each commit's own offset
+
+	Branch           string // Ref analyzed in every repository instead of the checked-out HEAD
+	AllRefs          bool   // Analyze the commits of every branch and tag (git log --all); Branch is ignored
+	Mailmap          string // Additional mailmap file applied to every repository
+	NoMerges         bool   // Skip merge commits (git log --no-merges), as the command does by default
+	MaxCommits       int    // Examine at most the N most recent commits per repository and period (0 = all)
+	IgnoreWhitespace bool   // Ignore whitespace-only changes (git -w)
+	NoRenames        bool   // Count renamed files as deleted and added instead of detecting renames (git -M)
+	RenameThreshold  int    // Similarity in percent a renamed or copied file needs to keep, git's 50 when 0 (git -M50%)
+	FindCopies       bool   // Also count a file copied from one changed in the same commit by its edits (git -C)
+	FindCopiesHarder bool   // Look for the sources of copies among every file, changed or not; slow on large repositories (git --find-copies-harder)
+	Numstat          bool   // Split ByLanguage from the per-file counts of the main pass instead of one pass per extension
+	SquashMerges     string // Commits that look like squash merges: include (default), exclude or separate
+
+	Grep       []string // Only analyze commits whose message matches one of these extended regexps (git log --grep)
+	InvertGrep bool     // Analyze the commits whose message matches none of Grep instead
+
+	MaxCommitLines int  // Commits changing more lines are outliers, listed in Outliers and left out (0 = no limit)
+	CapCommitLines bool // Count the outliers with their changes scaled down to MaxCommitLines instead
+
+	Extensions       []string // Only analyze files with these extensions (without the dot); none analyzes every file
+	AllFiles         bool     // Analyze every file, even with Extensions or AutoExt
+	AutoExt          bool     // Analyze the dominant file extensions of each repository
+	AutoExtSkip      []string // Extensions and file names never picked by AutoExt
+	Excludes         []string // Gitignore-style patterns excluded on top of each .gitstatsignore
+	ExcludeGenerated bool     // Also exclude vendored, generated and lock files, see GeneratedPatterns
+	Paths            []string // Only analyze files below these paths
+	Includes         []string // Only analyze files matching one of these gitignore-style patterns, e.g. src/**, or below one of Paths
+	PathStats        string   // Only analyze this file or directory
+	ByLanguage       bool     // Also collect each author's changes per file extension into Languages, from the main pass with Numstat
+
+	PathDepth    int      // Also collect each author's changes per directory of this depth into Components
+	PathPrefixes []string // Collect Components per path prefix instead, e.g. services/api; other files are under OtherComponent
+
+	Authors             []string   // Only count authors whose email matches one of these globs, substrings or /regexps/
+	ExcludeAuthors      []string   // Never count authors whose email matches one of these patterns
+	ExcludeBots         bool       // Never count bot accounts such as dependabot[bot], see BotPatterns
+	CoAuthors           string     // Also credit the Co-authored-by trailers of a commit: split or duplicate its lines, "" to skip
+	CaseSensitiveEmails bool       // Keep authors whose emails differ only in case apart
+	Identities          []Identity // Emails and names merged into one canonical author each
+
+	ByRepo       bool   // Also collect the stats of each repository into Repos
+	Submodules   string // Also analyze the checked-out submodules of each repository, recursively: separate, or parent to count them in their top-level repository in Repos; "" to skip
+	MergeByName  bool   // Merge authors sharing the same display name
+	Tags         bool   // Count the annotated tags created per person
+	TagsPattern  string // Only count tags whose name matches the glob
+	PullRequests bool   // Collect GitHub pull requests from merge commit messages
+	Reviews      bool   // Also collect into Reviews the pull request activity of each person, from a Git that is a ReviewSource
+	ChurnDays    int    // Also collect into Churn the added lines deleted again within this many days, 0 to skip
+	Heatmap      bool   // Also collect the commits of each author by weekday and hour into Heatmap
+	Breadth      bool   // Also collect into Files the paths each author changed per period, see GlobalStats.Breadth
+	Dedupe       bool   // With several repositories, count the commits of forks and mirrors once, in the first repository having them; see GlobalStats.Duplicates
+	Jobs         int    // Repositories processed concurrently
+	CacheDir     string // Directory caching the results of each period; "" disables the cache
+	Unshallow    bool   // Deepen shallow clones with git fetch to cover the analyzed window first
+
+	TicketPattern string   // Also collect into Tickets the changes per ticket ID matched by this regexp in commit messages, "" to skip
+	CodeOwners    bool     // Also collect into Owners the changes per owners of the changed files in each repository's CODEOWNERS
+	Metrics       []Metric // Also collect into Metrics the values of these metrics per author and period, see RegisterMetric
+
+	Git      Git          // Runs the git commands; nil runs the git binary with ExecGit
+	Logger   *log.Logger  // Receives the git commands run; nil discards them
+	Progress *log.Logger  // Receives progress such as the auto-detected extensions; nil discards them
+	Debug    *slog.Logger // Receives key=value diagnostics; nil disables them
+
+	// OnProgress is called with the repositories scanned so far and their total after
+	// the log pass of each one, never concurrently; nil for none
+	OnProgress func(done, total int)
+}
+
+// collector runs the git commands of one Collect call.
+type collector struct {
+	opts        Options
+	log         *log.Logger
+	progress    *log.Logger
+	debug       *slog.Logger
+	mailmapFile string
+	autoExts    map[string][]string // Extensions detected per repo with AutoExt
+	repoIgnores map[string][]string // Exclusion patterns from each repo's .gitstatsignore
+	identities  identityMap
+	component   func(path string) string // Components key of a path, nil without PathDepth and PathPrefixes
+	window      Period                   // Union of the analyzed periods
+	parse       parseOptions             // Settings of the log parser, from opts
+	shallow     map[string]time.Time     // Start of the history of each shallow clone
+	parents     map[string]string        // Top-level repository of each submodule, with Submodules
+}
+
+// Collector analyzes the repositories selected by its Options. Each Collect call reads
+// the repositories afresh, so one Collector can produce repeated reports.
+type Collector struct {
+	opts Options
+}
+
+// NewCollector returns a Collector for opts.
+func NewCollector(opts Options) *Collector {
+	return &Collector{opts: opts}
+}
+
+// Collect analyzes the repositories selected by opts; see Collector.Collect.
+func Collect(opts Options) (GlobalStats, error) {
+	return NewCollector(opts).Collect()
+}
+
+// newCollector returns the collector of one Collect call, with the defaults of the
+// options filled in.
+func (col *Collector) newCollector() *collector {
+	opts := col.opts
+	c := &collector{
+		opts:        opts,
+		log:         opts.Logger,
+		progress:    opts.Progress,
+		debug:       opts.Debug,
+		mailmapFile: opts.Mailmap,
+		autoExts:    make(map[string][]string),
+		repoIgnores: make(map[string][]string),
+		shallow:     make(map[string]time.Time),
+		parents:     make(map[string]string),
+		identities:  newIdentityMap(opts.Identities, opts.CaseSensitiveEmails),
+		component:   componentFunc(opts.PathDepth, opts.PathPrefixes),
+	}
+	if c.log == nil {
+		c.log = log.New(io.Discard, "", 0)
+	}
+	if c.progress == nil {
+		c.progress = log.New(io.Discard, "", 0)
+	}
+	if c.debug == nil {
+		c.debug = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if c.mailmapFile != "" {
+		// git resolves a relative mailmap.file against the repo, not the working directory
+		if abs, err := filepath.Abs(c.mailmapFile); err == nil {
+			c.mailmapFile = abs
+		}
+	}
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.Git == nil {
+		opts.Git = ExecGit{}
+	}
+	if len(opts.Periods) == 0 {
+		opts.Periods = MonthPeriods(1, time.Now())
+	}
+	c.opts = opts
+	c.parse = newParseOptions(opts, c.component)
+	return c
+}
+
+// Collect analyzes the repositories of the Collector. Problems with a single repository
+// are reported in Warnings when analyzing several; otherwise they fail the collection.
+func (col *Collector) Collect() (GlobalStats, error) {
+	c := col.newCollector()
+	opts := c.opts
+
+	// Commits are read in a single pass over the whole window, then bucketed by period
+	c.window = opts.Periods[0]
+	for _, p := range opts.Periods[1:] {
+		if p.Since.Before(c.window.Since) {
+			c.window.Since = p.Since
+		}
+		if p.Until.After(c.window.Until) {
+			c.window.Until = p.Until
+		}
+	}
+
+	gb := NewGlobalStats()
+	squashStats := NewGlobalStats()
+
+	for _, patterns := range [][]string{opts.Authors, opts.ExcludeAuthors} {
+		if err := checkAuthorPatterns(patterns); err != nil {
+			return *gb, err
+		}
+	}
+	var ticketPattern *regexp.Regexp
+	if opts.TicketPattern != "" {
+		var err error
+		if ticketPattern, err = regexp.Compile(opts.TicketPattern); err != nil {
+			return *gb, fmt.Errorf("invalid ticket pattern: %s", err)
+		}
+	}
+	var reviews ReviewSource
+	if opts.Reviews {
+		var ok bool
+		if reviews, ok = opts.Git.(ReviewSource); !ok {
+			return *gb, fmt.Errorf("reviews are read from a hosting API: Git must be a ReviewSource such as GitHubGit or GitLabGit")
+		}
+	}
+
+	dirs := opts.Repos
+	if len(dirs) == 0 {
+		var err error
+		if dirs, err = repoDirs(opts.Path, opts.All, opts.Depth, opts.SkipDirs, c.debug); err != nil {
+			return *gb, fmt.Errorf("failed to read directory: %s", err)
+		}
+	}
+	if opts.Submodules != "" {
+		var warnings []string
+		dirs, warnings = c.withSubmodules(dirs)
+		gb.Warnings = append(gb.Warnings, warnings...)
+	}
+	// Problems with one of several repositories are only warnings
+	several := opts.All || len(dirs) > 1
+
+	// Separate stats per repo, only needed for ByRepo
+	var repoStats map[string]*GlobalStats
+	if opts.ByRepo {
+		repoStats = make(map[string]*GlobalStats)
+		for _, dir := range dirs {
+			// A submodule counted in its parent shares the parent's stats
+			if repo := c.repoOf(dir); repo != dir {
+				repoStats[dir] = repoStats[repo]
+			} else {
+				repoStats[dir] = NewGlobalStats()
+			}
+		}
+	}
+	if opts.Heatmap {
+		gb.Heatmap = make(map[string]Heatmap)
+		for _, stats := range repoStats {
+			stats.Heatmap = make(map[string]Heatmap)
+		}
+	}
+	if len(opts.Metrics) > 0 {
+		gb.Metrics = make(map[string]map[string]map[string]int)
+	}
+	if opts.Breadth {
+		gb.Files = make(map[string]map[string]map[string]bool)
+	}
+
+	// Shallow clones are deepened before anything reads their history
+	shallowWarnings := make([]string, len(dirs))
+	shallowStarts := make([]time.Time, len(dirs))
+	c.eachRepo(dirs, func(i int, dir string) {
+		shallowStarts[i], shallowWarnings[i] = c.checkShallow(dir)
+	})
+	for i, dir := range dirs {
+		if !shallowStarts[i].IsZero() {
+			c.shallow[dir] = shallowStarts[i]
+		}
+		if shallowWarnings[i] != "" {
+			gb.Warnings = append(gb.Warnings, shallowWarnings[i])
+		}
+	}
+
+	if opts.AutoExt {
+		detected := make([][]string, len(dirs))
+		errs := make([]error, len(dirs))
+		c.eachRepo(dirs, func(i int, dir string) {
+			detected[i], errs[i] = c.detectExtensions(dir, opts.AutoExtSkip)
+		})
+		for i, dir := range dirs {
+			if errs[i] != nil {
+				gb.Warnings = append(gb.Warnings, errs[i].Error())
+				continue
+			}
+			c.autoExts[dir] = detected[i]
+			c.progress.Printf("Auto-detected extensions for %s: %s", dir, strings.Join(detected[i], ","))
+		}
+	}
+
+	for _, dir := range dirs {
+		patterns, err := readIgnoreFile(dir)
+		if err != nil {
+			gb.Warnings = append(gb.Warnings, fmt.Sprintf("Failed to read %s: %s", filepath.Join(dir, ignoreFileName), err))
+			continue
+		}
+		c.repoIgnores[dir] = patterns
+	}
+
+	// Commits already counted per repo, so one reachable from several refs is counted once
+	seen := make(map[string]map[string]bool)
+	for _, dir := range dirs {
+		seen[dir] = make(map[string]bool)
+	}
+	var duplicates []int
+	if several && opts.Dedupe {
+		duplicates = c.seedDuplicates(dirs, seen)
+	}
+
+	// Results are merged in repo order so the report doesn't depend on which git
+	// finished first
+	results := make([][]LogResult, len(dirs))
+	languageResults := make([]map[string][]LogResult, len(dirs))
+	errs := make([]error, len(dirs))
+	var progressMu sync.Mutex
+	done := 0
+	c.eachRepo(dirs, func(i int, dir string) {
+		if opts.OnProgress != nil {
+			defer func() {
+				progressMu.Lock()
+				defer progressMu.Unlock()
+				done++
+				opts.OnProgress(done, len(dirs))
+			}()
+		}
+		if opts.Branch != "" && !opts.AllRefs && !c.hasRef(dir, opts.Branch) {
+			errs[i] = errNoBranch
+			return
+		}
+		var buckets map[string]LogResult
+		var err error
+		if duplicates != nil && duplicates[i] > 0 {
+			// Cached periods of a fork would count its duplicates again
+			buckets, err = c.scanDir(dir, c.logPathspec(dir), seen[dir], c.window.Since)
+		} else {
+			buckets, err = c.processDir(dir, c.logPathspec(dir), seen[dir])
+		}
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		for _, p := range opts.Periods {
+			result, ok := buckets[p.Label]
+			if !ok {
+				result = *newLogResult()
+			}
+			results[i] = append(results[i], result)
+		}
+		if opts.ByLanguage && opts.Numstat {
+			// The --numstat pass already split every change by file extension
+			languageResults[i] = make(map[string][]LogResult)
+			for _, result := range results[i] {
+				for ext, extResult := range result.Extensions {
+					languageResults[i][ext] = append(languageResults[i][ext], *extResult)
+				}
+			}
+		} else if opts.ByLanguage {
+			languageResults[i], errs[i] = c.processLanguages(dir)
+		}
+	})
+
+	for i, dir := range dirs {
+		if errs[i] != nil {
+			if !several {
+				if errors.Is(errs[i], errNoBranch) {
+					return *gb, fmt.Errorf("%s: no branch %s", dir, opts.Branch)
+				}
+				return *gb, fmt.Errorf("%s: %w", dir, errs[i])
+			}
+			if errors.Is(errs[i], errNoBranch) {
+				gb.Warnings = append(gb.Warnings, fmt.Sprintf("Skipping %s: no branch %s", dir, opts.Branch))
+				continue
+			}
+			if errors.Is(errs[i], ErrNotRepository) {
+				// A directory with a stray or broken .git entry is not worth a warning
+				c.log.Printf("Skipping %s: not a git repository", dir)
+				continue
+			}
+			gb.Warnings = append(gb.Warnings, errs[i].Error())
+			continue
+		}
+		if duplicates != nil && duplicates[i] > 0 {
+			if gb.Duplicates == nil {
+				gb.Duplicates = make(map[string]int)
+			}
+			gb.Duplicates[RepoName(opts.Path, dir)] = duplicates[i]
+		}
+
+		for j, result := range results[i] {
+			result = filterAuthors(c.identities.merge(result), c.countsAuthor)
+			for k := range result.Outliers {
+				result.Outliers[k].Repo = RepoName(opts.Path, c.repoOf(dir))
+			}
+			if several && result.Files != nil {
+				// The same path in two repositories is two files
+				result.Files = prefixPaths(result.Files, RepoName(opts.Path, dir))
+			}
+			monthStr := opts.Periods[j].Label
+			switch opts.SquashMerges {
+			case "exclude":
+			case "separate":
+				squashStats.Add(LogResult{Stats: result.Squashes, Sizes: result.SquashSizes, Metrics: result.SquashMetrics}, monthStr)
+			default:
+				for author, sizes := range result.SquashSizes {
+					result.Sizes[author] = append(result.Sizes[author], sizes...)
+				}
+				for author, values := range result.SquashMetrics {
+					if result.Metrics == nil {
+						result.Metrics = make(map[string]map[string]int)
+					}
+					addMetrics(result.Metrics, author, values)
+				}
+				for author, counts := range result.Squashes {
+					stats := result.Stats[author]
+					stats.Insertions += counts.Insertions
+					stats.Deletions += counts.Deletions
+					stats.Commits += counts.Commits
+					result.Stats[author] = stats
+				}
+			}
+			gb.Add(result, monthStr)
+			if repoStats != nil {
+				repoStats[dir].Add(result, monthStr)
+			}
+		}
+
+		for language, results := range languageResults[i] {
+			for _, result := range results {
+				result = filterAuthors(c.identities.merge(result), c.countsAuthor)
+				if opts.SquashMerges != "exclude" && opts.SquashMerges != "separate" {
+					gb.addLanguage(language, result.Squashes)
+					if repoStats != nil {
+						repoStats[dir].addLanguage(language, result.Squashes)
+					}
+				}
+				gb.addLanguage(language, result.Stats)
+				if repoStats != nil {
+					repoStats[dir].addLanguage(language, result.Stats)
+				}
+			}
+		}
+
+		// The --numstat pass split every change by component too
+		for _, result := range results[i] {
+			for component, componentResult := range result.Components {
+				componentResult := filterAuthors(c.identities.merge(*componentResult), c.countsAuthor)
+				targets := []*GlobalStats{gb}
+				if repoStats != nil {
+					targets = append(targets, repoStats[dir])
+				}
+				for _, target := range targets {
+					if opts.SquashMerges != "exclude" && opts.SquashMerges != "separate" {
+						target.addComponent(component, componentResult.Squashes)
+					}
+					target.addComponent(component, componentResult.Stats)
+				}
+			}
+		}
+	}
+
+	// Tags and pull requests are collected once over the whole analyzed window
+	windowSince, windowUntil := c.window.Since, c.window.Until.AddDate(0, 0, 1)
+
+	if opts.Tags {
+		tagStats := make([]*GlobalStats, len(dirs))
+		errs := make([]error, len(dirs))
+		c.eachRepo(dirs, func(i int, dir string) {
+			tagStats[i] = NewGlobalStats()
+			errs[i] = c.processTags(tagStats[i], dir, windowSince, windowUntil, opts.TagsPattern)
+		})
+		for i := range dirs {
+			if errs[i] != nil {
+				gb.Warnings = append(gb.Warnings, errs[i].Error())
+				continue
+			}
+			for tagger, count := range tagStats[i].Tags {
+				gb.Tags[c.identities.canonical(tagger, tagStats[i].Names[tagger])] += count
+			}
+			for email, name := range tagStats[i].Names {
+				if identity := c.identities.resolve(email, name); identity != nil {
+					email = identity.Email
+					if identity.Name != "" {
+						name = identity.Name
+					}
+				}
+				if _, exists := gb.Names[email]; !exists {
+					gb.Names[email] = name
+				}
+			}
+		}
+	}
+
+	if opts.PullRequests {
+		prs := make([][]PullRequest, len(dirs))
+		errs := make([]error, len(dirs))
+		c.eachRepo(dirs, func(i int, dir string) {
+			if opts.Branch != "" && !opts.AllRefs && !c.hasRef(dir, opts.Branch) {
+				return
+			}
+			args := append(c.gitArgs(dir), "log", "--pretty=%H%x09%aE%x09%s",
+				"--diff-merges=first-parent", "--shortstat",
+			)
+			args = append(args, c.revArgs()...)
+			args = append(args, c.diffArgs()...)
+			args = append(args, c.window.ArgsIn(c.opts.Location)...)
+			args = append(args, c.pathspec(dir)...)
+
+			output, err := c.git(args...)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			repo := ""
+			if several {
+				repo = RepoName(opts.Path, dir)
+			}
+			prs[i] = ParsePullRequests(string(output), repo)
+		})
+		for i := range dirs {
+			if errs[i] != nil {
+				gb.Warnings = append(gb.Warnings, errs[i].Error())
+				continue
+			}
+			for _, pr := range prs[i] {
+				pr.Author = c.identities.canonical(pr.Author, pr.Author)
+				gb.PullRequests = append(gb.PullRequests, pr)
+			}
+		}
+	}
+
+	if reviews != nil {
+		events := make([][]ReviewEvent, len(dirs))
+		errs := make([]error, len(dirs))
+		c.eachRepo(dirs, func(i int, dir string) {
+			// The same margin of a day as the log pass, the periods decide
+			events[i], errs[i] = reviews.ReviewEvents(dir, windowSince.AddDate(0, 0, -1), windowUntil.AddDate(0, 0, 1))
+		})
+		bucket := periodBucket(opts.Periods, opts.Location)
+		gb.Reviews = make(map[string]map[string]ReviewStats)
+		for i, dir := range dirs {
+			if errs[i] != nil {
+				gb.Warnings = append(gb.Warnings, errs[i].Error())
+				continue
+			}
+			for _, event := range events[i] {
+				// Logins of bot accounts end in [bot] like their emails
+				if opts.ExcludeBots && isBot(event.Author) {
+					continue
+				}
+				period, ok := bucket(event.Time, event.Time)
+				if !ok {
+					continue
+				}
+				gb.addReview(event.Author, period, event.Kind)
+				if repoStats != nil {
+					repoStats[dir].addReview(event.Author, period, event.Kind)
+				}
+			}
+		}
+	}
+
+	if ticketPattern != nil {
+		tickets := make([]map[string]map[string]ChangesStats, len(dirs))
+		errs := make([]error, len(dirs))
+		c.eachRepo(dirs, func(i int, dir string) {
+			if opts.Branch != "" && !opts.AllRefs && !c.hasRef(dir, opts.Branch) {
+				return
+			}
+			tickets[i], errs[i] = c.processTickets(dir, ticketPattern)
+		})
+		gb.Tickets = make(map[string]map[string]ChangesStats)
+		for i, dir := range dirs {
+			if errs[i] != nil {
+				gb.Warnings = append(gb.Warnings, errs[i].Error())
+				continue
+			}
+			targets := []*GlobalStats{gb}
+			if repoStats != nil {
+				if repoStats[dir].Tickets == nil {
+					repoStats[dir].Tickets = make(map[string]map[string]ChangesStats)
+				}
+				targets = append(targets, repoStats[dir])
+			}
+			for author, authorTickets := range tickets[i] {
+				author = c.identities.canonical(author, "")
+				if !c.countsAuthor(author) {
+					continue
+				}
+				for _, target := range targets {
+					target.addTickets(author, authorTickets)
+				}
+			}
+		}
+	}
+
+	if opts.CodeOwners {
+		owners := make([]map[string]map[string]ChangesStats, len(dirs))
+		errs := make([]error, len(dirs))
+		c.eachRepo(dirs, func(i int, dir string) {
+			if opts.Branch != "" && !opts.AllRefs && !c.hasRef(dir, opts.Branch) {
+				return
+			}
+			owners[i], errs[i] = c.processOwners(dir)
+		})
+		gb.Owners = make(map[string]map[string]ChangesStats)
+		for i, dir := range dirs {
+			if errs[i] != nil {
+				gb.Warnings = append(gb.Warnings, errs[i].Error())
+				continue
+			}
+			targets := []*GlobalStats{gb}
+			if repoStats != nil {
+				if repoStats[dir].Owners == nil {
+					repoStats[dir].Owners = make(map[string]map[string]ChangesStats)
+				}
+				targets = append(targets, repoStats[dir])
+			}
+			for author, authorOwners := range owners[i] {
+				author = c.identities.canonical(author, "")
+				if !c.countsAuthor(author) {
+					continue
+				}
+				for _, target := range targets {
+					target.addOwners(author, authorOwners)
+				}
+			}
+		}
+	}
+
+	if opts.ChurnDays > 0 {
+		churns := make([]map[string]Churn, len(dirs))
+		errs := make([]error, len(dirs))
+		c.eachRepo(dirs, func(i int, dir string) {
+			if opts.Branch != "" && !opts.AllRefs && !c.hasRef(dir, opts.Branch) {
+				return
+			}
+			churns[i], errs[i] = c.processChurn(dir)
+		})
+		gb.Churn = make(map[string]Churn)
+		for i, dir := range dirs {
+			if errs[i] != nil {
+				gb.Warnings = append(gb.Warnings, errs[i].Error())
+				continue
+			}
+			targets := []*GlobalStats{gb}
+			if repoStats != nil {
+				if repoStats[dir].Churn == nil {
+					repoStats[dir].Churn = make(map[string]Churn)
+				}
+				targets = append(targets, repoStats[dir])
+			}
+			for author, churn := range churns[i] {
+				author = c.identities.canonical(author, "")
+				if !c.countsAuthor(author) {
+					continue
+				}
+				for _, target := range targets {
+					total := target.Churn[author]
+					total.Added += churn.Added
+					total.Churned += churn.Churned
+					target.Churn[author] = total
+				}
+			}
+		}
+	}
+
+	for tagger := range gb.Tags {
+		if !c.countsAuthor(tagger) {
+			delete(gb.Tags, tagger)
+		}
+	}
+
+	if opts.MergeByName {
+		gb.Merged = MergeByName(gb)
+	}
+	if opts.SquashMerges == "separate" {
+		gb.Squashes = squashStats
+	}
+	if repoStats != nil {
+		gb.Repos = make(map[string]*GlobalStats)
+		for dir, stats := range repoStats {
+			if c.repoOf(dir) != dir {
+				continue
+			}
+			if opts.MergeByName {
+				stats.Merged = MergeByName(stats)
+			}
+			gb.Repos[RepoName(opts.Path, dir)] = stats
+		}
+	}
+	return *gb, nil
+}
+
+// eachRepo calls fn for every repository of dirs from a pool of Jobs workers and
+// returns once all calls are done. fn must only write to state of its own repo,
+// typically slot i of a slice merged afterwards in repo order.
+func (c *collector) eachRepo(dirs []string, fn func(i int, dir string)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(c.opts.Jobs, 1), max(len(dirs), 1)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i, dirs[i])
+			}
+		}()
+	}
+	for i := range dirs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// matchAuthor reports whether email matches one of patterns, case-insensitively. A
+// pattern between slashes is a regular expression, e.g. "/^(jenkins|ci)@/"; one with
+// glob characters must match the whole email, e.g. "*@team.com"; any other pattern
+// matches as a substring. Invalid regular expressions match nothing, see
+// checkAuthorPatterns.
+func matchAuthor(email string, patterns []string) bool {
+	email = strings.ToLower(email)
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if expr, ok := authorRegexp(pattern); ok {
+			if re, err := regexp.Compile("(?i)" + expr); err == nil && re.MatchString(email) {
+				return true
+			}
+			continue
+		}
+		pattern = strings.ToLower(pattern)
+		if strings.ContainsAny(pattern, "*?[") {
+			if matched, _ := path.Match(pattern, email); matched {
+				return true
+			}
+		} else if pattern != "" && strings.Contains(email, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorRegexp returns the regular expression of a "/expr/" author pattern, and whether
+// pattern is one.
+func authorRegexp(pattern string) (string, bool) {
+	if len(pattern) < 2 || pattern[0] != '/' || pattern[len(pattern)-1] != '/' {
+		return "", false
+	}
+	return pattern[1 : len(pattern)-1], true
+}
+
+// checkAuthorPatterns returns an error for the first pattern that is not a valid
+// regular expression, a glob or a substring.
+func checkAuthorPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if expr, ok := authorRegexp(pattern); ok {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid author pattern %s: %s", pattern, err)
+			}
+		} else if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid author pattern %s: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// BotPatterns match the emails of well-known bot and automation accounts, dropped
+// with ExcludeBots. Any email containing "[bot]", the suffix GitHub gives app
+// accounts, is a bot as well.
+var BotPatterns = []string{
+	"noreply@github.com",
+	"*@dependabot.com",
+	"bot@renovateapp.com",
+	"*-bot@*",
+	"*@noreply.gitlab.com",
+}
+
+// isBot reports whether email belongs to a bot account.
+func isBot(email string) bool {
+	return strings.Contains(strings.ToLower(email), "[bot]") || matchAuthor(email, BotPatterns)
+}
+
+// countsAuthor reports whether the commits of email are counted: it must match
+// Authors when any are given, and neither ExcludeAuthors nor, with ExcludeBots, a bot.
+func (c *collector) countsAuthor(email string) bool {
+	if c.opts.ExcludeBots && isBot(email) {
+		return false
+	}
+	if matchAuthor(email, c.opts.ExcludeAuthors) {
+		return false
+	}
+	return len(c.opts.Authors) == 0 || matchAuthor(email, c.opts.Authors)
+}
+
+// filterAuthors drops the authors of result for which keep is false.
+func filterAuthors(result LogResult, keep func(email string) bool) LogResult {
+	for _, stats := range []map[string]ChangesStats{result.Stats, result.Squashes} {
+		for author := range stats {
+			if !keep(author) {
+				delete(stats, author)
+			}
+		}
+	}
+	for author := range result.Binary {
+		if !keep(author) {
+			delete(result.Binary, author)
+		}
+	}
+	for author := range result.LastCommit {
+		if !keep(author) {
+			delete(result.LastCommit, author)
+		}
+	}
+	for author := range result.Days {
+		if !keep(author) {
+			delete(result.Days, author)
+			delete(result.Hours, author)
+			delete(result.Files, author)
+		}
+	}
+	for _, sizes := range []map[string][]int{result.Sizes, result.SquashSizes} {
+		for author := range sizes {
+			if !keep(author) {
+				delete(sizes, author)
+			}
+		}
+	}
+	for _, metrics := range []map[string]map[string]int{result.Metrics, result.SquashMetrics} {
+		for author := range metrics {
+			if !keep(author) {
+				delete(metrics, author)
+			}
+		}
+	}
+	result.Outliers = slices.DeleteFunc(result.Outliers, func(outlier OutlierCommit) bool { return !keep(outlier.Author) })
+	return result
+}
+
+// ErrNotRepository is returned by Collect when Path is not inside a git repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// git runs git with args through the Git of the options and returns its output.
+func (c *collector) git(args ...string) ([]byte, error) {
+	commandStr := strings.Join(args, " ")
+	c.log.Println(commandStr)
+	return c.opts.Git.Run(args...)
+}
+
+// gitStream runs git with args and calls read with its output, streamed when the Git
+// of the options is a StreamGit.
+func (c *collector) gitStream(read func(output io.Reader) error, args ...string) error {
+	if git, ok := c.opts.Git.(StreamGit); ok {
+		c.log.Println(strings.Join(args, " "))
+		return git.Stream(read, args...)
+	}
+	output, err := c.git(args...)
+	if err != nil {
+		return err
+	}
+	return read(bytes.NewReader(output))
+}
+
+// processLanguages runs one git log pass per analyzed extension of dir, returning
+// the results of every period keyed by extension. Each extension has its own set of
+// seen commits, as a commit touching several languages counts for each of them.
+func (c *collector) processLanguages(dir string) (map[string][]LogResult, error) {
+	exts := c.extensions(dir)
+	if len(exts) == 0 || c.opts.AllFiles {
+		// When every file is analyzed, the languages are the dominant ones of dir
+		skip := c.opts.AutoExtSkip
+		if skip == nil {
+			skip = strings.Split(DefaultAutoExtSkip, ",")
+		}
+		var err error
+		if exts, err = c.detectExtensions(dir, skip); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make(map[string][]LogResult)
+	for _, ext := range exts {
+		pathspec := append(append([]string{"--"}, c.selectExtensions([]string{ext})...), c.excludes(dir, false)...)
+		buckets, err := c.processDir(dir, pathspec, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range buckets {
+			results[ext] = append(results[ext], result)
+		}
+	}
+	return results, nil
+}
+
+// errNoBranch marks a repository lacking Options.Branch.
+var errNoBranch = errors.New("no such branch")
+
+// hasRef reports whether ref names a commit in dir.
+func (c *collector) hasRef(dir, ref string) bool {
+	_, err := c.git(append(c.gitArgs(dir), "rev-parse", "--verify", "--quiet", ref+"^{commit}")...)
+	return err == nil
+}
+
+// revArgs returns the git log arguments selecting the analyzed commits: those of Grep,
+// then --all, the Branch, or none for the checked-out HEAD.
+func (c *collector) revArgs() []string {
+	var args []string
+	if len(c.opts.Grep) > 0 {
+		args = append(args, "--extended-regexp")
+		for _, pattern := range c.opts.Grep {
+			args = append(args, "--grep="+pattern)
+		}
+		if c.opts.InvertGrep {
+			args = append(args, "--invert-grep")
+		}
+	}
+	if c.opts.AllRefs {
+		return append(args, "--all")
+	}
+	if c.opts.Branch != "" {
+		return append(args, c.opts.Branch)
+	}
+	return args
+}
+
+// mergeArgs returns the git log argument for merge commits: --no-merges with NoMerges,
+// and --full-history otherwise, as the simplification of a history limited to paths
+// leaves out every merge that matches one of its parents there.
+func (c *collector) mergeArgs() string {
+	if c.opts.NoMerges {
+		return "--no-merges"
+	}
+	return "--full-history"
+}
+
+// gitArgs returns the git arguments common to every log invocation in dir.
+func (c *collector) gitArgs(dir string) []string {
+	args := []string{"--no-pager", "-C", dir}
+	if c.mailmapFile != "" {
+		args = append(args, "-c", "mailmap.file="+c.mailmapFile)
+	}
+	return args
+}
+
+// extensions returns the file extensions analyzed in dir, none when every file is.
+func (c *collector) extensions(dir string) []string {
+	if exts, ok := c.autoExts[dir]; ok {
+		return exts
+	}
+	return c.opts.Extensions
+}
+
+// extPathspecs returns the pathspecs selecting the files with one of exts, below one
+// of paths when any are given. Unlike with the glob magic, * matches across
+// directories, so "src/*.go" also selects src/api/server.go.
+func extPathspecs(paths, exts []string) []string {
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	var specs []string
+	for _, p := range paths {
+		for _, ext := range exts {
+			spec := "*." + ext
+			if p = strings.TrimSuffix(p, "/"); p != "" {
+				spec = p + "/" + spec
+			}
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// pathspec returns the trailing arguments selecting the files analyzed in dir.
+func (c *collector) pathspec(dir string) []string {
+	return c.selectFiles(dir, c.opts.ExcludeGenerated)
+}
+
+// logPathspec is pathspec for the log passes parsed with the parseOptions of the
+// collection, which skip the lines of generated files instead: limited to the other
+// files, git log would leave out the commits touching none of them.
+func (c *collector) logPathspec(dir string) []string {
+	return c.selectFiles(dir, false)
+}
+
+// selectFiles returns the trailing arguments selecting the files analyzed in dir,
+// excluding GeneratedPatterns with generated.
+func (c *collector) selectFiles(dir string, generated bool) []string {
+	var args []string
+	if c.opts.PathStats != "" {
+		// --follow tracks a single file across renames; it can't be used with directories
+		// nor with other pathspecs, and the file selected by name isn't excluded
+		if info, err := os.Stat(filepath.Join(dir, c.opts.PathStats)); err == nil && info.Mode().IsRegular() {
+			return []string{"--follow", "--", c.opts.PathStats}
+		}
+		args = []string{"--", c.opts.PathStats}
+	} else if c.opts.AllFiles || len(c.extensions(dir)) == 0 {
+		// Without extensions Paths and Includes select every file below them, and
+		// without either there is no pathspec: the whole tree is analyzed
+		excludes := c.excludes(dir, generated)
+		includes := includePathspecs(c.opts.Includes, nil)
+		if len(c.opts.Paths) == 0 && len(includes) == 0 && len(excludes) == 0 {
+			return nil
+		}
+		return append(append(append([]string{"--"}, c.opts.Paths...), includes...), excludes...)
+	} else {
+		args = append([]string{"--"}, c.selectExtensions(c.extensions(dir))...)
+	}
+	return append(args, c.excludes(dir, generated)...)
+}
+
+// selectExtensions returns the pathspecs of the files with one of exts below Paths and
+// matching Includes, or anywhere without either.
+func (c *collector) selectExtensions(exts []string) []string {
+	var specs []string
+	// Includes alone narrow the files of the extensions to theirs
+	if len(c.opts.Paths) > 0 || len(c.opts.Includes) == 0 {
+		specs = extPathspecs(c.opts.Paths, exts)
+	}
+	return append(specs, includePathspecs(c.opts.Includes, exts)...)
+}
+
+// excludes returns the exclude pathspecs of dir: Excludes, the repo's .gitstatsignore
+// and, with generated, GeneratedPatterns all exclude paths.
+func (c *collector) excludes(dir string, generated bool) []string {
+	patterns := append(append([]string(nil), c.opts.Excludes...), c.repoIgnores[dir]...)
+	if generated {
+		patterns = append(patterns, GeneratedPatterns...)
+	}
+	return excludePathspecs(patterns)
+}
+
+// diffArgs returns the git log arguments deciding how the changes of a commit are
+// counted. Renames are passed explicitly so the diff.renames setting doesn't matter;
+// copies are only looked for on request, as git does.
+func (c *collector) diffArgs() []string {
+	threshold := ""
+	if c.opts.RenameThreshold > 0 {
+		threshold = strconv.Itoa(c.opts.RenameThreshold) + "%"
+	}
+	args := []string{"-M" + threshold}
+	if c.opts.NoRenames {
+		args = []string{"--no-renames"}
+	} else if c.opts.FindCopies || c.opts.FindCopiesHarder {
+		args = append(args, "-C"+threshold)
+		if c.opts.FindCopiesHarder {
+			args = append(args, "--find-copies-harder")
+		}
+	}
+	if c.opts.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	return args
+}
+
+// processDir returns the commits of the files of dir selected by pathspec keyed by the
+// label of their period, loading the periods cached in CacheDir and running a single
+// git log pass for the others. seen is only used for dir, so dirs can be processed
+// concurrently.
+func (c *collector) processDir(dir string, pathspec []string, seen map[string]bool) (map[string]LogResult, error) {
+	if c.opts.CacheDir == "" {
+		return c.scanDir(dir, pathspec, seen, c.window.Since)
+	}
+	return c.cachedScanDir(dir, pathspec, seen)
+}
+
+// scanDir runs a single git log pass from since to the end of the analyzed window.
+func (c *collector) scanDir(dir string, pathspec []string, seen map[string]bool, since time.Time) (map[string]LogResult, error) {
+	// %aE/%aN and %cE/%cN apply the repo's .mailmap (and Mailmap) to identities
+	args := append(c.gitArgs(dir), "log", remoteLogFormat)
+	if c.opts.CoAuthors != "" {
+		args[len(args)-1] += "%x09%(trailers:key=Co-authored-by,valueonly,separator=%x1f)"
+	}
+	// Per-file counts tell binary files ("-") apart from files without line changes, and
+	// unlike the --shortstat summary they are never translated; with -z paths are
+	// printed as they are, without quoting
+	args = append(args, "--numstat", "-z")
+	args = append(args, c.diffArgs()...)
+	args = append(args, c.revArgs()...)
+	args = append(args, c.mergeArgs())
+
+	started := time.Now()
+	scan := Period{Since: since, Until: c.window.Until}
+	output := &countingReader{}
+	var buckets map[string]LogResult
+	var err error
+	if c.opts.MaxCommits > 0 {
+		// MaxCommits samples the newest commits of each period: one pass per period with
+		// --max-count, so git stops there instead of listing the whole window. Commits
+		// of the neighbouring days scanned for the offsets count towards it as well.
+		buckets = make(map[string]LogResult)
+		args = append(args, "--max-count="+strconv.Itoa(c.opts.MaxCommits))
+		for _, p := range c.opts.Periods {
+			if p.Until.Before(since) {
+				continue
+			}
+			var period map[string]LogResult
+			if period, err = c.logPass(dir, args, pathspec, p, []Period{p}, seen, output); err != nil {
+				break
+			}
+			for label, result := range period {
+				buckets[label] = result
+			}
+		}
+	} else {
+		buckets, err = c.logPass(dir, args, pathspec, scan, c.opts.Periods, seen, output)
+	}
+	if err != nil {
+		c.debug.Debug("git log failed", "repo", dir, "err", err)
+		return nil, err
+	}
+	if c.debug.Enabled(context.Background(), slog.LevelDebug) {
+		elapsed := time.Since(started)
+		commits, authors := 0, make(map[string]bool)
+		for _, result := range buckets {
+			commits += result.Commits
+			for author := range result.Stats {
+				authors[author] = true
+			}
+		}
+		c.debug.Debug("repo processed", "repo", dir, "branch", c.currentBranch(dir),
+			"since", scan.Since.Format("2006-01-02"), "commits", commits,
+			"authors", len(authors), "bytes", output.n, "elapsed", elapsed)
+		for _, result := range buckets {
+			for _, warning := range result.Warnings {
+				c.debug.Debug("parse warning", "repo", dir, "warning", warning)
+			}
+		}
+	}
+	return buckets, nil
+}
+
+// logPass runs git log with args over scan in dir and parses its output as it is read,
+// into the periods of the commits. Commits are bucketed by the date of their own offset
+// or Location, up to a day off the local date git compares --since and --until with,
+// so a day more is scanned on each side.
+func (c *collector) logPass(dir string, args, pathspec []string, scan Period, periods []Period, seen map[string]bool, output *countingReader) (map[string]LogResult, error) {
+	if !scan.Since.IsZero() {
+		scan.Since = scan.Since.AddDate(0, 0, -1)
+	}
+	scan.Until = scan.Until.AddDate(0, 0, 1)
+	args = append(slices.Clip(args), scan.ArgsIn(c.opts.Location)...)
+	args = append(args, pathspec...)
+
+	var buckets map[string]LogResult
+	err := c.gitStream(func(r io.Reader) error {
+		output.r = r
+		var err error
+		buckets, err = parseLogBuckets(output, seen, periodBucket(periods, c.opts.Location), c.parse)
+		return err
+	}, args...)
+	return buckets, err
+}
+
+// countingReader counts the bytes read through it, for the debug log.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
+
+// periodBucket places commits in the period containing their author date, or their
+// committer date for commits authored outside every period (e.g. rebased), as git
+// selects the commits of the window by committer date. A date falls on the day of its
+// own offset, the one it was made with, or of loc when set.
+func periodBucket(periods []Period, loc *time.Location) bucketFunc {
+	find := func(t time.Time) (string, bool) {
+		if t.IsZero() {
+			return "", false
+		}
+		if loc != nil {
+			t = t.In(loc)
+		}
+		year, month, day := t.Date()
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		for _, p := range periods {
+			if !date.Before(p.Since) && !date.After(p.Until) {
+				return p.Label, true
+			}
+		}
+		return "", false
+	}
+	return func(authored, committed time.Time) (string, bool) {
+		if label, ok := find(authored); ok {
+			return label, true
+		}
+		return find(committed)
+	}
+}
@@ -0,0 +1,16 @@
+package gitstats
+
+import "io"
+
+// plainWriter strips ANSI color codes from everything written through it. The print
+// functions emit each code together with its text, so codes never span writes.
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := p.w.Write(ansiRegex.ReplaceAll(b, nil)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,26 @@
+package gitstats
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPlainWriterStripsColors(t *testing.T) {
+	var buf bytes.Buffer
+	printStats(plainWriter{&buf}, *sampleStats(), reportOptions{Border: borderStyles["none"]})
+
+	if bytes.Contains(buf.Bytes(), []byte("\033[")) {
+		t.Errorf("output contains ANSI codes: %q", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("alice@example.com")) {
+		t.Errorf("output lost its content: %q", buf.String())
+	}
+}
+
+func sampleStats() *GlobalStats {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 10, Deletions: 2, Commits: 1},
+	}}, "(2024-03) March 2024")
+	return gb
+}
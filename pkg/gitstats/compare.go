@@ -0,0 +1,264 @@
+package gitstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ComparePeriods returns window labeled with its dates as current, and the period it
+// is compared with as previous: the one right before it with "previous", of the same
+// number of calendar months when window spans whole months and of days otherwise, or
+// the same dates a year earlier with "year".
+func ComparePeriods(window Period, mode string) (previous, current Period, err error) {
+	current = window
+	if current.Since.IsZero() {
+		return previous, current, fmt.Errorf("comparing needs a period with a start date")
+	}
+	switch mode {
+	case "previous":
+		if current.Since.Day() == 1 && current.Until.AddDate(0, 0, 1).Day() == 1 {
+			months := (current.Until.Year()-current.Since.Year())*12 + int(current.Until.Month()-current.Since.Month()) + 1
+			previous.Since = current.Since.AddDate(0, -months, 0)
+			previous.Until = current.Since.AddDate(0, 0, -1)
+		} else {
+			days := int(current.Until.Sub(current.Since).Hours()/24) + 1
+			previous.Since = current.Since.AddDate(0, 0, -days)
+			previous.Until = current.Since.AddDate(0, 0, -1)
+		}
+	case "year":
+		previous.Since = current.Since.AddDate(-1, 0, 0)
+		previous.Until = current.Until.AddDate(-1, 0, 0)
+	default:
+		return previous, current, fmt.Errorf("unknown comparison: %s", mode)
+	}
+	for _, p := range []*Period{&previous, &current} {
+		p.Label = "(" + p.Since.Format("2006-01-02") + " - " + p.Until.Format("2006-01-02") + ")"
+	}
+	return previous, current, nil
+}
+
+// ComparedStats is the changes of an author or repository in the two compared periods.
+type ComparedStats struct {
+	Previous ChangesStats `json:"previous"`
+	Current  ChangesStats `json:"current"`
+}
+
+// Delta returns the change of the net lines from Previous to Current.
+func (s ComparedStats) Delta() int {
+	return s.Current.Insertions - s.Current.Deletions - (s.Previous.Insertions - s.Previous.Deletions)
+}
+
+// Percent returns Delta in percent of the previous net lines, and false when there
+// were none to compare with.
+func (s ComparedStats) Percent() (float64, bool) {
+	previous := s.Previous.Insertions - s.Previous.Deletions
+	if previous == 0 {
+		return 0, false
+	}
+	if previous < 0 {
+		previous = -previous
+	}
+	return float64(s.Delta()) * 100 / float64(previous), true
+}
+
+// Comparison is the changes of each author and repository in two periods collected
+// together, see ComparePeriods.
+type Comparison struct {
+	Previous Period
+	Current  Period
+	Authors  map[string]ComparedStats
+	Repos    map[string]ComparedStats // With Repos in the compared stats
+}
+
+// Compare returns the comparison of the previous and current periods of globalStats.
+func Compare(globalStats GlobalStats, previous, current Period) Comparison {
+	comparison := Comparison{Previous: previous, Current: current, Authors: compareAuthors(globalStats, previous, current)}
+	if globalStats.Repos != nil {
+		comparison.Repos = make(map[string]ComparedStats)
+		for name, repo := range globalStats.Repos {
+			var sum ComparedStats
+			for _, stats := range compareAuthors(*repo, previous, current) {
+				sum.Previous = addStats(sum.Previous, stats.Previous)
+				sum.Current = addStats(sum.Current, stats.Current)
+			}
+			comparison.Repos[name] = sum
+		}
+	}
+	return comparison
+}
+
+func compareAuthors(globalStats GlobalStats, previous, current Period) map[string]ComparedStats {
+	authors := make(map[string]ComparedStats)
+	for author, months := range globalStats.Stats {
+		authors[author] = ComparedStats{Previous: months[previous.Label], Current: months[current.Label]}
+	}
+	return authors
+}
+
+func addStats(a, b ChangesStats) ChangesStats {
+	return ChangesStats{Insertions: a.Insertions + b.Insertions, Deletions: a.Deletions + b.Deletions, Commits: a.Commits + b.Commits}
+}
+
+// RenderComparison writes comparison in the text (default), markdown or json format
+// of opts; Border and Color apply as in Render. Authors and repositories are listed by
+// the change of their net lines, risers first, and the text formats name up to three
+// top risers and decliners.
+func RenderComparison(w io.Writer, comparison Comparison, opts RenderOptions) error {
+	if !opts.Color {
+		w = plainWriter{w}
+	}
+	border := opts.Border
+	switch opts.Format {
+	case "json":
+		return printComparisonJSON(w, comparison)
+	case "markdown":
+		border = "markdown"
+		w = plainWriter{w}
+	case "", "text":
+		if border == "" {
+			border = "none"
+		}
+	default:
+		return fmt.Errorf("unknown format: %s", opts.Format)
+	}
+	style, ok := borderStyles[border]
+	if !ok {
+		return fmt.Errorf("unknown border style: %s", opts.Border)
+	}
+
+	blue := "\033[94m"
+	green := "\033[32m"
+	red := "\033[31m"
+	reset := "\033[0m"
+	signed := func(n int) string {
+		if n > 0 {
+			return green + "+" + strconv.Itoa(n) + reset
+		}
+		if n < 0 {
+			return red + strconv.Itoa(n) + reset
+		}
+		return "0"
+	}
+	percent := func(s ComparedStats) string {
+		p, ok := s.Percent()
+		if !ok {
+			if s.Delta() == 0 {
+				return "-"
+			}
+			return "new"
+		}
+		return strconv.FormatFloat(p, 'f', 1, 64) + "%"
+	}
+	byDelta := func(stats map[string]ComparedStats) []string {
+		var keys []string
+		for key := range stats {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if di, dj := stats[keys[i]].Delta(), stats[keys[j]].Delta(); di != dj {
+				return di > dj
+			}
+			return keys[i] < keys[j]
+		})
+		return keys
+	}
+	compared := func(title, column string, stats map[string]ComparedStats) {
+		fmt.Fprintf(w, "\n%s%s%s\n", blue, title, reset)
+		t := table{
+			header:     []string{column, "Commits before", "Commits now", "Net before", "Net now", "Change", "Change %"},
+			rightAlign: []bool{false, true, true, true, true, true, true},
+		}
+		var total ComparedStats
+		for _, key := range byDelta(stats) {
+			s := stats[key]
+			t.addRow(key, strconv.Itoa(s.Previous.Commits), strconv.Itoa(s.Current.Commits),
+				strconv.Itoa(s.Previous.Insertions-s.Previous.Deletions), strconv.Itoa(s.Current.Insertions-s.Current.Deletions),
+				signed(s.Delta()), percent(s))
+			total.Previous = addStats(total.Previous, s.Previous)
+			total.Current = addStats(total.Current, s.Current)
+		}
+		t.footer = []string{"Total", strconv.Itoa(total.Previous.Commits), strconv.Itoa(total.Current.Commits),
+			strconv.Itoa(total.Previous.Insertions - total.Previous.Deletions), strconv.Itoa(total.Current.Insertions - total.Current.Deletions),
+			signed(total.Delta()), percent(total)}
+		t.render(w, style)
+	}
+
+	fmt.Fprintf(w, "%sComparing %s with %s (net lines)%s\n", blue, comparison.Current.Label, comparison.Previous.Label, reset)
+	compared("By developer:", "Author", comparison.Authors)
+	if comparison.Repos != nil {
+		compared("By repository:", "Repository", comparison.Repos)
+	}
+
+	authors := byDelta(comparison.Authors)
+	var risers, decliners []string
+	for _, author := range authors {
+		if comparison.Authors[author].Delta() > 0 && len(risers) < 3 {
+			risers = append(risers, fmt.Sprintf("%s (%s)", author, signed(comparison.Authors[author].Delta())))
+		}
+	}
+	for i := len(authors) - 1; i >= 0; i-- {
+		if comparison.Authors[authors[i]].Delta() < 0 && len(decliners) < 3 {
+			decliners = append(decliners, fmt.Sprintf("%s (%s)", authors[i], signed(comparison.Authors[authors[i]].Delta())))
+		}
+	}
+	if len(risers) > 0 || len(decliners) > 0 {
+		fmt.Fprintln(w)
+	}
+	if len(risers) > 0 {
+		fmt.Fprintf(w, "Top risers: %s\n", strings.Join(risers, ", "))
+	}
+	if len(decliners) > 0 {
+		fmt.Fprintf(w, "Top decliners: %s\n", strings.Join(decliners, ", "))
+	}
+	return nil
+}
+
+// jsonComparison is the -format=json document of -compare.
+type jsonComparison struct {
+	Previous     string                       `json:"previous"`
+	Current      string                       `json:"current"`
+	Authors      map[string]jsonComparedStats `json:"authors"`
+	Repositories map[string]jsonComparedStats `json:"repositories,omitempty"`
+}
+
+// jsonComparedStats is ComparedStats with its net change, and its change in percent
+// unless there was nothing to compare with.
+type jsonComparedStats struct {
+	ComparedStats
+	Change  int      `json:"change"`
+	Percent *float64 `json:"changePercent,omitempty"`
+}
+
+func printComparisonJSON(w io.Writer, comparison Comparison) error {
+	convert := func(stats map[string]ComparedStats) map[string]jsonComparedStats {
+		if stats == nil {
+			return nil
+		}
+		converted := make(map[string]jsonComparedStats)
+		for key, s := range stats {
+			js := jsonComparedStats{ComparedStats: s, Change: s.Delta()}
+			if p, ok := s.Percent(); ok {
+				js.Percent = &p
+			}
+			converted[key] = js
+		}
+		return converted
+	}
+	report := jsonComparison{
+		Previous:     periodKey(comparison.Previous.Label),
+		Current:      periodKey(comparison.Current.Label),
+		Authors:      convert(comparison.Authors),
+		Repositories: convert(comparison.Repos),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JSON: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package gitstats
+
+import (
+	"io"
+	"path"
+	"strings"
+)
+
+const (
+	// RootComponent holds the files at the top of a repository with Options.PathDepth.
+	RootComponent = "(root)"
+	// OtherComponent holds the files below none of Options.PathPrefixes.
+	OtherComponent = "(other)"
+)
+
+// componentFunc returns the function giving the Components key of a file path: the
+// first of prefixes it is below, or else its directory cut to depth. It returns nil when
+// neither is set.
+func componentFunc(depth int, prefixes []string) func(path string) string {
+	if len(prefixes) > 0 {
+		cleaned := make([]string, len(prefixes))
+		for i, prefix := range prefixes {
+			cleaned[i] = path.Clean(strings.Trim(prefix, "/"))
+		}
+		return func(file string) string {
+			for _, prefix := range cleaned {
+				if file == prefix || strings.HasPrefix(file, prefix+"/") {
+					return prefix
+				}
+			}
+			return OtherComponent
+		}
+	}
+	if depth <= 0 {
+		return nil
+	}
+	return func(file string) string {
+		dir := path.Dir(file)
+		if dir == "." {
+			return RootComponent
+		}
+		if parts := strings.Split(dir, "/"); len(parts) > depth {
+			return strings.Join(parts[:depth], "/")
+		}
+		return dir
+	}
+}
+
+// addComponent accumulates the changes per author of one component.
+func (gb *GlobalStats) addComponent(component string, stats map[string]ChangesStats) {
+	for author, counts := range stats {
+		if gb.Components == nil {
+			gb.Components = make(map[string]map[string]ChangesStats)
+		}
+		if gb.Components[author] == nil {
+			gb.Components[author] = make(map[string]ChangesStats)
+		}
+		addChanges(gb.Components[author], map[string]ChangesStats{component: counts})
+	}
+}
+
+// printComponents prints the changes per component, most net lines first, with the
+// authors who worked on each.
+func printComponents(w io.Writer, globalStats GlobalStats, style borderStyle) {
+	printBreakdown(w, globalStats.Components, "Lines by directory:", "Directory", "No changes", style)
+}
@@ -0,0 +1,57 @@
+package gitstats
+
+import (
+	"fmt"
+	"sort"
+)
+
+// concentrationTop is the number of largest authors whose share Concentration reports.
+const concentrationTop = 3
+
+// Concentration tells how evenly a figure such as the insertions of a month is spread
+// over the authors.
+type Concentration struct {
+	Top      int     // Largest authors TopShare is of, 3 unless there are fewer
+	TopShare float64 // Percentage of the total held by the Top largest authors
+	// Gini is the Gini coefficient of the values: 0 when everyone has as much, close to
+	// 1 when one author has everything
+	Gini float64
+}
+
+// NewConcentration returns the Concentration of the values of the authors; negative
+// values count as 0.
+func NewConcentration(values []int) Concentration {
+	sorted := make([]int, len(values))
+	total := 0
+	for i, v := range values {
+		sorted[i] = max(v, 0)
+		total += sorted[i]
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+	c := Concentration{Top: min(concentrationTop, len(sorted))}
+	if total == 0 {
+		return c
+	}
+	top := 0
+	for _, v := range sorted[:c.Top] {
+		top += v
+	}
+	c.TopShare = float64(top) * 100 / float64(total)
+
+	// With the values in ascending order, G = 2·Σ i·x_i / (n·Σ x) - (n+1)/n
+	n := float64(len(sorted))
+	weighted := 0.0
+	for i, v := range sorted {
+		weighted += (n - float64(i)) * float64(v)
+	}
+	c.Gini = 2*weighted/(n*float64(total)) - (n+1)/n
+	return c
+}
+
+// describe describes c for the authors of group, e.g. "top 3 developers 82.1%, Gini 0.47".
+func (c Concentration) describe(group string) string {
+	if c.Top != 1 {
+		group += "s"
+	}
+	return fmt.Sprintf("top %d %s %.1f%%, Gini %.2f", c.Top, group, c.TopShare, c.Gini)
+}
@@ -0,0 +1,128 @@
+package gitstats
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Condition is a comparison of a figure of the report with a number, such as
+// "total_insertions < 1000", for gating CI or monitoring on the activity of a
+// repository.
+type Condition struct {
+	Variable string // One of ConditionVariables
+	Operator string // <, <=, >, >=, == or !=
+	Value    int
+}
+
+// conditionPattern matches a condition, the longer operators first.
+var conditionPattern = regexp.MustCompile(`^\s*([a-z_]+)\s*(<=|>=|==|!=|<|>)\s*(-?[0-9]+)\s*$`)
+
+// ConditionVariables are the figures conditions compare, with what each of them is:
+// the totals of the analyzed periods, and of the latest of them with last_month_.
+var ConditionVariables = map[string]string{
+	"total_insertions":      "lines inserted",
+	"total_deletions":       "lines deleted",
+	"total_net":             "insertions minus deletions",
+	"total_commits":         "commits",
+	"authors":               "authors with at least one commit",
+	"months":                "analyzed months",
+	"last_month_insertions": "lines inserted in the latest month",
+	"last_month_deletions":  "lines deleted in the latest month",
+	"last_month_net":        "insertions minus deletions in the latest month",
+	"last_month_commits":    "commits in the latest month",
+	"last_month_authors":    "authors with at least one commit in the latest month",
+}
+
+// ParseCondition parses a condition such as "authors < 3".
+func ParseCondition(expr string) (Condition, error) {
+	match := conditionPattern.FindStringSubmatch(expr)
+	if match == nil {
+		return Condition{}, fmt.Errorf("invalid condition %q, expected a variable, an operator and a number such as total_insertions < 1000", expr)
+	}
+	if _, ok := ConditionVariables[match[1]]; !ok {
+		names := make([]string, 0, len(ConditionVariables))
+		for name := range ConditionVariables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Condition{}, fmt.Errorf("unknown variable %q in condition %q, expected one of %s", match[1], expr, strings.Join(names, ", "))
+	}
+	value, err := strconv.Atoi(match[3])
+	if err != nil {
+		return Condition{}, fmt.Errorf("invalid number in condition %q: %s", expr, err)
+	}
+	return Condition{Variable: match[1], Operator: match[2], Value: value}, nil
+}
+
+// String returns the condition as ParseCondition reads it.
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %d", c.Variable, c.Operator, c.Value)
+}
+
+// Eval reports whether the condition holds for globalStats, and the value its variable
+// has there.
+func (c Condition) Eval(globalStats GlobalStats) (bool, int) {
+	value := conditionValue(globalStats, c.Variable)
+	switch c.Operator {
+	case "<":
+		return value < c.Value, value
+	case "<=":
+		return value <= c.Value, value
+	case ">":
+		return value > c.Value, value
+	case ">=":
+		return value >= c.Value, value
+	case "==":
+		return value == c.Value, value
+	default:
+		return value != c.Value, value
+	}
+}
+
+// conditionValue returns the value of variable for globalStats. The latest month is
+// the last analyzed one, even when nobody committed in it.
+func conditionValue(globalStats GlobalStats, variable string) int {
+	months := analyzedMonths(globalStats)
+	if variable == "months" {
+		return len(months)
+	}
+	period := ""
+	if name, ok := strings.CutPrefix(variable, "last_month_"); ok {
+		if len(months) == 0 {
+			return 0
+		}
+		period, variable = months[len(months)-1], name
+	} else {
+		variable = strings.TrimPrefix(variable, "total_")
+	}
+
+	var total ChangesStats
+	authors := 0
+	for _, authorMonths := range globalStats.Stats {
+		var stats ChangesStats
+		for month, monthStats := range authorMonths {
+			if period == "" || month == period {
+				stats = addStats(stats, monthStats)
+			}
+		}
+		if stats.Commits > 0 {
+			authors++
+		}
+		total = addStats(total, stats)
+	}
+	switch variable {
+	case "insertions":
+		return total.Insertions
+	case "deletions":
+		return total.Deletions
+	case "net":
+		return total.Insertions - total.Deletions
+	case "commits":
+		return total.Commits
+	default:
+		return authors
+	}
+}
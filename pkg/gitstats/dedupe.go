@@ -0,0 +1,88 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// seedDuplicates marks in seen, for every repository of dirs, the commits of the
+// analyzed periods that an earlier repository of dirs has as well, so the commits of
+// forks and mirrors are counted once, in the first repository that has them. It
+// returns the number of commits marked per repository. A repository whose commits
+// cannot be listed is left as it is.
+func (c *collector) seedDuplicates(dirs []string, seen map[string]map[string]bool) []int {
+	hashes := make([][]string, len(dirs))
+	bucket := periodBucket(c.opts.Periods, c.opts.Location)
+	c.eachRepo(dirs, func(i int, dir string) {
+		args := append(c.gitArgs(dir), "log", "--format=%H%x09%at%x09%ct")
+		args = append(args, c.revArgs()...)
+		args = append(args, c.mergeArgs())
+		// The same margin of a day as scanDir, the periods decide
+		scan := c.window
+		if !scan.Since.IsZero() {
+			scan.Since = scan.Since.AddDate(0, 0, -1)
+		}
+		scan.Until = scan.Until.AddDate(0, 0, 1)
+		args = append(args, scan.ArgsIn(c.opts.Location)...)
+		args = append(args, c.logPathspec(dir)...)
+		output, err := c.git(args...)
+		if err != nil {
+			c.debug.Debug("listing commits failed", "repo", dir, "err", err)
+			return
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) != 3 {
+				continue
+			}
+			if _, ok := bucket(parseCommitTime(fields[1]), parseCommitTime(fields[2])); ok {
+				hashes[i] = append(hashes[i], fields[0])
+			}
+		}
+	})
+
+	duplicates := make([]int, len(dirs))
+	counted := make(map[string]bool)
+	for i, dir := range dirs {
+		for _, hash := range hashes[i] {
+			if counted[hash] {
+				seen[dir][hash] = true
+				duplicates[i]++
+			}
+		}
+		for _, hash := range hashes[i] {
+			counted[hash] = true
+		}
+	}
+	return duplicates
+}
+
+// printDuplicates prints the commits skipped per repository as they were counted in
+// another one, with the most duplicated repositories first.
+func printDuplicates(w io.Writer, globalStats GlobalStats, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	fmt.Fprintf(w, "\n%sDuplicate commits skipped (forks and mirrors, counted in the first repository having them):%s\n", blue, reset)
+	repos := make([]string, 0, len(globalStats.Duplicates))
+	total := 0
+	for repo, count := range globalStats.Duplicates {
+		repos = append(repos, repo)
+		total += count
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if globalStats.Duplicates[repos[i]] != globalStats.Duplicates[repos[j]] {
+			return globalStats.Duplicates[repos[i]] > globalStats.Duplicates[repos[j]]
+		}
+		return repos[i] < repos[j]
+	})
+	t := table{header: []string{"Repository", "Commits"}, rightAlign: []bool{false, true}}
+	for _, repo := range repos {
+		t.addRow(repo, strconv.Itoa(globalStats.Duplicates[repo]))
+	}
+	t.footer = []string{"Total", strconv.Itoa(total)}
+	t.render(w, style)
+}
@@ -0,0 +1,43 @@
+package gitstats_test
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"git.otiumsoft.com/otiumcommon/gitstats/pkg/gitstats"
+)
+
+// recordedGit serves a recorded git log, so the example runs without a repository.
+type recordedGit struct{}
+
+func (recordedGit) Run(args ...string) ([]byte, error) {
+	if !slices.Contains(args, "log") {
+		return nil, fmt.Errorf("unexpected command %q", args)
+	}
+	return []byte("a1\talice@example.com\t2024-03-05T12:00:00Z\tAlice\n" +
+		" 1 file changed, 4 insertions(+), 1 deletion(-)\n" +
+		"b1\tbob@example.com\t2024-03-06T12:00:00Z\tBob\n" +
+		" 2 files changed, 7 insertions(+)\n"), nil
+}
+
+// A service embeds the analysis by collecting the stats of a repository and rendering
+// them, here as CSV.
+func Example() {
+	periods := gitstats.MonthPeriods(1, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	gb, err := gitstats.Collect(gitstats.Options{Path: ".", Periods: periods, Git: recordedGit{}})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(gb.Totals().Insertions, "insertions")
+	if err := gitstats.Render(os.Stdout, gb, gitstats.RenderOptions{Format: "csv", Header: true}); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// 11 insertions
+	// author,month,insertions,deletions,commits
+	// alice@example.com,(2024-03) March 2024,4,1,1
+	// bob@example.com,(2024-03) March 2024,7,0,1
+}
@@ -0,0 +1,70 @@
+package gitstats
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureRepo is a throwaway git repository for tests.
+type fixtureRepo struct {
+	t   *testing.T
+	Dir string
+}
+
+func newFixtureRepo(t *testing.T) *fixtureRepo {
+	t.Helper()
+	return newFixtureRepoAt(t, t.TempDir())
+}
+
+// newFixtureRepoAt creates the repository in dir, making the directory if needed.
+func newFixtureRepoAt(t *testing.T, dir string) *fixtureRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	repo := &fixtureRepo{t: t, Dir: dir}
+	repo.git("init", "-q")
+	return repo
+}
+
+func (r *fixtureRepo) git(args ...string) string {
+	r.t.Helper()
+	return r.gitEnv(nil, args...)
+}
+
+func (r *fixtureRepo) gitEnv(env []string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", r.Dir}, args...)...)
+	cmd.Env = append(append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+r.Dir), env...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+func (r *fixtureRepo) write(name, content string) {
+	r.t.Helper()
+	path := filepath.Join(r.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit commits all changes as email at date (RFC 3339).
+func (r *fixtureRepo) commit(email, date, message string) {
+	r.t.Helper()
+	r.git("add", "-A")
+	// The committer date drives --since/--until, so keep it equal to the author date
+	r.gitEnv([]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date},
+		"-c", "user.name="+email, "-c", "user.email="+email, "commit", "-q", "--allow-empty", "-m", message)
+}
@@ -0,0 +1,98 @@
+package gitstats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Git runs the git commands of a Collector and returns their standard output. Every
+// command names its repository with "-C <dir>" ahead of the subcommand. Implementations
+// return ErrNotRepository when the directory is not a git repository, and must be safe
+// for concurrent use, as repositories are processed in parallel.
+//
+// ExecGit, the default, runs the git binary. Another Git can serve the commands from a
+// library or a recorded fixture, e.g. in containers without git, as long as its output
+// matches the formats git prints for them.
+type Git interface {
+	Run(args ...string) ([]byte, error)
+}
+
+// StreamGit is a Git that can also hand over the output of a command while it runs.
+// Collect reads the git log of a repository from Stream when its Git implements it,
+// so that the whole log of a large history is never held in memory.
+type StreamGit interface {
+	Git
+	// Stream runs git with args and calls read with its standard output. It returns
+	// the error of read, or else the failure of the command like Run does.
+	Stream(read func(output io.Reader) error, args ...string) error
+}
+
+// ExecGit runs the git binary found in PATH, or at Path when set. Commands run with
+// LC_ALL=C so that git's messages, such as the --shortstat summary, are not translated.
+type ExecGit struct {
+	Path string
+}
+
+// Run runs git with args. Failures carry git's own error message, and
+// ErrNotRepository when the directory is not a git repository.
+func (g ExecGit) Run(args ...string) ([]byte, error) {
+	path := g.Path
+	if path == "" {
+		path = "git"
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, execError(err, exitErr.Stderr)
+		}
+		return nil, execError(err, nil)
+	}
+	return output, nil
+}
+
+// Stream runs git with args, passing its standard output to read through a pipe.
+func (g ExecGit) Stream(read func(output io.Reader) error, args ...string) error {
+	path := g.Path
+	if path == "" {
+		path = "git"
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return execError(err, nil)
+	}
+	if err := cmd.Start(); err != nil {
+		return execError(err, nil)
+	}
+	readErr := read(stdout)
+	// git must not block on a full pipe when read stopped early
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return execError(err, stderr.Bytes())
+	}
+	return readErr
+}
+
+// execError describes the failure err of a git command with its stderr output, or
+// returns ErrNotRepository when the directory is not a git repository.
+func execError(err error, stderr []byte) error {
+	message := strings.TrimSpace(string(stderr))
+	if strings.Contains(message, "not a git repository") {
+		return ErrNotRepository
+	}
+	if message != "" {
+		return fmt.Errorf("failed to execute command: %s: %s", err, message)
+	}
+	return fmt.Errorf("failed to execute command: %s", err)
+}
@@ -0,0 +1,340 @@
+package gitstats
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitHubGit is a Git serving the log pass of Collect from the GitHub GraphQL API, so
+// repositories are analyzed without cloning them. Repositories are named "owner/name"
+// in Options.Repos, e.g. those of GitHubGit.OrgRepos. The API only reports the totals
+// of each commit, so file selections (Extensions, Paths, Excludes) don't apply, and
+// the passes needing more than the log, such as ByLanguage, Tags or the checks of
+// CacheDir, fail.
+type GitHubGit struct {
+	Token  string       // Personal access token, required by the GraphQL API
+	URL    string       // API root, https://api.github.com by default
+	Client *http.Client // http.Client with a timeout by default
+}
+
+func (g GitHubGit) url(path string) string {
+	root := g.URL
+	if root == "" {
+		root = "https://api.github.com"
+	}
+	return strings.TrimSuffix(root, "/") + path
+}
+
+// Run answers the git log commands of the main pass of Collect, and rev-parse --verify
+// for the Branch.
+func (g GitHubGit) Run(args ...string) ([]byte, error) {
+	repo, command, rest := parseRemoteCommand(args)
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("%s: GitHub repositories are named owner/name", repo)
+	}
+	switch command {
+	case "log":
+		request, err := parseLogRequest(repo, rest)
+		if err != nil {
+			return nil, err
+		}
+		commits, err := g.history(owner, name, request)
+		if err != nil {
+			return nil, err
+		}
+		return formatLog(commits, request.noMerges), nil
+	case "rev-parse":
+		if len(rest) == 3 && rest[0] == "--verify" && rest[1] == "--quiet" {
+			return g.verify(owner, name, strings.TrimSuffix(rest[2], "^{commit}"))
+		}
+	}
+	return nil, fmt.Errorf("git %s is not available through the GitHub API", command)
+}
+
+// githubCommitFields are the fields of the commits of a history query.
+const githubCommitFields = `fragment commits on Commit {
+  history(first: 100, after: $cursor, since: $since, until: $until) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      oid additions deletions changedFilesIfAvailable authoredDate committedDate
+      parents { totalCount }
+      author { name email }
+      committer { name email }
+    }
+  }
+}`
+
+type githubCommit struct {
+	OID          string    `json:"oid"`
+	Additions    int       `json:"additions"`
+	Deletions    int       `json:"deletions"`
+	ChangedFiles int       `json:"changedFilesIfAvailable"`
+	Authored     time.Time `json:"authoredDate"`
+	Committed    time.Time `json:"committedDate"`
+	Parents      struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"parents"`
+	Author    githubActor `json:"author"`
+	Committer githubActor `json:"committer"`
+}
+
+type githubActor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type githubHistory struct {
+	History struct {
+		PageInfo struct {
+			HasNextPage bool   `json:"hasNextPage"`
+			EndCursor   string `json:"endCursor"`
+		} `json:"pageInfo"`
+		Nodes []githubCommit `json:"nodes"`
+	} `json:"history"`
+}
+
+type githubRepository struct {
+	Object           *githubHistory `json:"object"`
+	DefaultBranchRef *struct {
+		Target *githubHistory `json:"target"`
+	} `json:"defaultBranchRef"`
+}
+
+// graphql runs query with variables and decodes its data into v.
+func (g GitHubGit) graphql(query string, variables map[string]any, v any) error {
+	var response struct {
+		Data   any `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	response.Data = v
+	body := map[string]any{"query": query, "variables": variables}
+	if err := requestJSON(g.Client, http.MethodPost, g.url("/graphql"), "Authorization", "Bearer "+g.Token, body, &response); err != nil {
+		return err
+	}
+	if len(response.Errors) > 0 {
+		return fmt.Errorf("GitHub API: %s", response.Errors[0].Message)
+	}
+	return nil
+}
+
+// history returns the commits of request, newest first, 100 per query.
+func (g GitHubGit) history(owner, name string, request logRequest) ([]remoteCommit, error) {
+	selection := "defaultBranchRef { target { ...commits } }"
+	declarations := ""
+	variables := map[string]any{"owner": owner, "name": name}
+	if request.rev != "" {
+		selection = "object(expression: $rev) { ...commits }"
+		declarations = ", $rev: String!"
+		variables["rev"] = request.rev
+	}
+	query := "query($owner: String!, $name: String!, $since: GitTimestamp, $until: GitTimestamp, $cursor: String" + declarations + ") {\n" +
+		"  repository(owner: $owner, name: $name) { " + selection + " }\n}\n" + githubCommitFields
+	if !request.since.IsZero() {
+		variables["since"] = request.since.Format(time.RFC3339)
+	}
+	if !request.until.IsZero() {
+		variables["until"] = request.until.Format(time.RFC3339)
+	}
+
+	var commits []remoteCommit
+	for {
+		var data struct {
+			Repository *githubRepository `json:"repository"`
+		}
+		if err := g.graphql(query, variables, &data); err != nil {
+			return nil, fmt.Errorf("failed to read the history of %s/%s: %s", owner, name, err)
+		}
+		if data.Repository == nil {
+			return nil, fmt.Errorf("failed to read the history of %s/%s: repository not found", owner, name)
+		}
+		history := data.Repository.Object
+		if request.rev == "" {
+			if data.Repository.DefaultBranchRef == nil {
+				// An empty repository has no default branch
+				return nil, nil
+			}
+			history = data.Repository.DefaultBranchRef.Target
+		}
+		if history == nil {
+			return nil, fmt.Errorf("failed to read the history of %s/%s: no commit %s", owner, name, request.rev)
+		}
+		for _, node := range history.History.Nodes {
+			commits = append(commits, remoteCommit{
+				Hash:           node.OID,
+				AuthorEmail:    node.Author.Email,
+				AuthorName:     node.Author.Name,
+				Authored:       node.Authored,
+				CommitterName:  node.Committer.Name,
+				CommitterEmail: node.Committer.Email,
+				Committed:      node.Committed,
+				Insertions:     node.Additions,
+				Deletions:      node.Deletions,
+				Files:          node.ChangedFiles,
+				Parents:        node.Parents.TotalCount,
+			})
+		}
+		if !history.History.PageInfo.HasNextPage {
+			return commits, nil
+		}
+		variables["cursor"] = history.History.PageInfo.EndCursor
+	}
+}
+
+// verify returns the hash of the commit rev names, like git rev-parse --verify.
+func (g GitHubGit) verify(owner, name, rev string) ([]byte, error) {
+	var data struct {
+		Repository *struct {
+			Object *struct {
+				OID string `json:"oid"`
+			} `json:"object"`
+		} `json:"repository"`
+	}
+	query := `query($owner: String!, $name: String!, $rev: String!) {
+  repository(owner: $owner, name: $name) { object(expression: $rev) { ... on Commit { oid } } }
+}`
+	if err := g.graphql(query, map[string]any{"owner": owner, "name": name, "rev": rev}, &data); err != nil {
+		return nil, err
+	}
+	if data.Repository == nil || data.Repository.Object == nil || data.Repository.Object.OID == "" {
+		return nil, fmt.Errorf("%s/%s: no commit %s", owner, name, rev)
+	}
+	return []byte(data.Repository.Object.OID + "\n"), nil
+}
+
+// githubPullRequestsQuery lists the pull requests of a repository, most recently updated
+// first, with their first 100 reviews.
+const githubPullRequestsQuery = `query($owner: String!, $name: String!, $cursor: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequests(first: 50, after: $cursor, orderBy: {field: UPDATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        createdAt updatedAt mergedAt
+        author { login }
+        reviews(first: 100) { nodes { author { login } state submittedAt comments { totalCount } } }
+      }
+    }
+  }
+}`
+
+type githubLogin struct {
+	Login string `json:"login"`
+}
+
+type githubPullRequest struct {
+	Created time.Time    `json:"createdAt"`
+	Updated time.Time    `json:"updatedAt"`
+	Merged  *time.Time   `json:"mergedAt"`
+	Author  *githubLogin `json:"author"`
+	Reviews struct {
+		Nodes []struct {
+			Author    *githubLogin `json:"author"`
+			State     string       `json:"state"`
+			Submitted *time.Time   `json:"submittedAt"`
+			Comments  struct {
+				TotalCount int `json:"totalCount"`
+			} `json:"comments"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+}
+
+// githubLoginOf returns the login of actor, "ghost" like on GitHub for deleted accounts.
+func githubLoginOf(actor *githubLogin) string {
+	if actor == nil || actor.Login == "" {
+		return "ghost"
+	}
+	return actor.Login
+}
+
+// ReviewEvents returns the pull request activity of the repository owner/name from
+// since until until. Every submitted review counts, with its comments; those of the
+// author of the pull request are left out.
+func (g GitHubGit) ReviewEvents(repo string, since, until time.Time) ([]ReviewEvent, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("%s: GitHub repositories are named owner/name", repo)
+	}
+	in := func(t time.Time) bool { return !t.Before(since) && t.Before(until) }
+
+	var events []ReviewEvent
+	variables := map[string]any{"owner": owner, "name": name}
+	for {
+		var data struct {
+			Repository *struct {
+				PullRequests struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []githubPullRequest `json:"nodes"`
+				} `json:"pullRequests"`
+			} `json:"repository"`
+		}
+		if err := g.graphql(githubPullRequestsQuery, variables, &data); err != nil {
+			return nil, fmt.Errorf("failed to read the pull requests of %s: %s", repo, err)
+		}
+		if data.Repository == nil {
+			return nil, fmt.Errorf("failed to read the pull requests of %s: repository not found", repo)
+		}
+		pulls := data.Repository.PullRequests
+		for _, pr := range pulls.Nodes {
+			if pr.Updated.Before(since) {
+				// Nothing older was updated in the window
+				return events, nil
+			}
+			author := githubLoginOf(pr.Author)
+			if in(pr.Created) {
+				events = append(events, ReviewEvent{Kind: ReviewOpened, Author: author, Time: pr.Created})
+			}
+			if pr.Merged != nil && in(*pr.Merged) {
+				events = append(events, ReviewEvent{Kind: ReviewMerged, Author: author, Time: *pr.Merged})
+			}
+			for _, review := range pr.Reviews.Nodes {
+				reviewer := githubLoginOf(review.Author)
+				if review.Submitted == nil || !in(*review.Submitted) || review.State == "PENDING" || reviewer == author {
+					continue
+				}
+				events = append(events, ReviewEvent{Kind: ReviewGiven, Author: reviewer, Time: *review.Submitted})
+				if review.State == "APPROVED" {
+					events = append(events, ReviewEvent{Kind: ReviewApproved, Author: reviewer, Time: *review.Submitted})
+				}
+				for range review.Comments.TotalCount {
+					events = append(events, ReviewEvent{Kind: ReviewComment, Author: reviewer, Time: *review.Submitted})
+				}
+			}
+		}
+		if !pulls.PageInfo.HasNextPage {
+			return events, nil
+		}
+		variables["cursor"] = pulls.PageInfo.EndCursor
+	}
+}
+
+// OrgRepos returns the repositories of the GitHub organization org as owner/name,
+// leaving out archived ones.
+func (g GitHubGit) OrgRepos(org string) ([]string, error) {
+	var repos []string
+	for page := 1; ; page++ {
+		var list []struct {
+			FullName string `json:"full_name"`
+			Archived bool   `json:"archived"`
+		}
+		path := fmt.Sprintf("/orgs/%s/repos?per_page=100&page=%d", url.PathEscape(org), page)
+		if err := requestJSON(g.Client, http.MethodGet, g.url(path), "Authorization", "Bearer "+g.Token, nil, &list); err != nil {
+			return nil, fmt.Errorf("failed to list the repositories of %s: %s", org, err)
+		}
+		for _, repo := range list {
+			if !repo.Archived {
+				repos = append(repos, repo.FullName)
+			}
+		}
+		if len(list) < 100 {
+			return repos, nil
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package gitstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitHubGitCollect(t *testing.T) {
+	var queries []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "Bad credentials", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		queries = append(queries, body.Variables)
+		// Two pages: a merge and a commit, then an older commit
+		page := `{"pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "nodes": [
+			{"oid": "m1", "additions": 50, "deletions": 5, "authoredDate": "2024-03-20T10:00:00Z", "committedDate": "2024-03-20T10:00:00Z",
+			 "parents": {"totalCount": 2}, "author": {"name": "Alice", "email": "alice@x.com"}, "committer": {"name": "Alice", "email": "alice@x.com"}},
+			{"oid": "a1", "additions": 10, "deletions": 2, "authoredDate": "2024-03-15T10:00:00Z", "committedDate": "2024-03-15T10:00:00Z",
+			 "parents": {"totalCount": 1}, "author": {"name": "Alice", "email": "Alice@X.com"}, "committer": {"name": "Alice", "email": "alice@x.com"}}]}`
+		if body.Variables["cursor"] == "c1" {
+			page = `{"pageInfo": {"hasNextPage": false}, "nodes": [
+			{"oid": "b1", "additions": 3, "deletions": 0, "authoredDate": "2024-03-02T10:00:00Z", "committedDate": "2024-03-02T10:00:00Z",
+			 "parents": {"totalCount": 1}, "author": {"name": "Bob", "email": "bob@x.com"}, "committer": {"name": "Bob", "email": "bob@x.com"}}]}`
+		}
+		w.Write([]byte(`{"data": {"repository": {"defaultBranchRef": {"target": {"history": ` + page + `}}}}}`))
+	}))
+	defer server.Close()
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Git:      GitHubGit{Token: "secret", URL: server.URL},
+		Repos:    []string{"acme/api"},
+		Periods:  []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Location: time.UTC,
+		ByRepo:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The merge counts as a commit without lines, like in git log
+	want := map[string]ChangesStats{
+		"alice@x.com": {Insertions: 10, Deletions: 2, Commits: 2},
+		"bob@x.com":   {Insertions: 3, Commits: 1},
+	}
+	for author, stats := range want {
+		if got := gb.Stats[author]["march"]; got != stats {
+			t.Errorf("%s: got %+v, want %+v", author, got, stats)
+		}
+	}
+	if gb.Repos["acme/api"] == nil {
+		t.Errorf("repos: got %v, want acme/api", gb.Repos)
+	}
+	if len(queries) != 2 || !strings.HasPrefix(queries[0]["since"].(string), "2024-02-29") || queries[0]["owner"] != "acme" {
+		t.Errorf("queries: got %v", queries)
+	}
+
+	_, err = Collect(Options{Git: GitHubGit{Token: "wrong", URL: server.URL}, Repos: []string{"acme/api"}})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("bad token: got %v, want 401", err)
+	}
+}
+
+func TestGitHubGitFileSelection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer server.Close()
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	_, err := Collect(Options{
+		Git:        GitHubGit{Token: "secret", URL: server.URL},
+		Repos:      []string{"acme/api"},
+		Periods:    []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Extensions: []string{"go"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "not available remotely") {
+		t.Errorf("err = %v, want the file selection refused", err)
+	}
+}
+
+func TestGitHubGitReviews(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !strings.Contains(body.Query, "pullRequests") {
+			w.Write([]byte(`{"data": {"repository": {"defaultBranchRef": {"target": {"history": {"pageInfo": {"hasNextPage": false}, "nodes": []}}}}}}`))
+			return
+		}
+		// The second pull request was last updated before the window, ending the listing
+		w.Write([]byte(`{"data": {"repository": {"pullRequests": {"pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "nodes": [
+			{"createdAt": "2024-03-04T10:00:00Z", "updatedAt": "2024-04-02T10:00:00Z", "mergedAt": "2024-04-02T10:00:00Z", "author": {"login": "alice"},
+			 "reviews": {"nodes": [
+				{"author": {"login": "bob"}, "state": "CHANGES_REQUESTED", "submittedAt": "2024-03-05T10:00:00Z", "comments": {"totalCount": 3}},
+				{"author": {"login": "alice"}, "state": "COMMENTED", "submittedAt": "2024-03-06T10:00:00Z", "comments": {"totalCount": 2}},
+				{"author": {"login": "bob"}, "state": "APPROVED", "submittedAt": "2024-04-01T10:00:00Z", "comments": {"totalCount": 0}},
+				{"author": {"login": "renovate[bot]"}, "state": "COMMENTED", "submittedAt": "2024-03-07T10:00:00Z", "comments": {"totalCount": 1}},
+				{"author": {"login": "carol"}, "state": "PENDING", "submittedAt": null, "comments": {"totalCount": 1}}]}},
+			{"createdAt": "2024-01-04T10:00:00Z", "updatedAt": "2024-02-01T10:00:00Z", "mergedAt": null, "author": {"login": "dave"},
+			 "reviews": {"nodes": []}}]}}}}`))
+	}))
+	defer server.Close()
+
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+	gb, err := Collect(Options{
+		Git:         GitHubGit{Token: "secret", URL: server.URL},
+		Repos:       []string{"acme/api"},
+		Periods:     []Period{{Label: "march", Since: march, Until: april.AddDate(0, 0, -1)}, {Label: "april", Since: april, Until: april.AddDate(0, 1, -1)}},
+		Location:    time.UTC,
+		Reviews:     true,
+		ExcludeBots: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]ReviewStats{
+		"alice": {"march": {Opened: 1}, "april": {Merged: 1}},
+		"bob":   {"march": {Reviews: 1, Comments: 3}, "april": {Reviews: 1, Approvals: 1}},
+	}
+	if fmt.Sprint(gb.Reviews) != fmt.Sprint(want) {
+		t.Errorf("reviews = %v, want %v", gb.Reviews, want)
+	}
+
+	var out strings.Builder
+	if err := Render(&out, gb, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"bob         0       0        2          1         3", "Total       1       1        2          1         3", "april       0       1        1          1         0"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("report lacks %q:\n%s", line, out.String())
+		}
+	}
+
+	if _, err := Collect(Options{Repos: []string{"."}, Periods: []Period{{Label: "march", Since: march, Until: april}}, Reviews: true}); err == nil {
+		t.Error("reviews without a ReviewSource: want error")
+	}
+}
@@ -0,0 +1,230 @@
+package gitstats
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GitLabGit is a Git serving the log pass of Collect from the GitLab REST API, like
+// GitHubGit. Repositories are named by their project path, e.g. "group/subgroup/api",
+// in Options.Repos, such as those of GitLabGit.GroupProjects. The same limits as for
+// GitHubGit apply.
+type GitLabGit struct {
+	Token  string       // Personal or group access token with the read_api scope
+	URL    string       // Instance, https://gitlab.com by default
+	Client *http.Client // http.Client with a timeout by default
+}
+
+func (g GitLabGit) get(path string, query url.Values, v any) error {
+	root := g.URL
+	if root == "" {
+		root = "https://gitlab.com"
+	}
+	u := strings.TrimSuffix(root, "/") + "/api/v4" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return requestJSON(g.Client, http.MethodGet, u, "PRIVATE-TOKEN", g.Token, nil, v)
+}
+
+// Run answers the git log commands of the main pass of Collect, and rev-parse --verify
+// for the Branch.
+func (g GitLabGit) Run(args ...string) ([]byte, error) {
+	project, command, rest := parseRemoteCommand(args)
+	switch command {
+	case "log":
+		request, err := parseLogRequest(project, rest)
+		if err != nil {
+			return nil, err
+		}
+		commits, err := g.commits(request)
+		if err != nil {
+			return nil, err
+		}
+		return formatLog(commits, request.noMerges), nil
+	case "rev-parse":
+		if len(rest) == 3 && rest[0] == "--verify" && rest[1] == "--quiet" {
+			var commit struct {
+				ID string `json:"id"`
+			}
+			rev := strings.TrimSuffix(rest[2], "^{commit}")
+			if err := g.get("/projects/"+url.PathEscape(project)+"/repository/commits/"+url.PathEscape(rev), nil, &commit); err != nil {
+				return nil, fmt.Errorf("%s: no commit %s: %s", project, rev, err)
+			}
+			return []byte(commit.ID + "\n"), nil
+		}
+	}
+	return nil, fmt.Errorf("git %s is not available through the GitLab API", command)
+}
+
+type gitlabCommit struct {
+	ID             string    `json:"id"`
+	AuthorName     string    `json:"author_name"`
+	AuthorEmail    string    `json:"author_email"`
+	AuthoredDate   time.Time `json:"authored_date"`
+	CommitterName  string    `json:"committer_name"`
+	CommitterEmail string    `json:"committer_email"`
+	CommittedDate  time.Time `json:"committed_date"`
+	ParentIDs      []string  `json:"parent_ids"`
+	Stats          struct {
+		Additions int `json:"additions"`
+		Deletions int `json:"deletions"`
+	} `json:"stats"`
+}
+
+// commits returns the commits of request, newest first, 100 per request.
+func (g GitLabGit) commits(request logRequest) ([]remoteCommit, error) {
+	query := url.Values{"with_stats": {"true"}, "per_page": {"100"}}
+	if request.rev != "" {
+		query.Set("ref_name", request.rev)
+	}
+	if !request.since.IsZero() {
+		query.Set("since", request.since.Format(time.RFC3339))
+	}
+	if !request.until.IsZero() {
+		query.Set("until", request.until.Format(time.RFC3339))
+	}
+
+	var commits []remoteCommit
+	for page := 1; ; page++ {
+		query.Set("page", fmt.Sprint(page))
+		var list []gitlabCommit
+		if err := g.get("/projects/"+url.PathEscape(request.repo)+"/repository/commits", query, &list); err != nil {
+			return nil, fmt.Errorf("failed to read the history of %s: %s", request.repo, err)
+		}
+		for _, commit := range list {
+			commits = append(commits, remoteCommit{
+				Hash:           commit.ID,
+				AuthorEmail:    commit.AuthorEmail,
+				AuthorName:     commit.AuthorName,
+				Authored:       commit.AuthoredDate,
+				CommitterName:  commit.CommitterName,
+				CommitterEmail: commit.CommitterEmail,
+				Committed:      commit.CommittedDate,
+				Insertions:     commit.Stats.Additions,
+				Deletions:      commit.Stats.Deletions,
+				Parents:        len(commit.ParentIDs),
+			})
+		}
+		if len(list) < 100 {
+			return commits, nil
+		}
+	}
+}
+
+type gitlabUser struct {
+	Username string `json:"username"`
+}
+
+// gitlabApproved is the body of the system note GitLab adds for an approval.
+const gitlabApproved = "approved this merge request"
+
+// ReviewEvents returns the merge request activity of project from since until until.
+// GitLab has no reviews of their own: a person reviews a merge request of someone else
+// when they first comment on or approve it in the window, and their comments and the
+// approvals are those notes.
+func (g GitLabGit) ReviewEvents(project string, since, until time.Time) ([]ReviewEvent, error) {
+	in := func(t time.Time) bool { return !t.Before(since) && t.Before(until) }
+	path := "/projects/" + url.PathEscape(project) + "/merge_requests"
+	query := url.Values{
+		"scope": {"all"}, "per_page": {"100"},
+		"updated_after": {since.Format(time.RFC3339)}, "updated_before": {until.Format(time.RFC3339)},
+	}
+
+	var events []ReviewEvent
+	for page := 1; ; page++ {
+		query.Set("page", fmt.Sprint(page))
+		var list []struct {
+			IID     int        `json:"iid"`
+			Created time.Time  `json:"created_at"`
+			Merged  *time.Time `json:"merged_at"`
+			Author  gitlabUser `json:"author"`
+		}
+		if err := g.get(path, query, &list); err != nil {
+			return nil, fmt.Errorf("failed to read the merge requests of %s: %s", project, err)
+		}
+		for _, mr := range list {
+			if in(mr.Created) {
+				events = append(events, ReviewEvent{Kind: ReviewOpened, Author: mr.Author.Username, Time: mr.Created})
+			}
+			if mr.Merged != nil && in(*mr.Merged) {
+				events = append(events, ReviewEvent{Kind: ReviewMerged, Author: mr.Author.Username, Time: *mr.Merged})
+			}
+			notes, err := g.notes(fmt.Sprintf("%s/%d/notes", path, mr.IID))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the notes of %s!%d: %s", project, mr.IID, err)
+			}
+			reviewed := make(map[string]bool)
+			for _, note := range notes {
+				reviewer := note.Author.Username
+				if reviewer == mr.Author.Username || !in(note.Created) {
+					continue
+				}
+				kind := ReviewComment
+				if note.System {
+					if note.Body != gitlabApproved {
+						continue
+					}
+					kind = ReviewApproved
+				}
+				if !reviewed[reviewer] {
+					reviewed[reviewer] = true
+					events = append(events, ReviewEvent{Kind: ReviewGiven, Author: reviewer, Time: note.Created})
+				}
+				events = append(events, ReviewEvent{Kind: kind, Author: reviewer, Time: note.Created})
+			}
+		}
+		if len(list) < 100 {
+			return events, nil
+		}
+	}
+}
+
+type gitlabNote struct {
+	Body    string     `json:"body"`
+	System  bool       `json:"system"`
+	Created time.Time  `json:"created_at"`
+	Author  gitlabUser `json:"author"`
+}
+
+// notes returns the notes of the merge request at path, oldest first.
+func (g GitLabGit) notes(path string) ([]gitlabNote, error) {
+	query := url.Values{"sort": {"asc"}, "order_by": {"created_at"}, "per_page": {"100"}}
+	var notes []gitlabNote
+	for page := 1; ; page++ {
+		query.Set("page", fmt.Sprint(page))
+		var list []gitlabNote
+		if err := g.get(path, query, &list); err != nil {
+			return nil, err
+		}
+		notes = append(notes, list...)
+		if len(list) < 100 {
+			return notes, nil
+		}
+	}
+}
+
+// GroupProjects returns the paths of the projects of the GitLab group, including
+// those of its subgroups, leaving out archived ones.
+func (g GitLabGit) GroupProjects(group string) ([]string, error) {
+	var projects []string
+	query := url.Values{"include_subgroups": {"true"}, "archived": {"false"}, "per_page": {"100"}}
+	for page := 1; ; page++ {
+		query.Set("page", fmt.Sprint(page))
+		var list []struct {
+			Path string `json:"path_with_namespace"`
+		}
+		if err := g.get("/groups/"+url.PathEscape(group)+"/projects", query, &list); err != nil {
+			return nil, fmt.Errorf("failed to list the projects of %s: %s", group, err)
+		}
+		for _, project := range list {
+			projects = append(projects, project.Path)
+		}
+		if len(list) < 100 {
+			return projects, nil
+		}
+	}
+}
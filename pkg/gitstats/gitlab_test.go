@@ -0,0 +1,112 @@
+package gitstats
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGitLabGitCollect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			http.Error(w, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/api/v4/groups/acme/projects":
+			if r.URL.Query().Get("include_subgroups") != "true" {
+				t.Errorf("group projects without subgroups: %s", r.URL)
+			}
+			w.Write([]byte(`[{"path_with_namespace": "acme/api"}, {"path_with_namespace": "acme/tools/cli"}]`))
+		case "/api/v4/projects/acme%2Fapi/repository/commits":
+			if r.URL.Query().Get("with_stats") != "true" {
+				t.Errorf("commits without stats: %s", r.URL)
+			}
+			w.Write([]byte(`[{"id": "a1", "author_name": "Alice", "author_email": "alice@x.com", "authored_date": "2024-03-15T10:00:00Z",
+				"committer_name": "Alice", "committer_email": "alice@x.com", "committed_date": "2024-03-15T10:00:00Z",
+				"parent_ids": ["p1"], "stats": {"additions": 10, "deletions": 2}}]`))
+		case "/api/v4/projects/acme%2Ftools%2Fcli/repository/commits":
+			w.Write([]byte(`[{"id": "b1", "author_name": "Alice", "author_email": "alice@x.com", "authored_date": "2024-03-02T10:00:00Z",
+				"committer_name": "Alice", "committer_email": "alice@x.com", "committed_date": "2024-03-02T10:00:00Z",
+				"parent_ids": ["p2"], "stats": {"additions": 5, "deletions": 1}}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	gitlab := GitLabGit{Token: "secret", URL: server.URL}
+	projects, err := gitlab.GroupProjects("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(projects) != "[acme/api acme/tools/cli]" {
+		t.Errorf("projects: got %v", projects)
+	}
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Git:      gitlab,
+		Repos:    projects,
+		Periods:  []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Location: time.UTC,
+		ByRepo:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gb.Stats["alice@x.com"]["march"], (ChangesStats{Insertions: 15, Deletions: 3, Commits: 2}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := gb.Repos["acme/tools/cli"].Stats["alice@x.com"]["march"].Insertions; got != 5 {
+		t.Errorf("acme/tools/cli: got %d insertions, want 5", got)
+	}
+}
+
+func TestGitLabGitReviews(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/acme%2Fapi/merge_requests":
+			if r.URL.Query().Get("scope") != "all" || r.URL.Query().Get("updated_after") == "" {
+				t.Errorf("merge requests: %s", r.URL)
+			}
+			w.Write([]byte(`[{"iid": 7, "created_at": "2024-03-04T10:00:00Z", "merged_at": "2024-03-09T10:00:00Z", "author": {"username": "alice"}}]`))
+		case "/api/v4/projects/acme%2Fapi/merge_requests/7/notes":
+			w.Write([]byte(`[
+				{"body": "Why?", "system": false, "created_at": "2024-03-05T10:00:00Z", "author": {"username": "bob"}},
+				{"body": "Because", "system": false, "created_at": "2024-03-05T11:00:00Z", "author": {"username": "alice"}},
+				{"body": "added 1 commit", "system": true, "created_at": "2024-03-06T10:00:00Z", "author": {"username": "alice"}},
+				{"body": "Fine", "system": false, "created_at": "2024-03-07T10:00:00Z", "author": {"username": "bob"}},
+				{"body": "approved this merge request", "system": true, "created_at": "2024-03-08T10:00:00Z", "author": {"username": "bob"}},
+				{"body": "approved this merge request", "system": true, "created_at": "2024-03-08T12:00:00Z", "author": {"username": "carol"}}]`))
+		case "/api/v4/projects/acme%2Fapi/repository/commits":
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Git:      GitLabGit{Token: "secret", URL: server.URL},
+		Repos:    []string{"acme/api"},
+		Periods:  []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Location: time.UTC,
+		Reviews:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A review per person and merge request, the notes of the author left out
+	want := map[string]map[string]ReviewStats{
+		"alice": {"march": {Opened: 1, Merged: 1}},
+		"bob":   {"march": {Reviews: 1, Approvals: 1, Comments: 2}},
+		"carol": {"march": {Reviews: 1, Approvals: 1}},
+	}
+	if fmt.Sprint(gb.Reviews) != fmt.Sprint(want) {
+		t.Errorf("reviews = %v, want %v", gb.Reviews, want)
+	}
+}
@@ -0,0 +1,3259 @@
+package gitstats
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestParseLogDedupsCommitAcrossBranches(t *testing.T) {
+	// The shared commit aaa1 is reachable from both main and feature
+	mainLog := "aaa1\talice@example.com\t1710000000\tAlice\n" +
+		"\n" +
+		" 1 file changed, 10 insertions(+), 2 deletions(-)\n"
+	featureLog := "bbb2\tbob@example.com\t1710100000\tBob\n" +
+		"\n" +
+		" 2 files changed, 5 insertions(+)\n" +
+		"aaa1\talice@example.com\t1710000000\tAlice\n" +
+		"\n" +
+		" 1 file changed, 10 insertions(+), 2 deletions(-)\n"
+
+	seen := make(map[string]bool)
+	first := ParseLog(mainLog, seen)
+	second := ParseLog(featureLog, seen)
+
+	if got := first.Stats["alice@example.com"]; got != (ChangesStats{Insertions: 10, Deletions: 2, Commits: 1}) {
+		t.Errorf("main: alice = %+v, want 10 insertions, 2 deletions, 1 commit", got)
+	}
+	if got, ok := second.Stats["alice@example.com"]; ok {
+		t.Errorf("feature: alice counted again: %+v", got)
+	}
+	if got := second.Stats["bob@example.com"]; got != (ChangesStats{Insertions: 5, Commits: 1}) {
+		t.Errorf("feature: bob = %+v, want 5 insertions, 1 commit", got)
+	}
+}
+
+func TestParseLogWithoutSeen(t *testing.T) {
+	log := "aaa1\talice@example.com\t1710000000\tAlice\n" +
+		"\n" +
+		" 1 file changed, 10 insertions(+), 2 deletions(-)\n" +
+		"aaa1\talice@example.com\t1710000000\tAlice\n" +
+		"\n" +
+		" 1 file changed, 10 insertions(+), 2 deletions(-)\n"
+
+	result := ParseLog(log, nil)
+	if got := result.Stats["alice@example.com"]; got != (ChangesStats{Insertions: 10, Deletions: 2, Commits: 1}) {
+		t.Errorf("alice = %+v, want the repeated commit counted once", got)
+	}
+}
+
+func TestCollectAllRefsCountsMergedCommitOnce(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "shared")
+	repo.git("checkout", "-q", "-b", "feature")
+	repo.write("b.md", "two\nthree\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "feature")
+	repo.git("checkout", "-q", "-")
+	repo.gitEnv([]string{"GIT_AUTHOR_DATE=2024-03-07T12:00:00Z", "GIT_COMMITTER_DATE=2024-03-07T12:00:00Z"},
+		"-c", "user.name=carol@example.com", "-c", "user.email=carol@example.com", "merge", "-q", "--no-ff", "-m", "merge", "feature")
+
+	// Both commits are reachable from the default branch and from feature
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{
+		Path:     repo.Dir,
+		Periods:  []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		AllRefs:  true,
+		NoMerges: true,
+	}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"]; got != (ChangesStats{Insertions: 1, Commits: 1}) {
+		t.Errorf("alice = %+v, want 1 insertion in 1 commit", got)
+	}
+	if got := gb.Stats["bob@example.com"]["march"]; got != (ChangesStats{Insertions: 2, Commits: 1}) {
+		t.Errorf("bob = %+v, want 2 insertions in 1 commit", got)
+	}
+	if _, ok := gb.Stats["carol@example.com"]; ok {
+		t.Errorf("merge commit counted with NoMerges: %+v", gb.Stats["carol@example.com"])
+	}
+}
+
+func TestParseLogInsertionsAndDeletionsOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		log  string
+		want ChangesStats
+	}{
+		{
+			name: "deletions only",
+			log:  "c1\ta@example.com\t1710000000\tA\n\n 1 file changed, 3 deletions(-)\n",
+			want: ChangesStats{Deletions: 3, Commits: 1},
+		},
+		{
+			name: "insertions only",
+			log:  "c1\ta@example.com\t1710000000\tA\n\n 1 file changed, 1 insertion(+)\n",
+			want: ChangesStats{Insertions: 1, Commits: 1},
+		},
+		{
+			// The unmatched counter of each line must be 0, never carried from the previous line
+			name: "insertions then deletions only",
+			log: "c1\ta@example.com\t1710000000\tA\n\n 2 files changed, 7 insertions(+), 4 deletions(-)\n" +
+				"c2\ta@example.com\t1710000100\tA\n\n 1 file changed, 2 deletions(-)\n" +
+				"c3\ta@example.com\t1710000200\tA\n\n 1 file changed, 5 insertions(+)\n",
+			want: ChangesStats{Insertions: 12, Deletions: 6, Commits: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseLog(tt.log, make(map[string]bool)).Stats["a@example.com"]
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogStreamed(t *testing.T) {
+	log := "c1\ta@example.com\t1710000000\tA\n\n 1 file changed, 10 insertions(+), 2 deletions(-)\n" +
+		"c2\ta@example.com\t1710000100\tA\n60\t0\tgen.go\n30\t10\tapi.go\n" +
+		"c3\tb@example.com\t1710000200\tB\n\n 1 file changed, 5 insertions(+)"
+
+	whole, _ := parseLogBuckets(strings.NewReader(log), make(map[string]bool), nil, parseOptions{maxLines: 50, capLines: true})
+	// A commit split across reads parses like one read at once
+	streamed, err := parseLogBuckets(iotest.OneByteReader(strings.NewReader(log)), make(map[string]bool), nil, parseOptions{maxLines: 50, capLines: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(streamed[""].Stats, streamed[""].Outliers) != fmt.Sprint(whole[""].Stats, whole[""].Outliers) {
+		t.Errorf("got %v, want %v", streamed[""].Stats, whole[""].Stats)
+	}
+
+	if _, err := parseLogBuckets(iotest.ErrReader(io.ErrUnexpectedEOF), make(map[string]bool), nil, parseOptions{}); err == nil {
+		t.Error("failing reader: got no error")
+	}
+}
+
+func TestParseLogOutliers(t *testing.T) {
+	log := "c1\ta@example.com\t1710000000\tA\n\n 1 file changed, 10 insertions(+), 2 deletions(-)\n" +
+		"c2\ta@example.com\t1710000100\tA\n60\t0\tgen.go\n30\t10\tapi.go\n" +
+		"c3\tb@example.com\t1710000200\tB\n\n 1 file changed, 5 insertions(+)\n"
+
+	for _, tc := range []struct {
+		capLines bool
+		want     ChangesStats
+	}{
+		{false, ChangesStats{Insertions: 10, Deletions: 2, Commits: 1}},
+		{true, ChangesStats{Insertions: 10 + 30 + 15, Deletions: 2 + 5, Commits: 2}},
+	} {
+		results, _ := parseLogBuckets(strings.NewReader(log), make(map[string]bool), nil, parseOptions{maxLines: 50, capLines: tc.capLines})
+		result := results[""]
+		if got := result.Stats["a@example.com"]; got != tc.want {
+			t.Errorf("capLines %t: got %+v, want %+v", tc.capLines, got, tc.want)
+		}
+		want := []OutlierCommit{{Hash: "c2", Author: "a@example.com", Date: time.Unix(1710000100, 0), Insertions: 90, Deletions: 10, Capped: tc.capLines}}
+		if fmt.Sprint(result.Outliers) != fmt.Sprint(want) {
+			t.Errorf("capLines %t: got outliers %+v, want %+v", tc.capLines, result.Outliers, want)
+		}
+		if got := result.Stats["b@example.com"]; got != (ChangesStats{Insertions: 5, Commits: 1}) {
+			t.Errorf("capLines %t: b@example.com got %+v", tc.capLines, got)
+		}
+	}
+}
+
+func TestCommitSizes(t *testing.T) {
+	log := "c1\ta@example.com\t1710000000\tA\n\n 1 file changed, 10 insertions(+), 2 deletions(-)\n" +
+		"c2\ta@example.com\t1710000100\tA\n3\t0\ta.go\n1\t1\tb.go\n" +
+		"c3\ta@example.com\t1710000200\tA\n" +
+		"c4\ta@example.com\t1710000300\tA\n\n 1 file changed, 1 insertion(+)\n"
+	sizes := ParseLog(log, make(map[string]bool)).Sizes["a@example.com"]
+	if fmt.Sprint(sizes) != "[12 5 0 1]" {
+		t.Fatalf("got sizes %v", sizes)
+	}
+	if got, want := newCommitSize(sizes), (CommitSize{Mean: 4.5, Median: 3}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := newCommitSize([]int{7, 1, 4}), (CommitSize{Mean: 4, Median: 4}); got != want {
+		t.Errorf("odd count: got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseLogSeparatesSquashMerges(t *testing.T) {
+	log := "c1\talice@example.com\t1710000000\tAlice\tAlice\talice@example.com\n\n 1 file changed, 4 insertions(+)\n" +
+		"c2\talice@example.com\t1710000100\tAlice\tGitHub\tnoreply@github.com\n\n 9 files changed, 300 insertions(+)\n"
+
+	result := ParseLog(log, make(map[string]bool))
+
+	if got := result.Stats["alice@example.com"]; got != (ChangesStats{Insertions: 4, Commits: 1}) {
+		t.Errorf("regular = %+v, want 4 insertions, 1 commit", got)
+	}
+	if got := result.Squashes["alice@example.com"]; got != (ChangesStats{Insertions: 300, Commits: 1}) {
+		t.Errorf("squashes = %+v, want 300 insertions, 1 commit", got)
+	}
+}
+
+func TestParsePullRequests(t *testing.T) {
+	log := "m1\tmaintainer@example.com\tMerge pull request #7 from dan/feature\n\n 3 files changed, 7 insertions(+), 2 deletions(-)\n" +
+		"c1\tdan@example.com\tAdd feature\n\n 1 file changed, 7 insertions(+)\n" +
+		"s1\tcarol@example.com\tFix typo (#12)\n\n 1 file changed, 1 insertion(+), 1 deletion(-)\n"
+
+	got := ParsePullRequests(log, "repo")
+	want := []PullRequest{
+		{Repo: "repo", Number: 7, Author: "dan", Insertions: 7, Deletions: 2},
+		{Repo: "repo", Number: 12, Author: "carol@example.com", Insertions: 1, Deletions: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d pull requests, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("pull request %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRollingAverage(t *testing.T) {
+	got := rollingAverage([]int{10, 20, 30, 0}, 3)
+	// The first two windows are partial and only average the months so far
+	want := []float64{10, 15, 20, 50.0 / 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("average %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNewTrend(t *testing.T) {
+	rising := NewTrend([]int{10, 20, 30, 40})
+	if rising.Slope != 10 || rising.Forecast != 50 || rising.Signal != TrendRising {
+		t.Errorf("rising = %+v, want slope 10, forecast 50, rising", rising)
+	}
+	if change, ok := rising.Change(); !ok || fmt.Sprintf("%.1f", change) != "33.3" {
+		t.Errorf("change = %v, %v, want 33%%", change, ok)
+	}
+
+	// The forecast does not go below zero
+	dropping := NewTrend([]int{60, 30, 0})
+	if dropping.Slope != -30 || dropping.Forecast != 0 || dropping.Signal != TrendDropping {
+		t.Errorf("dropping = %+v, want slope -30, forecast 0, dropping", dropping)
+	}
+	if _, ok := dropping.Change(); !ok {
+		t.Error("dropping: want a change")
+	}
+
+	// A gentle slope, or too few months, is not flagged
+	if steady := NewTrend([]int{100, 105, 100, 110}); steady.Signal != "" {
+		t.Errorf("steady = %+v, want no signal", steady)
+	}
+	if short := NewTrend([]int{0, 50}); short.Signal != "" || short.Slope != 50 {
+		t.Errorf("two months = %+v, want slope 50 without signal", short)
+	}
+	if _, ok := NewTrend([]int{0, 50}).Change(); ok {
+		t.Error("change from an empty month: want none")
+	}
+}
+
+func TestRenderTrend(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"a@example.com": {Insertions: 10, Commits: 1}, "b@example.com": {Insertions: 40, Commits: 1}}}, "(2024-01) January 2024")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"a@example.com": {Insertions: 20, Commits: 1}, "b@example.com": {Insertions: 40, Commits: 1}}}, "(2024-02) February 2024")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"a@example.com": {Insertions: 30, Commits: 1}, "b@example.com": {Insertions: 40, Commits: 1}}}, "(2024-03) March 2024")
+
+	var out strings.Builder
+	if err := Render(&out, *gb, RenderOptions{Trend: true}); err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	section := report[strings.Index(report, "Trends of insertions per month:"):]
+	for _, want := range []string{"2024-01 - 2024-03", "a@example.com", "+10.0", "+50%", "40  ▲ rising", "b@example.com", "+0.0", "Total"} {
+		if !strings.Contains(section, want) {
+			t.Errorf("trend section lacks %q:\n%s", want, section)
+		}
+	}
+	if strings.Contains(section, "dropping") {
+		t.Errorf("trend section flags a drop:\n%s", section)
+	}
+}
+
+func TestNewConcentration(t *testing.T) {
+	for _, tc := range []struct {
+		values []int
+		want   Concentration
+	}{
+		{[]int{25, 25, 25, 25}, Concentration{Top: 3, TopShare: 75, Gini: 0}},
+		{[]int{0, 0, 0, 100}, Concentration{Top: 3, TopShare: 100, Gini: 0.75}},
+		{[]int{10, 0}, Concentration{Top: 2, TopShare: 100, Gini: 0.5}},
+		{[]int{}, Concentration{}},
+		{[]int{0, 0}, Concentration{Top: 2}},
+	} {
+		if got := NewConcentration(tc.values); got != tc.want {
+			t.Errorf("%v: got %+v, want %+v", tc.values, got, tc.want)
+		}
+	}
+	got := NewConcentration([]int{50, 30, 10, 5, 5})
+	if fmt.Sprintf("%.1f %.2f", got.TopShare, got.Gini) != "90.0 0.46" {
+		t.Errorf("uneven: got %+v, want 90%% and a Gini of 0.46", got)
+	}
+}
+
+func TestRenderShares(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"a@example.com": {Insertions: 30, Commits: 3},
+		"b@example.com": {Insertions: 10, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	var out strings.Builder
+	if err := Render(&out, *gb, RenderOptions{Shares: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Commits  Commits %  Insertions",
+		"a@example.com        3      75.0%          30   75.0%",
+		"Concentration of insertions: top 2 developers 100.0%, Gini 0.25",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := Render(&out, *gb, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "Commits %") || strings.Contains(out.String(), "Concentration") {
+		t.Errorf("report without Shares has them:\n%s", out.String())
+	}
+}
+
+func TestCapAuthorsKeepsTotals(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"a@example.com": {Insertions: 50, Commits: 2},
+		"b@example.com": {Insertions: 30, Deletions: 5, Commits: 1},
+		"c@example.com": {Insertions: 3, Commits: 1},
+		"d@example.com": {Insertions: 1, Deletions: 1, Commits: 1},
+	}}, "2024-03")
+
+	capped := CapAuthors(gb, 2, func(stats ChangesStats) int { return stats.Insertions })
+
+	if len(capped.Stats) != 3 {
+		t.Fatalf("got %d authors, want 2 plus others", len(capped.Stats))
+	}
+	if got := capped.Stats[OthersAuthor]["2024-03"]; got != (ChangesStats{Insertions: 4, Deletions: 1, Commits: 2}) {
+		t.Errorf("others = %+v, want 4 insertions, 1 deletion, 2 commits", got)
+	}
+	if totals := capped.Totals(); totals.Insertions != 84 || totals.Deletions != 6 {
+		t.Errorf("totals = %d/%d, want 84/6", totals.Insertions, totals.Deletions)
+	}
+}
+
+func TestRenderMaxAuthorsFollowsSort(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"a@example.com": {Insertions: 50, Deletions: 45, Commits: 1},
+		"b@example.com": {Insertions: 30, Commits: 1},
+		"c@example.com": {Insertions: 10, Commits: 1},
+	}}, "2024-03")
+
+	// a has the most insertions but the fewest net lines
+	for _, tc := range []struct {
+		sort    string
+		kept    []string
+		dropped string
+	}{
+		{"net", []string{"b@example.com", "c@example.com"}, "a@example.com"},
+		{"insertions", []string{"a@example.com", "b@example.com"}, "c@example.com"},
+	} {
+		for _, format := range []string{"json", "csv", "sql", "prometheus"} {
+			var buf strings.Builder
+			if err := Render(&buf, *gb, RenderOptions{Format: format, Header: true, MaxAuthors: 2, Sort: tc.sort}); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			for _, author := range append(tc.kept, OthersAuthor) {
+				if !strings.Contains(out, author) {
+					t.Errorf("-sort %s, %s: %s missing:\n%s", tc.sort, format, author, out)
+				}
+			}
+			if strings.Contains(out, tc.dropped) {
+				t.Errorf("-sort %s, %s: %s kept:\n%s", tc.sort, format, tc.dropped, out)
+			}
+		}
+	}
+}
+
+func TestParseLogTrimsAuthorEmail(t *testing.T) {
+	log := "c1\talice@example.com \t1710000000\tAlice\n\n 1 file changed, 2 insertions(+)\n" +
+		"c2\t alice@example.com\t1710000100\tAlice\n\n 1 file changed, 3 insertions(+)\n" +
+		"c3\tbob smith@example.com\t1710000200\tBob\n\n 1 file changed, 4 insertions(+)\n" +
+		"c4\tfile changed@example.com\t1710000300\tOdd\n\n 1 file changed, 5 insertions(+)\n"
+
+	result := ParseLog(log, make(map[string]bool))
+
+	if got := result.Stats["alice@example.com"]; got != (ChangesStats{Insertions: 5, Commits: 2}) {
+		t.Errorf("alice = %+v, want 5 insertions, 2 commits under one key", got)
+	}
+	if got := result.Stats["bob smith@example.com"]; got != (ChangesStats{Insertions: 4, Commits: 1}) {
+		t.Errorf("bob = %+v, want 4 insertions, 1 commit", got)
+	}
+	if got := result.Stats["file changed@example.com"]; got != (ChangesStats{Insertions: 5, Commits: 1}) {
+		t.Errorf("odd author = %+v, want 5 insertions, 1 commit", got)
+	}
+	if len(result.Stats) != 3 {
+		t.Errorf("got %d authors, want 3: %v", len(result.Stats), result.Stats)
+	}
+	if len(result.Warnings) != 2 {
+		t.Errorf("got %d warnings, want 2 for the space-containing emails: %v", len(result.Warnings), result.Warnings)
+	}
+}
+
+func TestParseLogFoldsEmailCase(t *testing.T) {
+	log := "c1\tAlice@Corp.com\t1710000000\tAlice\n\n 1 file changed, 2 insertions(+)\n" +
+		"c2\talice@corp.com\t1710000100\tAlice\n\n 1 file changed, 3 insertions(+)\n"
+
+	result := ParseLog(log, make(map[string]bool))
+	if got := result.Stats["alice@corp.com"]; got != (ChangesStats{Insertions: 5, Commits: 2}) || len(result.Stats) != 1 {
+		t.Errorf("stats = %v, want one alice@corp.com entry with 5 insertions, 2 commits", result.Stats)
+	}
+
+	results, _ := parseLogBuckets(strings.NewReader(log), make(map[string]bool), nil, parseOptions{caseSensitive: true})
+	result = results[""]
+	if len(result.Stats) != 2 {
+		t.Errorf("case-sensitive: stats = %v, want both spellings", result.Stats)
+	}
+}
+
+func TestParseLogCoAuthors(t *testing.T) {
+	log := "c1\talice@corp.com\t1710000000\tAlice\tAlice\talice@corp.com\t1710000000\tBob <Bob@corp.com>\x1fnobody\x1fAlice <alice@corp.com>\n\n" +
+		" 1 file changed, 5 insertions(+), 2 deletions(-)\n"
+
+	for _, tc := range []struct {
+		mode       string
+		alice, bob ChangesStats
+	}{
+		{"", ChangesStats{Insertions: 5, Deletions: 2, Commits: 1}, ChangesStats{}},
+		{"split", ChangesStats{Insertions: 3, Deletions: 1, Commits: 1}, ChangesStats{Insertions: 2, Deletions: 1, Commits: 1}},
+		{"duplicate", ChangesStats{Insertions: 5, Deletions: 2, Commits: 1}, ChangesStats{Insertions: 5, Deletions: 2, Commits: 1}},
+	} {
+		var metrics []Metric
+		for _, name := range []string{"insertions", "deletions"} {
+			m, err := LookupMetric(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			metrics = append(metrics, m)
+		}
+		results, _ := parseLogBuckets(strings.NewReader(log), make(map[string]bool), nil, parseOptions{coAuthors: tc.mode, metrics: metrics})
+		result := results[""]
+		if got := result.Stats["alice@corp.com"]; got != tc.alice {
+			t.Errorf("%q: alice = %v, want %v", tc.mode, got, tc.alice)
+		}
+		if got := result.Stats["bob@corp.com"]; got != tc.bob {
+			t.Errorf("%q: bob = %v, want %v", tc.mode, got, tc.bob)
+		}
+		// The metrics are credited like the lines
+		for author, want := range map[string]ChangesStats{"alice@corp.com": tc.alice, "bob@corp.com": tc.bob} {
+			values := result.Metrics[author]
+			if values["insertions"] != want.Insertions || values["deletions"] != want.Deletions {
+				t.Errorf("%q: %s metrics = %v, want %d insertions and %d deletions", tc.mode, author, values, want.Insertions, want.Deletions)
+			}
+		}
+	}
+}
+
+func TestPeriodArgsIn(t *testing.T) {
+	p := Period{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	// Berlin switches to summer time on the last Sunday of March
+	if got, want := strings.Join(p.ArgsIn(berlin), " "), "--since=2024-03-01T00:00:00+01:00 --until=2024-03-31T23:59:59+02:00"; got != want {
+		t.Errorf("ArgsIn = %s, want %s", got, want)
+	}
+	if got, want := strings.Join(p.ArgsIn(nil), " "), "--since=2024-03-01 00:00:00 --until=2024-03-31 23:59:59"; got != want {
+		t.Errorf("ArgsIn(nil) = %s, want %s", got, want)
+	}
+}
+
+func TestMonthPeriodsFrom(t *testing.T) {
+	now := time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC)
+	var labels []string
+	for _, p := range MonthPeriodsFrom(2, 26, now) {
+		labels = append(labels, p.Label)
+	}
+	if got, want := strings.Join(labels, ", "), "(2024-01) 26 Dec 2023 - 25 Jan 2024, (2023-12) 26 Nov 2023 - 25 Dec 2023"; got != want {
+		t.Errorf("labels = %s, want %s", got, want)
+	}
+
+	p := MonthPeriodsFrom(1, 26, time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC))[0]
+	if p.Since.Format("2006-01-02") != "2024-01-26" || p.Until.Format("2006-01-02") != "2024-02-25" {
+		t.Errorf("period on the start day = %s to %s", p.Since.Format("2006-01-02"), p.Until.Format("2006-01-02"))
+	}
+	if got := MonthPeriodsFrom(1, 1, now)[0].Label; got != "(2024-01) January 2024" {
+		t.Errorf("calendar month label = %s", got)
+	}
+	if _, err := PeriodsFrom("week", 26, 1, now); err == nil {
+		t.Error("expected an error for weeks with a start day")
+	}
+}
+
+func TestBarsAndSparkline(t *testing.T) {
+	for _, tt := range []struct {
+		value, largest int
+		want           string
+	}{
+		{100, 100, "████"},
+		{50, 100, "██"},
+		{45, 100, "█▊"},
+		{1, 1000, "▏"},
+		{0, 100, ""},
+		{-5, 100, ""},
+	} {
+		if got := bar(tt.value, tt.largest, 4); got != tt.want {
+			t.Errorf("bar(%d, %d): got %q, want %q", tt.value, tt.largest, got, tt.want)
+		}
+	}
+	if got := sparkline([]int{0, 10, 35, 70, -3}); got != "▁▂▄█▁" {
+		t.Errorf("sparkline: got %q", got)
+	}
+}
+
+func TestRenderTenure(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Periods = map[string]bool{"(2024-02) February 2024": true, "(2024-03) March 2024": true}
+	gb.Stats = map[string]map[string]ChangesStats{
+		"old@example.com": {"(2024-02) February 2024": {Insertions: 5, Commits: 1}},
+		"new@example.com": {"(2024-03) March 2024": {Insertions: 3, Commits: 1}},
+	}
+	gb.ActiveDays = map[string]map[string]bool{
+		"old@example.com": {"2023-06-01": true, "2023-09-10": true},
+		"new@example.com": {"2024-03-04": true, "2024-03-20": true},
+	}
+	var out strings.Builder
+	err := Render(&out, *gb, RenderOptions{Tenure: true, InactiveMonths: 3, Now: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"old@example.com  2023-06-01    2023-09-10             102  inactive for 3+ months",
+		"new@example.com  2024-03-04    2024-03-20              17  new in 2024-03",
+		"1 new in the last period, 1 inactive for 3 months or more",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRenderActivityGap(t *testing.T) {
+	result := ParseLog("c1\tquiet@example.com\t1706788800\tQuiet\n\n 1 file changed, 1 insertion(+)\n"+
+		"c2\tbusy@example.com\t1711540800\tBusy\n\n 1 file changed, 2 insertions(+)\n", make(map[string]bool))
+	globalStats := NewGlobalStats()
+	globalStats.Add(result, "march")
+	var out strings.Builder
+	err := Render(&out, *globalStats, RenderOptions{ActivityGap: true, GapDays: 30, Now: time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	quiet := strings.Index(report, "  quiet@example.com                 59 days (last 2024-02-01) inactive\n")
+	busy := strings.Index(report, "  busy@example.com                   4 days (last 2024-03-27)\n")
+	if quiet < 0 || busy < 0 || quiet > busy {
+		t.Errorf("want quiet flagged inactive, then busy:\n%s", report)
+	}
+}
+
+func TestRenderASCIIChart(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 1000, Commits: 1}}}, "2024-01")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 1_500_000_000, Commits: 1}}}, "2024-02")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"bob@example.com": {Insertions: 1_500_000_000, Commits: 1}}}, "2024-02")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"bob@example.com": {Insertions: 1_500_000_000, Commits: 1}}}, "2024-03")
+
+	var out strings.Builder
+	if err := Render(&out, *gb, RenderOptions{Chart: true, ChartWidth: 40}); err != nil {
+		t.Fatal(err)
+	}
+	_, chart, _ := strings.Cut(out.String(), "Insertions per month:\n")
+	lines := strings.Split(chart, "\n")[:3]
+	// 40 columns less the month, the largest value and the spacing leave 17 for the bars
+	for i, want := range []struct {
+		bars  int
+		total string
+	}{{0, "1000"}, {17, "3000000000"}, {8, "1500000000"}} {
+		if got := strings.Count(lines[i], "#"); got != want.bars || !strings.HasSuffix(lines[i], " "+want.total) || len(lines[i]) != 38 {
+			t.Errorf("line %d = %q, want %d bars and %s in 38 columns", i, lines[i], want.bars, want.total)
+		}
+	}
+
+	single := NewGlobalStats()
+	single.Add(LogResult{Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 5, Commits: 1}}}, "2024-01")
+	out.Reset()
+	if err := Render(&out, *single, RenderOptions{Chart: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Not enough months to chart a trend") {
+		t.Errorf("single month charted:\n%s", out.String())
+	}
+}
+
+func TestRenderDeletionsAndNet(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"cleaner@example.com": {Insertions: 40, Deletions: 38, Commits: 2},
+		"writer@example.com":  {Insertions: 10, Deletions: 1, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	var out strings.Builder
+	if err := Render(&out, *gb, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	_, developers, _ := strings.Cut(out.String(), "Total lines by developer:\n")
+	// Sorted by net lines by default, so mostly deleting does not rank first
+	want := "  Author               Commits  Insertions   Ins %  Deletions  Net   Net %  Lines/commit\n" +
+		"  writer@example.com         1          10   20.0%          1   +9   81.8%          10.0\n" +
+		"  cleaner@example.com        2          40   80.0%         38   +2   18.2%          20.0\n" +
+		"  Total summary              3          50  100.0%         39  +11  100.0%          16.7\n"
+	if !strings.HasPrefix(developers, want) {
+		t.Errorf("developer table:\n%s\nwant:\n%s", developers, want)
+	}
+}
+
+func TestRenderTop(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"a@example.com": {Insertions: 40, Commits: 4},
+		"b@example.com": {Insertions: 30, Commits: 3},
+		"c@example.com": {Insertions: 20, Deletions: 5, Commits: 2},
+		"d@example.com": {Insertions: 10, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	render := func(opts RenderOptions) (string, string) {
+		t.Helper()
+		var out strings.Builder
+		if err := Render(&out, *gb, opts); err != nil {
+			t.Fatal(err)
+		}
+		month, developers, _ := strings.Cut(out.String(), "Total lines by developer:\n")
+		return month, developers
+	}
+	others := "  … and 2 others        3          30   30.0%          5  +25   26.3%          10.0\n"
+	total := "  Total summary        10         100  100.0%          5  +95  100.0%          10.0\n"
+
+	month, developers := render(RenderOptions{Sort: "insertions", Top: 2})
+	if !strings.Contains(developers, "b@example.com") || strings.Contains(developers, "c@example.com") || !strings.Contains(developers, others) {
+		t.Errorf("developer table with Top 2:\n%s", developers)
+	}
+	if !strings.Contains(developers, total) {
+		t.Errorf("want the totals of every author:\n%s", developers)
+	}
+	if !strings.Contains(month, "d@example.com") {
+		t.Errorf("per-month table limited without TopAll:\n%s", month)
+	}
+
+	if month, _ = render(RenderOptions{Sort: "insertions", Top: 2, TopAll: true}); strings.Contains(month, "d@example.com") || !strings.Contains(month, "… and 2 others") {
+		t.Errorf("per-month table with TopAll:\n%s", month)
+	}
+	if _, developers = render(RenderOptions{Sort: "insertions"}); !strings.Contains(developers, "d@example.com") || strings.Contains(developers, "others") {
+		t.Errorf("developer table with Top 0:\n%s", developers)
+	}
+}
+
+func TestRenderNetOnly(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 10, Deletions: 2, Commits: 1},
+		"bob@example.com":   {Insertions: 1, Deletions: 4, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	var out strings.Builder
+	if err := Render(&out, *gb, RenderOptions{NetOnly: true, Color: true}); err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	for _, want := range []string{
+		"  Author             Commits  Net lines   Net %\n",
+		"  bob@example.com          1         \033[31m-3\033[0m  -60.0%\n",
+		"  Total summary            2         \033[32m+5\033[0m  100.0%",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("missing %q in:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "Insertions") || strings.Contains(report, "Deletions") {
+		t.Errorf("insertions and deletions shown with NetOnly:\n%s", report)
+	}
+
+	// Machine-readable formats keep both raw figures
+	out.Reset()
+	if err := Render(&out, *gb, RenderOptions{NetOnly: true, Format: "csv"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "bob@example.com,(2024-03) March 2024,1,4,1\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("missing %q in:\n%s", want, out.String())
+	}
+}
+
+func TestTableBorders(t *testing.T) {
+	tests := map[string]string{
+		"none": "  Author  Net\n" +
+			"  alice    \033[32m+8\033[0m\n" +
+			"  Total    +8\n",
+		"ascii": "  +--------+-----+\n" +
+			"  | Author | Net |\n" +
+			"  +--------+-----+\n" +
+			"  | alice  |  \033[32m+8\033[0m |\n" +
+			"  +--------+-----+\n" +
+			"  | Total  |  +8 |\n" +
+			"  +--------+-----+\n",
+		"unicode-box": "  ┌────────┬─────┐\n" +
+			"  │ Author │ Net │\n" +
+			"  ├────────┼─────┤\n" +
+			"  │ alice  │  \033[32m+8\033[0m │\n" +
+			"  ├────────┼─────┤\n" +
+			"  │ Total  │  +8 │\n" +
+			"  └────────┴─────┘\n",
+	}
+	for border, want := range tests {
+		// Colors take no room in the columns
+		tbl := table{header: []string{"Author", "Net"}, rightAlign: []bool{false, true}, footer: []string{"Total", "+8"}}
+		tbl.addRow("alice", "\033[32m+8\033[0m")
+		var out strings.Builder
+		tbl.render(&out, borderStyles[border])
+		if out.String() != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", border, out.String(), want)
+		}
+	}
+
+	if err := Render(io.Discard, *NewGlobalStats(), RenderOptions{Border: "double"}); err == nil {
+		t.Error("unknown border style: got no error")
+	}
+}
+
+func TestRenderChart(t *testing.T) {
+	repo := func(stats map[string]map[string]ChangesStats) *GlobalStats {
+		gb := NewGlobalStats()
+		gb.Stats = stats
+		return gb
+	}
+	gb := *repo(map[string]map[string]ChangesStats{
+		"alice@x.com": {"2024-02": {Insertions: 30, Commits: 1}, "2024-03": {Insertions: 10, Deletions: 4, Commits: 2}},
+		"bob@x.com":   {"2024-03": {Insertions: 5, Commits: 1}},
+	})
+	gb.Repos = map[string]*GlobalStats{
+		"api": repo(map[string]map[string]ChangesStats{"alice@x.com": {"2024-02": {Insertions: 30, Commits: 1}}}),
+		"web": repo(map[string]map[string]ChangesStats{"alice@x.com": {"2024-03": {Insertions: 10, Deletions: 4, Commits: 2}}, "bob@x.com": {"2024-03": {Insertions: 5, Commits: 1}}}),
+	}
+
+	for chart, want := range map[string][]string{
+		"monthly":      {"<title>2024-02: 30 insertions</title>", "<title>2024-03: 4 deletions</title>"},
+		"contributors": {"<title>alice@x.com: 40</title>", "<title>bob@x.com: 5</title>"},
+		"repositories": {"<title>alice@x.com in api: 30</title>", "<title>bob@x.com in web: 5</title>", ">others</text>"},
+	} {
+		var b strings.Builder
+		if err := RenderChart(&b, gb, chart, RenderOptions{Sort: "insertions"}); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(b.String(), "<svg ") || !strings.HasSuffix(b.String(), "</svg>\n") {
+			t.Errorf("%s: not an SVG image: %s", chart, b.String())
+		}
+		for _, w := range want {
+			if !strings.Contains(b.String(), w) {
+				t.Errorf("%s: missing %s in %s", chart, w, b.String())
+			}
+		}
+	}
+
+	gb.Repos = nil
+	if err := RenderChart(io.Discard, gb, "repositories", RenderOptions{}); err == nil {
+		t.Error("repositories chart without repositories: got no error")
+	}
+}
+
+func TestCollectGrep(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.txt", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "feat: add a (EPIC-12)")
+	repo.write("b.txt", "one\ntwo\n")
+	repo.commit("alice@example.com", "2024-03-06T12:00:00Z", "chore: add b")
+	repo.write("c.txt", "one\ntwo\nthree\n")
+	repo.commit("alice@example.com", "2024-03-07T12:00:00Z", "Revert \"something\"")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		grep   []string
+		invert bool
+		want   ChangesStats
+	}{
+		{nil, false, ChangesStats{Insertions: 6, Commits: 3}},
+		{[]string{"EPIC-[0-9]+"}, false, ChangesStats{Insertions: 1, Commits: 1}},
+		{[]string{"^chore:", "^Revert"}, true, ChangesStats{Insertions: 1, Commits: 1}},
+	} {
+		gb, err := Collect(Options{
+			Path:       repo.Dir,
+			Periods:    []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+			Grep:       tc.grep,
+			InvertGrep: tc.invert,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["alice@example.com"]["march"]; got != tc.want {
+			t.Errorf("Grep %q, InvertGrep %t: got %+v, want %+v", tc.grep, tc.invert, got, tc.want)
+		}
+	}
+}
+
+func TestCollectTickets(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.txt", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "PROJ-1: add a")
+	repo.write("b.txt", "one\ntwo\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "Add b\n\nFixes PROJ-1 and PROJ-2, see PROJ-2")
+	repo.write("c.txt", "one\ntwo\nthree\n")
+	repo.commit("alice@example.com", "2024-03-07T12:00:00Z", "no ticket")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:          repo.Dir,
+		Periods:       []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		TicketPattern: DefaultTicketPattern,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]ChangesStats{
+		"alice@example.com": {"PROJ-1": {Insertions: 1, Commits: 1}},
+		"bob@example.com":   {"PROJ-1": {Insertions: 2, Commits: 1}, "PROJ-2": {Insertions: 2, Commits: 1}},
+	}
+	if fmt.Sprint(gb.Tickets) != fmt.Sprint(want) {
+		t.Errorf("got %+v, want %+v", gb.Tickets, want)
+	}
+
+	if _, err := Collect(Options{Path: repo.Dir, TicketPattern: "("}); err == nil {
+		t.Error("invalid pattern: got no error")
+	}
+}
+
+func TestCollectByPath(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("services/api/main.go", "one\ntwo\n")
+	repo.write("services/web/ui/app.js", "one\n")
+	repo.write("README.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "init")
+	repo.write("services/web/ui/app.js", "one\ntwo\nthree\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "ui")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		depth    int
+		prefixes []string
+		want     string
+	}{
+		{2, nil, "map[alice@example.com:map[(root):{1 0 1} services/api:{2 0 1} services/web:{1 0 1}] bob@example.com:map[services/web:{2 0 1}]]"},
+		{0, []string{"services/web/"}, "map[alice@example.com:map[(other):{3 0 1} services/web:{1 0 1}] bob@example.com:map[services/web:{2 0 1}]]"},
+	} {
+		gb, err := Collect(Options{
+			Path:         repo.Dir,
+			Periods:      []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+			PathDepth:    tc.depth,
+			PathPrefixes: tc.prefixes,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(gb.Components); got != tc.want {
+			t.Errorf("depth %d, prefixes %q: got %s, want %s", tc.depth, tc.prefixes, got, tc.want)
+		}
+	}
+}
+
+func TestCollectOwners(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write(".github/CODEOWNERS", "# Owners\n* @org/core\n/services/api/ @org/api # API team\n*.md @org/docs @carol\nvendor/\n")
+	repo.write("services/api/main.go", "one\ntwo\n")
+	repo.write("README.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "init")
+	repo.write("services/api/main.go", "one\ntwo\nthree\n")
+	repo.write("vendor/lib.go", "one\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "api")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:       repo.Dir,
+		Periods:    []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		CodeOwners: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]ChangesStats{
+		"alice@example.com": {"@org/api": {Insertions: 2, Commits: 1}, "@org/core": {Insertions: 5, Commits: 1}, "@org/docs @carol": {Insertions: 1, Commits: 1}},
+		"bob@example.com":   {"(unowned)": {Insertions: 1, Commits: 1}, "@org/api": {Insertions: 1, Commits: 1}},
+	}
+	if fmt.Sprint(gb.Owners) != fmt.Sprint(want) {
+		t.Errorf("got %+v, want %+v", gb.Owners, want)
+	}
+}
+
+func TestCollectMetrics(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "one\ntwo\n")
+	repo.write("main_test.go", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "init")
+	repo.write("main_test.go", "one\ntwo\nthree\n")
+	repo.commit("bob@example.com", "2024-04-06T12:00:00Z", "tests")
+
+	insertions, err := LookupMetric("insertions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LookupMetric("nope"); err == nil {
+		t.Error("unknown metric: want error")
+	}
+	testLines := FileMetric("test-lines", func(file FileChange) int {
+		if strings.HasSuffix(file.Path, "_test.go") {
+			return file.Insertions + file.Deletions
+		}
+		return 0
+	})
+	march, april := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path: repo.Dir,
+		Periods: []Period{
+			{Label: "march", Since: march, Until: april.AddDate(0, 0, -1)},
+			{Label: "april", Since: april, Until: april.AddDate(0, 1, -1)},
+		},
+		Metrics: []Metric{insertions, testLines},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]map[string]int{
+		"alice@example.com": {"march": {"insertions": 3, "test-lines": 1}},
+		"bob@example.com":   {"april": {"insertions": 2, "test-lines": 2}},
+	}
+	if fmt.Sprint(gb.Metrics) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", gb.Metrics, want)
+	}
+	if got := gb.Metrics["bob@example.com"]["april"]["insertions"]; got != gb.Stats["bob@example.com"]["april"].Insertions {
+		t.Errorf("insertions metric = %d, want the insertions of the stats", got)
+	}
+
+	var out strings.Builder
+	if err := Render(&out, gb, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "test-lines") {
+		t.Errorf("metrics table missing:\n%s", out.String())
+	}
+}
+
+func TestCollectBreadth(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.go", "one\n")
+	repo.write("src/b.go", "one\n")
+	repo.write("src/c.go", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "init")
+	repo.write("a.go", "one\ntwo\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "a")
+	repo.write("src/b.go", "one\ntwo\n")
+	repo.commit("alice@example.com", "2024-04-02T12:00:00Z", "b")
+
+	march, april := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path: repo.Dir,
+		Periods: []Period{
+			{Label: "march", Since: march, Until: april.AddDate(0, 0, -1)},
+			{Label: "april", Since: april, Until: april.AddDate(0, 1, -1)},
+		},
+		Breadth: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		author, period string
+		want           Breadth
+	}{
+		{"alice@example.com", "march", Breadth{Files: 3, Dirs: 2}},
+		{"alice@example.com", "april", Breadth{Files: 1, Dirs: 1}},
+		{"alice@example.com", "", Breadth{Files: 3, Dirs: 2}},
+		{"bob@example.com", "", Breadth{Files: 1, Dirs: 1}},
+		{"", "march", Breadth{Files: 3, Dirs: 2}},
+	} {
+		if got := gb.Breadth(tc.author, tc.period); got != tc.want {
+			t.Errorf("Breadth(%q, %q) = %+v, want %+v", tc.author, tc.period, got, tc.want)
+		}
+	}
+
+	var out strings.Builder
+	if err := Render(&out, gb, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Files  Dirs") {
+		t.Errorf("breadth columns missing:\n%s", out.String())
+	}
+}
+
+func TestCustomPeriod(t *testing.T) {
+	now := time.Date(2024, 3, 30, 15, 0, 0, 0, time.UTC)
+
+	p, err := CustomPeriod("2024-03-11", "2024-03-24", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Label != "(2024-03-11 - 2024-03-24)" {
+		t.Errorf("label = %q", p.Label)
+	}
+
+	if p, err = CustomPeriod("2024-03-11", "", now); err != nil || !p.Until.Equal(time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("missing -until: got %v, %v, want today", p.Until, err)
+	}
+	if _, err := CustomPeriod("2024-03-24", "2024-03-11", now); err == nil {
+		t.Error("until before since: want error")
+	}
+	if _, err := CustomPeriod("03/11/2024", "", now); err == nil {
+		t.Error("malformed date: want error")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2024, 3, 30, 15, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		value string
+		want  string
+	}{
+		{"2024-01-15", "2024-01-15"},
+		{"today", "2024-03-30"},
+		{"yesterday", "2024-03-29"},
+		{"3.weeks.ago", "2024-03-09"},
+		{"2.months.ago", "2024-01-30"},
+		{"2 days ago", "2024-03-28"},
+		{"1.year.ago", "2023-03-30"},
+	} {
+		got, err := ParseDate(tt.value, now)
+		if err != nil || got.Format("2006-01-02") != tt.want {
+			t.Errorf("ParseDate(%q) = %v, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := ParseDate("3.fortnights.ago", now); err == nil {
+		t.Error("unknown unit: want error")
+	}
+}
+
+func TestRangePeriods(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	periods, err := RangePeriods("month", "2024-03-15", "2024-05-10", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range periods {
+		got = append(got, p.Since.Format("2006-01-02")+".."+p.Until.Format("2006-01-02"))
+	}
+	want := []string{"2024-05-01..2024-05-10", "2024-04-01..2024-04-30", "2024-03-15..2024-03-31"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("periods = %v, want %v", got, want)
+	}
+
+	if periods, _ := RangePeriods("month", "", "2024-05-10", now); len(periods) != 1 || !periods[0].Since.IsZero() {
+		t.Errorf("without -since: got %v, want one unbounded period", periods)
+	}
+}
+
+func TestRepoDirsRecursive(t *testing.T) {
+	base := t.TempDir()
+	for _, dir := range []string{"top/.git", "top/nested/.git", "work/team-a/repo/.git", "work/team-b/deep/er/repo/.git", "plain/docs"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	debug := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dirs, err := repoDirs(base, true, 3, nil, debug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// top/nested lives inside a repo and work/team-b/deep/er/repo is deeper than 3 levels
+	want := []string{filepath.Join(base, "top"), filepath.Join(base, "work/team-a/repo")}
+	if strings.Join(dirs, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", dirs, want)
+	}
+
+	if dirs, err = repoDirs(base, true, -1, []string{"team-a", "top"}, debug); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{filepath.Join(base, "work/team-b/deep/er/repo")}
+	if strings.Join(dirs, ",") != strings.Join(want, ",") {
+		t.Errorf("unlimited depth, skipping team-a and top: got %v, want %v", dirs, want)
+	}
+	if dirs, _ = repoDirs(base, true, -1, []string{"work/*"}, debug); len(dirs) != 1 {
+		t.Errorf("skipping work/*: got %v, want only top", dirs)
+	}
+}
+
+func TestBareRepositories(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("CODEOWNERS", "* @org/core\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "init")
+	base := t.TempDir()
+	repo.git("clone", "-q", "--bare", repo.Dir, filepath.Join(base, "mirrors", "app.git"))
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	periods := []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}
+	gb, err := Collect(Options{Path: base, All: true, Depth: 3, ByRepo: true, CodeOwners: true, Periods: periods})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := gb.Repos["mirrors/app"]
+	if app == nil || app.Stats["alice@example.com"]["march"].Commits != 1 {
+		t.Fatalf("bare repository: got repos %v", gb.Repos)
+	}
+	if got := app.Owners["alice@example.com"]["@org/core"].Insertions; got != 1 {
+		t.Errorf("CODEOWNERS read at HEAD: got %d insertions, want 1", got)
+	}
+
+	gitDir := filepath.Join(repo.Dir, ".git")
+	if gb, err = Collect(Options{Repos: []string{gitDir}, Periods: periods}); err != nil {
+		t.Fatal(err)
+	}
+	if gb.Stats["alice@example.com"]["march"].Commits != 1 {
+		t.Errorf("git directory of a clone: got %v", gb.Stats)
+	}
+	if got, want := RepoName(".", gitDir), filepath.Base(repo.Dir); got != want {
+		t.Errorf("RepoName of a .git directory = %q, want %q", got, want)
+	}
+}
+
+func TestShallowClone(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.txt", "one\n")
+	repo.commit("bob@example.com", "2024-02-10T12:00:00Z", "old")
+	repo.write("a.txt", "one\ntwo\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "new")
+	clone := filepath.Join(t.TempDir(), "clone")
+	repo.git("clone", "-q", "--depth", "1", "file://"+repo.Dir, clone)
+
+	since := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{Repos: []string{clone}, Periods: []Period{{Label: "q", Since: since, Until: since.AddDate(0, 2, -1)}}}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gb.Warnings) != 1 || !strings.Contains(gb.Warnings[0], "shallow clone with history only from 2024-03-05") {
+		t.Errorf("got warnings %q, want one about the shallow clone", gb.Warnings)
+	}
+
+	opts.Unshallow = true
+	if gb, err = Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(gb.Warnings) != 0 || gb.Stats["bob@example.com"]["q"].Commits != 1 {
+		t.Errorf("unshallowed: got warnings %q and stats %v, want bob's commit", gb.Warnings, gb.Stats)
+	}
+}
+
+func TestCollectSubmodules(t *testing.T) {
+	shared := newFixtureRepo(t)
+	shared.write("lib.go", "one\ntwo\n")
+	shared.commit("bob@example.com", "2024-03-05T12:00:00Z", "lib")
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "one\n")
+	repo.commit("alice@example.com", "2024-03-06T12:00:00Z", "main")
+	repo.git("-c", "protocol.file.allow=always", "submodule", "add", "-q", shared.Dir, "libs/shared")
+	repo.commit("alice@example.com", "2024-03-07T12:00:00Z", "add shared")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	periods := []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}
+	gb, err := Collect(Options{Path: repo.Dir, ByRepo: true, Submodules: "separate", Periods: periods})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["bob@example.com"]["march"].Insertions; got != 2 {
+		t.Errorf("submodule commits: got %d insertions, want 2", got)
+	}
+	sub := gb.Repos["libs/shared"]
+	if len(gb.Repos) != 2 || sub == nil || sub.Stats["bob@example.com"]["march"].Insertions != 2 {
+		t.Errorf("separate: got repos %v, want the submodule on its own", gb.Repos)
+	}
+
+	if gb, err = Collect(Options{Path: repo.Dir, ByRepo: true, Submodules: "parent", Periods: periods}); err != nil {
+		t.Fatal(err)
+	}
+	parent := gb.Repos[RepoName(repo.Dir, repo.Dir)]
+	if len(gb.Repos) != 1 || parent == nil || parent.Stats["bob@example.com"]["march"].Insertions != 2 {
+		t.Errorf("parent: got repos %v, want the submodule in its parent", gb.Repos)
+	}
+}
+
+func TestMailmapCollapsesEmails(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write(".mailmap", "Alice <alice@work.example> <alice@personal.example>\n")
+	repo.write("a.md", "one\n")
+	repo.commit("alice@work.example", "2024-03-05T12:00:00Z", "work")
+	repo.write("b.md", "two\nthree\n")
+	repo.commit("alice@personal.example", "2024-03-06T12:00:00Z", "personal")
+
+	output := repo.git("log", "--pretty=%H%x09%aE%x09%at%x09%aN", "--shortstat", "--", "*.md")
+	result := ParseLog(output, make(map[string]bool))
+
+	if got := result.Stats["alice@work.example"]; got != (ChangesStats{Insertions: 3, Commits: 2}) {
+		t.Errorf("alice = %+v, want 3 insertions, 2 commits", got)
+	}
+	if len(result.Stats) != 1 {
+		t.Errorf("got %d authors, want 1: %v", len(result.Stats), result.Stats)
+	}
+}
+
+func TestPrintCSVQuotesFields(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"smith, bob":        {Insertions: 4, Deletions: 1, Commits: 1},
+		"alice@example.com": {Insertions: 2, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	var buf strings.Builder
+	if err := printCSV(&buf, *gb, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "author,month,insertions,deletions,commits\n" +
+		"alice@example.com,(2024-03) March 2024,2,0,1\n" +
+		"\"smith, bob\",(2024-03) March 2024,4,1,1\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestParseLogCountsCommitsWithoutShortstat(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "add a")
+	repo.commit("alice@example.com", "2024-03-06T12:00:00Z", "empty")
+
+	output := repo.git("log", "--pretty=%H%x09%aE%x09%at%x09%aN", "--shortstat")
+	result := ParseLog(output, make(map[string]bool))
+
+	if got := result.Stats["alice@example.com"]; got != (ChangesStats{Insertions: 1, Commits: 2}) {
+		t.Errorf("alice = %+v, want 1 insertion, 2 commits", got)
+	}
+}
+
+func TestTotalsMatchMonthlySummaries(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", strings.Repeat("line\n", 10))
+	repo.commit("alice@example.com", "2024-01-10T12:00:00Z", "january")
+	repo.write("b.md", strings.Repeat("line\n", 4))
+	repo.commit("bob@example.com", "2024-02-10T12:00:00Z", "february")
+	repo.write("a.md", strings.Repeat("line\n", 7))
+	repo.commit("alice@example.com", "2024-03-10T12:00:00Z", "march")
+
+	gb := NewGlobalStats()
+	seen := make(map[string]bool)
+	for _, p := range MonthPeriods(3, time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)) {
+		args := append([]string{"log", "--pretty=%H%x09%aE%x09%at%x09%aN", "--shortstat"}, p.Args()...)
+		gb.Add(ParseLog(repo.git(args...), seen), p.Label)
+	}
+
+	var monthly ChangesStats
+	for _, month := range sortedMonths(*gb) {
+		for _, months := range gb.Stats {
+			monthly.Insertions += months[month].Insertions
+			monthly.Deletions += months[month].Deletions
+			monthly.Commits += months[month].Commits
+		}
+	}
+	want := ChangesStats{Insertions: 14, Deletions: 3, Commits: 3}
+	if got := gb.Totals(); got != want || monthly != want {
+		t.Errorf("totals = %+v, monthly sum = %+v, want %+v", got, monthly, want)
+	}
+}
+
+func TestIgnoreWhitespaceDropsReindentation(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\ntwo\nthree\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "add a")
+	repo.write("a.md", "  one\n  two\n  three\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "reindent")
+
+	args := []string{"log", "--pretty=%H%x09%aE%x09%at%x09%aN", "--shortstat"}
+	plain := ParseLog(repo.git(args...), make(map[string]bool))
+	ignored := ParseLog(repo.git(append(args, "-w")...), make(map[string]bool))
+
+	if got := plain.Stats["bob@example.com"]; got != (ChangesStats{Insertions: 3, Deletions: 3, Commits: 1}) {
+		t.Errorf("without -w: bob = %+v, want 3 insertions, 3 deletions, 1 commit", got)
+	}
+	// The whitespace-only commit has no shortstat line at all with -w but is still counted
+	if got := ignored.Stats["bob@example.com"]; got != (ChangesStats{Commits: 1}) {
+		t.Errorf("with -w: bob = %+v, want no lines, 1 commit", got)
+	}
+	if got := ignored.Stats["alice@example.com"]; got != (ChangesStats{Insertions: 3, Commits: 1}) {
+		t.Errorf("with -w: alice = %+v, want 3 insertions, 1 commit", got)
+	}
+}
+
+func TestCollectAndRender(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\ntwo\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "add a")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:    repo.Dir,
+		Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"]; got != (ChangesStats{Insertions: 2, Commits: 1}) {
+		t.Errorf("alice = %+v, want 2 insertions, 1 commit", got)
+	}
+
+	var buf strings.Builder
+	if err := Render(&buf, gb, RenderOptions{Format: "csv", Header: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "author,month,insertions,deletions,commits\nalice@example.com,march,2,0,1\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCollectNotARepository(t *testing.T) {
+	if _, err := Collect(Options{Path: t.TempDir()}); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("err = %v, want ErrNotRepository", err)
+	}
+}
+
+func TestCollectByLanguage(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\ntwo\n")
+	repo.write("ci.yml", "steps:\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "docs and ci")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:       repo.Dir,
+		Periods:    []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		ByLanguage: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	languages := gb.Languages["alice@example.com"]
+	if languages["md"] != (ChangesStats{Insertions: 2, Commits: 1}) || languages["yml"] != (ChangesStats{Insertions: 1, Commits: 1}) {
+		t.Errorf("languages = %+v, want md 2 and yml 1 insertions, one commit each", languages)
+	}
+	if got := gb.Stats["alice@example.com"]["march"]; got != (ChangesStats{Insertions: 3, Commits: 1}) {
+		t.Errorf("total = %+v, want 3 insertions, 1 commit", got)
+	}
+}
+
+func TestCollectByLanguageNumstat(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\ntwo\n")
+	repo.write("ci.yml", "steps:\n")
+	repo.write("Makefile", "all:\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "docs and ci")
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-06T12:00:00Z", "trim docs")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:       repo.Dir,
+		Periods:    []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		ByLanguage: true,
+		Numstat:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	languages := gb.Languages["alice@example.com"]
+	want := map[string]ChangesStats{
+		"md":       {Insertions: 2, Deletions: 1, Commits: 2},
+		"yml":      {Insertions: 1, Commits: 1},
+		"makefile": {Insertions: 1, Commits: 1},
+	}
+	if fmt.Sprint(languages) != fmt.Sprint(want) {
+		t.Errorf("languages = %+v, want %+v", languages, want)
+	}
+}
+
+func TestNumstatPath(t *testing.T) {
+	for field, want := range map[string]string{
+		"main.go":                  "main.go",
+		"docs/{old => new}.md":     "docs/new.md",
+		"{src => lib}/util.go":     "lib/util.go",
+		"docs/{ => api}/index.md":  "docs/api/index.md",
+		"old.txt => notes/new.rst": "notes/new.rst",
+	} {
+		if got := numstatPath(field); got != want {
+			t.Errorf("numstatPath(%q) = %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestParseLogNumstatZ(t *testing.T) {
+	// An empty commit, a rename with a tweak next to a binary file, and a path with a tab
+	log := "c3\ta@example.com\t1710000200\tA\x00" +
+		"c2\ta@example.com\t1710000100\tA\n1\t1\t\x00old.md\x00notes/new.rst\x00-\t-\tlogo.png\x00\x00" +
+		"c1\tb@example.com\t1710000000\tB\n4\t0\tmy\tfile.go\x00"
+	results, err := parseLogBuckets(strings.NewReader(log), make(map[string]bool), nil, parseOptions{byExtension: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := results[""]
+	if got := result.Stats["a@example.com"]; got != (ChangesStats{Insertions: 1, Deletions: 1, Commits: 2}) {
+		t.Errorf("a = %+v, want two commits and the one-line edit", got)
+	}
+	if got := result.Stats["b@example.com"]; got != (ChangesStats{Insertions: 4, Commits: 1}) {
+		t.Errorf("b = %+v, want 4 insertions", got)
+	}
+	if result.Binary["a@example.com"] != 1 {
+		t.Errorf("binary = %v, want logo.png of a", result.Binary)
+	}
+	var exts []string
+	for ext := range result.Extensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	if got := strings.Join(exts, ","); got != "go,png,rst" {
+		t.Errorf("extensions = %s, want go,png,rst", got)
+	}
+}
+
+func TestCollectChurn(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "one\ntwo\nthree\nfour\n")
+	repo.commit("alice@example.com", "2024-03-01T12:00:00Z", "add")
+	// Bob rewrites two of Alice's lines within a week
+	repo.write("main.go", "one\n2\n3\nfour\n")
+	repo.commit("bob@example.com", "2024-03-05T12:00:00Z", "rewrite")
+	// Alice's last line goes much later, which is not churn
+	repo.write("main.go", "one\n2\n3\n")
+	repo.commit("carol@example.com", "2024-03-28T12:00:00Z", "drop")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:      repo.Dir,
+		Periods:   []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		ChurnDays: 21,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Churn["alice@example.com"]; got != (Churn{Added: 4, Churned: 2}) {
+		t.Errorf("alice = %+v, want 4 added, 2 churned", got)
+	}
+	if got := gb.Churn["bob@example.com"]; got != (Churn{Added: 2}) {
+		t.Errorf("bob = %+v, want 2 added, none churned", got)
+	}
+	if got := gb.Churn["alice@example.com"].Percent(); got != 50 {
+		t.Errorf("alice churn = %.1f%%, want 50%%", got)
+	}
+}
+
+func TestParseChurnRename(t *testing.T) {
+	log := "\x00alice@example.com\t1710000000\n\n" +
+		"diff --git a/old.go b/old.go\n" +
+		"new file mode 100644\n" +
+		"--- /dev/null\n" +
+		"+++ b/old.go\n" +
+		"@@ -0,0 +1,2 @@\n" +
+		"+func a() {}\n" +
+		"+\n" +
+		"\x00bob@example.com\t1710000100\n\n" +
+		"diff --git a/old.go b/new.go\n" +
+		"similarity index 50%\n" +
+		"rename from old.go\n" +
+		"rename to new.go\n" +
+		"--- a/old.go\n" +
+		"+++ b/new.go\n" +
+		"@@ -1 +1 @@\n" +
+		"-func a() {}\n" +
+		"+func b() {}\n"
+
+	churn := ParseChurn(log, time.Hour, time.Time{}, false)
+	if got := churn["alice@example.com"]; got != (Churn{Added: 1, Churned: 1}) {
+		t.Errorf("alice = %+v, want the line deleted after the rename churned", got)
+	}
+}
+
+func TestCollectActiveDays(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.txt", "1\n")
+	repo.commit("alice@example.com", "2024-03-05T09:00:00Z", "one")
+	repo.write("a.txt", "1\n2\n")
+	repo.commit("alice@example.com", "2024-03-05T17:00:00Z", "two")
+	repo.write("a.txt", "1\n2\n3\n")
+	repo.commit("alice@example.com", "2024-03-12T12:00:00Z", "three")
+	repo.write("b.txt", "1\n")
+	repo.commit("bob@example.com", "2024-03-12T13:00:00Z", "four")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:     repo.Dir,
+		Periods:  []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Location: time.UTC,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(gb.ActiveDays["alice@example.com"]); got != 2 {
+		t.Errorf("alice active days = %d, want 2", got)
+	}
+
+	var buf strings.Builder
+	if err := Render(&buf, gb, RenderOptions{Days: true}); err != nil {
+		t.Fatal(err)
+	}
+	_, developers, _ := strings.Cut(buf.String(), "Total lines by developer")
+	for _, want := range []string{"Active days  Commits/day", "2          1.5", "2          2.0"} {
+		if !strings.Contains(developers, want) {
+			t.Errorf("missing %q in the developer table:\n%s", want, developers)
+		}
+	}
+}
+
+func TestCollectHeatmap(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.txt", "1\n")
+	repo.commit("alice@example.com", "2024-03-04T09:00:00Z", "monday")
+	repo.write("a.txt", "1\n2\n")
+	repo.commit("alice@example.com", "2024-03-11T09:30:00Z", "next monday")
+	repo.write("b.txt", "1\n")
+	repo.commit("bob@example.com", "2024-03-10T23:00:00Z", "sunday")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:     repo.Dir,
+		Periods:  []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Location: time.UTC,
+		Heatmap:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Heatmap["alice@example.com"][0][9]; got != 2 {
+		t.Errorf("alice commits on Monday at 9 = %d, want 2", got)
+	}
+	if got := gb.Heatmap["bob@example.com"][6][23]; got != 1 {
+		t.Errorf("bob commits on Sunday at 23 = %d, want 1", got)
+	}
+
+	var buf strings.Builder
+	if err := Render(&buf, gb, RenderOptions{HeatmapByAuthor: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Commits by weekday and hour:",
+		"  Mon  ·········█··············     2",
+		"  Sun  ·······················▒     1",
+		"bob@example.com:",
+		"  Sun  ·······················█     1",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in the heatmap:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "1\n2\n3\n4\n5\n6\n7\n8\n")
+	repo.write("docs/a.md", "1\n2\n")
+	repo.commit("alice@example.com", "2024-03-04T09:00:00Z", "one")
+	repo.write("main.go", "1\n2\n3\n4\n5\n6\nseven\neight\n")
+	repo.commit("Bob@example.com", "2024-03-05T09:00:00Z", "two")
+
+	ownership, err := NewCollector(Options{Path: repo.Dir}).Ownership()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(ownership.Authors), "map[alice@example.com:8 bob@example.com:2]"; got != want {
+		t.Errorf("Authors = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(ownership.Dirs), "map[.:map[alice@example.com:6 bob@example.com:2] docs:map[alice@example.com:2]]"; got != want {
+		t.Errorf("Dirs = %s, want %s", got, want)
+	}
+	if got := BusFactor(ownership.Authors); got != 1 {
+		t.Errorf("BusFactor = %d, want 1", got)
+	}
+	if got := BusFactor(ownership.Dirs["."]); got != 2 {
+		t.Errorf("BusFactor of . = %d, want 2", got)
+	}
+}
+
+func TestComparePeriods(t *testing.T) {
+	march := Period{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)}
+	quarter := Period{Since: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)}
+	days := Period{Since: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)}
+	for _, tc := range []struct {
+		window Period
+		mode   string
+		want   string
+	}{
+		{march, "previous", "(2024-02-01 - 2024-02-29)"},
+		{quarter, "previous", "(2024-01-01 - 2024-03-31)"},
+		{days, "previous", "(2024-03-03 - 2024-03-09)"},
+		{quarter, "year", "(2023-04-01 - 2023-06-30)"},
+	} {
+		previous, current, err := ComparePeriods(tc.window, tc.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous.Label != tc.want {
+			t.Errorf("ComparePeriods(%s, %s) = %s, want %s", current.Label, tc.mode, previous.Label, tc.want)
+		}
+	}
+	if _, _, err := ComparePeriods(Period{Until: march.Until}, "previous"); err == nil {
+		t.Error("expected an error for a period without a start date")
+	}
+}
+
+func TestRenderComparison(t *testing.T) {
+	previous, current, _ := ComparePeriods(Period{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)}, "previous")
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 100, Commits: 2}, "bob@example.com": {Insertions: 50, Commits: 1}}}, previous.Label)
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 150, Commits: 3}, "carol@example.com": {Insertions: 10, Commits: 1}}}, current.Label)
+
+	comparison := Compare(*gb, previous, current)
+	if got := comparison.Authors["alice@example.com"].Delta(); got != 50 {
+		t.Errorf("alice change = %d, want 50", got)
+	}
+	var buf strings.Builder
+	if err := RenderComparison(&buf, comparison, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"alice@example.com               2            3         100      150     +50     50.0%",
+		"carol@example.com               0            1           0       10     +10       new",
+		"Top risers: alice@example.com (+50), carol@example.com (+10)",
+		"Top decliners: bob@example.com (-50)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in the comparison:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestParseLogNumstat(t *testing.T) {
+	log := "c1\talice@example.com\t1710000000\tAlice\n\n" +
+		"10\t2\tmain.go\n" +
+		"-\t-\tlogo.png\n" +
+		"3\t0\tdocs/{old => new}.md\n" +
+		"c2\tbob@example.com\t1710000100\tBob\n\n" +
+		"0\t4\tREADME.md\n"
+
+	result := ParseLog(log, make(map[string]bool))
+
+	if got := result.Stats["alice@example.com"]; got != (ChangesStats{Insertions: 13, Deletions: 2, Commits: 1}) {
+		t.Errorf("alice = %+v, want 13 insertions, 2 deletions, 1 commit", got)
+	}
+	if got := result.Binary["alice@example.com"]; got != 1 {
+		t.Errorf("alice binary files = %d, want 1", got)
+	}
+	if got := result.Stats["bob@example.com"]; got != (ChangesStats{Deletions: 4, Commits: 1}) {
+		t.Errorf("bob = %+v, want 4 deletions, 1 commit", got)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", result.Warnings)
+	}
+}
+
+func TestRenderMarkdownEscapesPipes(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"a|b@example.com": {Insertions: 3, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	var buf strings.Builder
+	if err := Render(&buf, *gb, RenderOptions{Format: "markdown"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"### (2024-03) March 2024\n",
+		"| Author           | Commits | Insertions |  Ins % | Deletions | Net |  Net % |\n",
+		"| ---------------- | ------: | ---------: | -----: | --------: | --: | -----: |\n",
+		"| a\\|b@example.com |       1 |          3 | 100.0% |         0 |  +3 | 100.0% |\n",
+		"| **Summary**      |       1 |",
+		"| **Total summary** |       1 |",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestMatchAuthor(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"alice@team.com", true},
+		{"Bob@Team.com", true},
+		{"carol@example.com", true},
+		{"dave@other.com", false},
+		{"team.com@other.com", false},
+		{"jenkins@ci.example.com", true},
+		{"Renovate-Bot@example.com", true},
+		{"my-jenkins@example.com", false},
+	}
+	patterns := []string{"*@team.com", "carol", `/^(jenkins|renovate-bot)@/`}
+	for _, tt := range tests {
+		if got := matchAuthor(tt.email, patterns); got != tt.want {
+			t.Errorf("matchAuthor(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAuthorPatterns(t *testing.T) {
+	if err := checkAuthorPatterns([]string{"*@team.com", "carol", `/^ci\d+@/`}); err != nil {
+		t.Errorf("valid patterns: %s", err)
+	}
+	for _, pattern := range []string{"/(/", "[a-"} {
+		if err := checkAuthorPatterns([]string{pattern}); err == nil {
+			t.Errorf("%s: want error", pattern)
+		}
+	}
+}
+
+func TestCollectMergeByName(t *testing.T) {
+	repo := newFixtureRepo(t)
+	for i, author := range [][2]string{
+		{"Alice Smith", "alice@work.example.com"},
+		{" alice smith ", "alice@home.example.com"},
+		{"Bob", "bob@example.com"},
+	} {
+		repo.write("a.md", strings.Repeat("line\n", i+1))
+		repo.git("add", "-A")
+		repo.gitEnv([]string{"GIT_AUTHOR_DATE=2024-03-05T12:00:00Z", "GIT_COMMITTER_DATE=2024-03-05T12:00:00Z"},
+			"-c", "user.name="+author[0], "-c", "user.email="+author[1], "commit", "-q", "-m", "change")
+	}
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:        repo.Dir,
+		Periods:     []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		MergeByName: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@home.example.com"]["march"]; got != (ChangesStats{Insertions: 2, Commits: 2}) {
+		t.Errorf("alice = %+v, want both emails merged: 2 insertions, 2 commits", got)
+	}
+	if _, ok := gb.Stats["alice@work.example.com"]; ok || len(gb.Stats) != 2 {
+		t.Errorf("authors = %v, want alice@home.example.com and bob@example.com", gb.Stats)
+	}
+
+	var out strings.Builder
+	PrintMergedNames(&out, gb.Merged)
+	if want := "Merged authors by name:\n  alice smith: alice@home.example.com, alice@work.example.com -> alice@home.example.com\n"; out.String() != want {
+		t.Errorf("merged names = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCollectTags(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-02-05T12:00:00Z", "first")
+	for _, tag := range [][3]string{
+		{"v0.9", "bob@example.com", "2024-02-20T12:00:00Z"},
+		{"v1.0", "alice@example.com", "2024-03-04T12:00:00Z"},
+		{"v1.1", "Alice@Example.com", "2024-03-18T12:00:00Z"},
+		{"nightly", "bob@example.com", "2024-03-19T12:00:00Z"},
+	} {
+		repo.gitEnv([]string{"GIT_COMMITTER_DATE=" + tag[2]},
+			"-c", "user.name="+tag[1], "-c", "user.email="+tag[1], "tag", "-a", "-m", tag[0], tag[0])
+	}
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{
+		Path:    repo.Dir,
+		Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Tags:    true,
+	}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"alice@example.com": 2, "bob@example.com": 1}; !maps.Equal(gb.Tags, want) {
+		t.Errorf("tags = %v, want %v", gb.Tags, want)
+	}
+
+	opts.TagsPattern = "v*"
+	if gb, err = Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"alice@example.com": 2}; !maps.Equal(gb.Tags, want) {
+		t.Errorf("tags matching v* = %v, want %v", gb.Tags, want)
+	}
+	var out strings.Builder
+	if err := Render(&out, gb, RenderOptions{Tags: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Tags by person:\n  alice@example.com                  2 tags\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("missing %q in:\n%s", want, out.String())
+	}
+}
+
+func TestCollectAutoExt(t *testing.T) {
+	repo := newFixtureRepo(t)
+	for name, lines := range map[string]int{
+		"main.go": 1, "a.go": 1, "b.go": 1, "c.go": 1, "README.md": 2, "docs/usage.md": 2,
+		"package-lock.json": 100, "web/yarn.lock": 100,
+	} {
+		repo.write(name, strings.Repeat("line\n", lines))
+	}
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "initial")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var progress strings.Builder
+	opts := Options{
+		Path:        repo.Dir,
+		Periods:     []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		AutoExt:     true,
+		AutoExtSkip: strings.Split(DefaultAutoExtSkip, ","),
+		Progress:    log.New(&progress, "", 0),
+	}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The lock files are skipped, leaving the .go and .md files
+	if got := gb.Stats["alice@example.com"]["march"].Insertions; got != 8 {
+		t.Errorf("insertions = %d, want 8 in the .go and .md files", got)
+	}
+	if want := "Auto-detected extensions for " + repo.Dir + ": go,md\n"; progress.String() != want {
+		t.Errorf("progress = %q, want %q", progress.String(), want)
+	}
+
+	opts.AutoExtSkip = append(opts.AutoExtSkip, "md")
+	progress.Reset()
+	if gb, err = Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"].Insertions; got != 4 || !strings.HasSuffix(progress.String(), ": go\n") {
+		t.Errorf("insertions = %d, progress %q, want only the .go files once .md is skipped", got, progress.String())
+	}
+}
+
+func TestCollectByRepo(t *testing.T) {
+	base := t.TempDir()
+	api := newFixtureRepoAt(t, filepath.Join(base, "api"))
+	api.write("a.md", "one\ntwo\n")
+	api.commit("alice@example.com", "2024-03-05T12:00:00Z", "api")
+	web := newFixtureRepoAt(t, filepath.Join(base, "web"))
+	web.write("b.md", "one\n")
+	web.commit("alice@example.com", "2024-03-06T12:00:00Z", "web")
+	web.write("c.md", "one\ntwo\nthree\n")
+	web.commit("bob@example.com", "2024-03-07T12:00:00Z", "more web")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:    base,
+		All:     true,
+		ByRepo:  true,
+		Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]map[string]int{
+		"api": {"alice@example.com": 2},
+		"web": {"alice@example.com": 1, "bob@example.com": 3},
+		"":    {"alice@example.com": 3, "bob@example.com": 3},
+	} {
+		stats := &gb
+		if name != "" {
+			stats = gb.Repos[name]
+		}
+		if stats == nil || len(stats.Stats) != len(want) {
+			t.Errorf("%q: got %v, want %v", name, stats, want)
+			continue
+		}
+		for author, insertions := range want {
+			if got := stats.Stats[author]["march"].Insertions; got != insertions {
+				t.Errorf("%q: %s has %d insertions, want %d", name, author, got, insertions)
+			}
+		}
+	}
+
+	var out strings.Builder
+	if err := Render(&out, gb, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	report := out.String()
+	first, second, all := strings.Index(report, "=== Repository api ==="), strings.Index(report, "=== Repository web ==="), strings.Index(report, "=== All repositories ===")
+	if first < 0 || first > second || second > all {
+		t.Errorf("want a section per repository before the totals:\n%s", report)
+	}
+}
+
+func TestCollectJobsIsDeterministic(t *testing.T) {
+	base := t.TempDir()
+	for i := 0; i < 6; i++ {
+		repo := newFixtureRepoAt(t, filepath.Join(base, fmt.Sprintf("repo%d", i)))
+		for j := 0; j <= i; j++ {
+			repo.write("a.md", strings.Repeat("line\n", j+1))
+			repo.commit(fmt.Sprintf("dev%d@example.com", j%3), fmt.Sprintf("2024-03-%02dT12:00:00Z", j+1), "change")
+		}
+	}
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var reports []string
+	for _, jobs := range []int{1, 4, 4, 4} {
+		gb, err := Collect(Options{
+			Path:    base,
+			All:     true,
+			ByRepo:  true,
+			Jobs:    jobs,
+			Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out strings.Builder
+		if err := Render(&out, gb, RenderOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, out.String())
+	}
+	if !strings.Contains(reports[0], "=== Repository repo5 ===") {
+		t.Fatalf("missing repositories in:\n%s", reports[0])
+	}
+	for i, report := range reports[1:] {
+		if report != reports[0] {
+			t.Errorf("run %d with 4 jobs differs from 1 job:\n%s\nwant:\n%s", i+1, report, reports[0])
+		}
+	}
+}
+
+func TestCollectAllRefs(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "main")
+	repo.git("checkout", "-q", "-b", "side")
+	repo.write("b.md", "two\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "side")
+	repo.git("checkout", "-q", "-")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{Path: repo.Dir, Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["bob@example.com"]; ok {
+		t.Errorf("stats = %v, want only the checked-out branch", gb.Stats)
+	}
+
+	opts.AllRefs, opts.CacheDir = true, t.TempDir()
+	for _, want := range []int{1, 2} {
+		if gb, err = Collect(opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["bob@example.com"]["march"].Commits; got != want {
+			t.Errorf("bob's commits = %d, want %d", got, want)
+		}
+		// A commit on a branch other than HEAD must invalidate the cache too
+		repo.git("checkout", "-q", "side")
+		repo.write("b.md", "two\nthree\n")
+		repo.commit("bob@example.com", "2024-03-07T12:00:00Z", "side again")
+		repo.git("checkout", "-q", "-")
+	}
+}
+
+func TestCollectBranch(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "main work")
+	repo.git("branch", "release")
+	repo.write("b.md", "two\nthree\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "unreleased work")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{
+		Path:    repo.Dir,
+		Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Branch:  "release",
+	}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["bob@example.com"]; ok || len(gb.Stats) != 1 {
+		t.Errorf("stats = %v, want only alice's release commit", gb.Stats)
+	}
+
+	opts.Branch = "missing"
+	if _, err := Collect(opts); err == nil {
+		t.Error("missing branch: want error")
+	}
+}
+
+func TestWeekPeriods(t *testing.T) {
+	// a Wednesday
+	periods := WeekPeriods(2, time.Date(2024, 3, 13, 15, 0, 0, 0, time.UTC))
+	if len(periods) != 2 {
+		t.Fatalf("got %d periods, want 2", len(periods))
+	}
+	if p := periods[0]; p.Label != "(2024-W11) 11 Mar 2024" || p.Since.Day() != 11 || p.Until.Day() != 17 {
+		t.Errorf("current week = %+v", p)
+	}
+	if p := periods[1]; p.Label != "(2024-W10) 4 Mar 2024" || p.Until.Day() != 10 {
+		t.Errorf("previous week = %+v", p)
+	}
+	if _, err := Periods("year", 1, time.Now()); err == nil {
+		t.Error("unknown granularity: want error")
+	}
+}
+
+func TestCollectBucketsByAuthorDate(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "first week")
+	repo.write("a.md", "one\ntwo\nthree\n")
+	repo.commit("bob@example.com", "2024-03-12T12:00:00Z", "second week")
+
+	periods := WeekPeriods(2, time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC))
+	gb, err := Collect(Options{Path: repo.Dir, Periods: periods})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"][periods[1].Label].Insertions; got != 1 {
+		t.Errorf("alice in %s = %d, want 1", periods[1].Label, got)
+	}
+	if got := gb.Stats["bob@example.com"][periods[0].Label].Insertions; got != 2 {
+		t.Errorf("bob in %s = %d, want 2", periods[0].Label, got)
+	}
+	if len(gb.Stats["alice@example.com"]) != 1 || len(gb.Stats["bob@example.com"]) != 1 {
+		t.Errorf("stats = %v, want one week per author", gb.Stats)
+	}
+}
+
+func TestCollectPaths(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("src/api/server.go", "one\ntwo\n")
+	repo.write("src/README.md", "one\n")
+	repo.write("docs/example.go", "one\ntwo\nthree\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "add files")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{
+		Path:       repo.Dir,
+		Periods:    []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		Paths:      []string{"src"},
+		Extensions: []string{"go"},
+	}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Totals().Insertions; got != 2 {
+		t.Errorf("-path src -ext go: insertions = %d, want 2", got)
+	}
+
+	opts.Extensions = nil
+	if gb, err = Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Totals().Insertions; got != 3 {
+		t.Errorf("-path src: insertions = %d, want 3 from every file below src", got)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	for _, tc := range []struct {
+		part, total int
+		want        string
+	}{
+		{1, 3, "33.3%"},
+		{-30, 98, "-30.6%"},
+		{0, 0, "0.0%"},
+	} {
+		if got := percent(tc.part, tc.total); got != tc.want {
+			t.Errorf("percent(%d, %d) = %q, want %q", tc.part, tc.total, got, tc.want)
+		}
+	}
+}
+
+func TestCollectExcludesAuthors(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "work")
+	repo.write("a.md", "one\ntwo\n")
+	repo.commit("49699333+dependabot[bot]@users.noreply.github.com", "2024-03-06T12:00:00Z", "bump")
+	repo.write("a.md", "one\ntwo\nthree\n")
+	repo.commit("ci@example.com", "2024-03-07T12:00:00Z", "release")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:           repo.Dir,
+		Periods:        []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		ExcludeBots:    true,
+		ExcludeAuthors: []string{"ci@"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gb.Stats) != 1 || gb.Totals().Insertions != 1 {
+		t.Errorf("stats = %v, totals = %+v, want only alice", gb.Stats, gb.Totals())
+	}
+}
+
+func TestCollectNoMerges(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "start")
+	repo.git("checkout", "-q", "-b", "feature")
+	repo.write("b.md", "two\nthree\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "feature")
+	repo.git("checkout", "-q", "-")
+	date := "2024-03-07T12:00:00Z"
+	repo.gitEnv([]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date},
+		"-c", "user.name=merger", "-c", "user.email=merger@example.com", "merge", "-q", "--no-ff", "-m", "merge feature", "feature")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{Path: repo.Dir, Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// git log shows no diff for merges, so a counted merge only adds a commit
+	if got := gb.Stats["merger@example.com"]["march"]; got != (ChangesStats{Commits: 1}) {
+		t.Errorf("merger = %+v, want the merge counted without lines", got)
+	}
+
+	opts.NoMerges = true
+	if gb, err = Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["merger@example.com"]; ok {
+		t.Errorf("stats = %v, want the merge commit skipped", gb.Stats)
+	}
+	if got := gb.Stats["bob@example.com"]["march"]; got != (ChangesStats{Insertions: 2, Commits: 1}) {
+		t.Errorf("bob = %+v, want the merged commit itself", got)
+	}
+}
+
+func TestCollectDetectsRenames(t *testing.T) {
+	repo := newFixtureRepo(t)
+	var lines strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&lines, "line %d\n", i)
+	}
+	repo.write("old.md", lines.String())
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "add old")
+	if err := os.Remove(filepath.Join(repo.Dir, "old.md")); err != nil {
+		t.Fatal(err)
+	}
+	repo.write("new.md", strings.Replace(lines.String(), "line 7\n", "line seven\n", 1))
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "rename with a tweak")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := Options{Path: repo.Dir, Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}}
+	for _, numstat := range []bool{false, true} {
+		opts.Numstat, opts.NoRenames = numstat, false
+		gb, err := Collect(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["bob@example.com"]["march"]; got != (ChangesStats{Insertions: 1, Deletions: 1, Commits: 1}) {
+			t.Errorf("numstat %v: bob = %+v, want the one-line edit", numstat, got)
+		}
+
+		opts.NoRenames = true
+		if gb, err = Collect(opts); err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["bob@example.com"]["march"]; got != (ChangesStats{Insertions: 20, Deletions: 20, Commits: 1}) {
+			t.Errorf("numstat %v, no renames: bob = %+v, want the whole file", numstat, got)
+		}
+	}
+}
+
+func TestCollectDetectsCopies(t *testing.T) {
+	repo := newFixtureRepo(t)
+	var lines, edited strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&lines, "line %d\n", i)
+		if i <= 12 {
+			fmt.Fprintf(&edited, "changed %d\n", i)
+		} else {
+			fmt.Fprintf(&edited, "line %d\n", i)
+		}
+	}
+	repo.write("a.md", lines.String())
+	repo.write("c.md", lines.String())
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "init")
+	repo.write("a.md", lines.String()+"extra\n")
+	repo.write("b.md", lines.String())
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "copy a changed file")
+	repo.write("d.md", lines.String())
+	repo.commit("carol@example.com", "2024-03-07T12:00:00Z", "copy an untouched file")
+	if err := os.Remove(filepath.Join(repo.Dir, "c.md")); err != nil {
+		t.Fatal(err)
+	}
+	repo.write("e.md", edited.String())
+	repo.commit("dave@example.com", "2024-03-08T12:00:00Z", "move with large edits")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name             string
+		opts             Options
+		bob, carol, dave int // Lines changed
+	}{
+		{"renames", Options{}, 21, 20, 40},
+		{"copies", Options{FindCopies: true}, 1, 20, 40},
+		{"copies harder", Options{FindCopiesHarder: true}, 1, 0, 40},
+		{"threshold", Options{RenameThreshold: 30}, 21, 20, 24},
+	} {
+		tc.opts.Path = repo.Dir
+		tc.opts.Periods = []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}
+		gb, err := Collect(tc.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := func(author string) int {
+			stats := gb.Stats[author]["march"]
+			return stats.Insertions + stats.Deletions
+		}
+		if lines("bob@example.com") != tc.bob || lines("carol@example.com") != tc.carol || lines("dave@example.com") != tc.dave {
+			t.Errorf("%s: got bob %d, carol %d, dave %d lines, want %d, %d, %d", tc.name,
+				lines("bob@example.com"), lines("carol@example.com"), lines("dave@example.com"), tc.bob, tc.carol, tc.dave)
+		}
+	}
+}
+
+func TestRenderHTMLEscapesAuthors(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"<script>@example.com": {Insertions: 1, Commits: 1},
+		"bob@example.com":      {Insertions: 4, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	var buf strings.Builder
+	if err := Render(&buf, *gb, RenderOptions{Format: "html", Sort: "insertions"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<script>") || !strings.Contains(out, "&lt;script&gt;@example.com") {
+		t.Errorf("author email not escaped:\n%s", out)
+	}
+	if bob, other := strings.Index(out, "bob@example.com"), strings.Index(out, "&lt;script&gt;"); bob > other {
+		t.Error("bob, the largest contributor, should be listed first")
+	}
+	if !strings.Contains(out, `style="width: 25%"`) {
+		t.Errorf("missing bar scaled to the largest contribution:\n%s", out)
+	}
+}
+
+// logCountingGit runs git like ExecGit, counting the commits git log emits.
+type logCountingGit struct {
+	commits *int
+}
+
+var commitHeader = regexp.MustCompile(`(?m)(^|\x00)[0-9a-f]{40}\t`)
+
+func (g logCountingGit) Run(args ...string) ([]byte, error) {
+	output, err := ExecGit{}.Run(args...)
+	if slices.Contains(args, "log") {
+		*g.commits += len(commitHeader.FindAll(output, -1))
+	}
+	return output, err
+}
+
+// concurrencyGit runs git like ExecGit, recording the most git log commands running at
+// once. Each is held for a moment so that concurrent ones overlap.
+type concurrencyGit struct {
+	mu               *sync.Mutex
+	running, maxSeen *int
+}
+
+func (g concurrencyGit) Run(args ...string) ([]byte, error) {
+	if !slices.Contains(args, "log") {
+		return ExecGit{}.Run(args...)
+	}
+	g.mu.Lock()
+	*g.running++
+	*g.maxSeen = max(*g.maxSeen, *g.running)
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		*g.running--
+		g.mu.Unlock()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	return ExecGit{}.Run(args...)
+}
+
+func TestCollectJobsBoundsWorkers(t *testing.T) {
+	base := t.TempDir()
+	for i := 0; i < 6; i++ {
+		repo := newFixtureRepoAt(t, filepath.Join(base, fmt.Sprintf("repo%d", i)))
+		repo.write("a.md", "one\n")
+		repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "change")
+	}
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, jobs := range []int{1, 3} {
+		var running, maxSeen int
+		gb, err := Collect(Options{
+			Path:    base,
+			All:     true,
+			Jobs:    jobs,
+			Periods: []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+			Git:     concurrencyGit{mu: new(sync.Mutex), running: &running, maxSeen: &maxSeen},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["alice@example.com"]["march"].Commits; got != 6 {
+			t.Errorf("%d jobs: %d commits, want one per repository", jobs, got)
+		}
+		if maxSeen != jobs {
+			t.Errorf("%d jobs: %d git log commands ran at once, want %d", jobs, maxSeen, jobs)
+		}
+	}
+}
+
+func TestCollectMaxCommitsLimitsGit(t *testing.T) {
+	repo := newFixtureRepo(t)
+	for day := 1; day <= 5; day++ {
+		repo.write("a.md", strings.Repeat("line\n", day))
+		repo.commit("alice@example.com", fmt.Sprintf("2024-03-%02dT12:00:00Z", day*5), "change")
+	}
+	repo.write("a.md", "april\n")
+	repo.commit("alice@example.com", "2024-04-10T12:00:00Z", "april")
+
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+	periods := []Period{
+		{Label: "april", Since: april, Until: april.AddDate(0, 1, -1)},
+		{Label: "march", Since: march, Until: april.AddDate(0, 0, -1)},
+	}
+	commits := 0
+	gb, err := Collect(Options{Path: repo.Dir, Periods: periods, MaxCommits: 2, Git: logCountingGit{commits: &commits}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"].Commits; got != 2 {
+		t.Errorf("march commits = %d, want 2", got)
+	}
+	if got := gb.Stats["alice@example.com"]["april"].Commits; got != 1 {
+		t.Errorf("april commits = %d, want 1", got)
+	}
+	if commits > 3 {
+		t.Errorf("git log emitted %d commits, want at most 2 per period", commits)
+	}
+}
+
+func TestCollectCountsWholeBoundaryDays(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-01T00:30:00", "first day")
+	repo.write("a.md", "one\ntwo\n")
+	repo.commit("bob@example.com", "2024-03-31T14:00:00", "last day")
+	repo.write("a.md", "one\ntwo\nthree\n")
+	repo.commit("carol@example.com", "2024-04-01T00:30:00", "next month")
+
+	gb, err := Collect(Options{Path: repo.Dir, Periods: MonthPeriods(1, time.Date(2024, 3, 15, 0, 0, 0, 0, time.Local))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["carol@example.com"]; ok || len(gb.Stats) != 2 {
+		t.Errorf("stats = %v, want alice and bob, the first and last day of March", gb.Stats)
+	}
+}
+
+func TestAverageLines(t *testing.T) {
+	if got := averageLines(ChangesStats{Insertions: 10, Commits: 4}); got != "2.5" {
+		t.Errorf("10 insertions in 4 commits = %q, want 2.5", got)
+	}
+	if got := averageLines(ChangesStats{Insertions: 10}); got != "0.0" {
+		t.Errorf("no commits = %q, want 0.0", got)
+	}
+}
+
+func TestCollectBucketsInCommitOffset(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	// 1 April in Tokyo is still 31 March in UTC
+	repo.commit("alice@example.com", "2024-04-01T01:00:00+09:00", "early in Tokyo")
+
+	periods := MonthPeriods(2, time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC))
+	gb, err := Collect(Options{Path: repo.Dir, Periods: periods})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["alice@example.com"][periods[0].Label]; !ok {
+		t.Errorf("own offset: stats = %v, want April", gb.Stats)
+	}
+
+	if gb, err = Collect(Options{Path: repo.Dir, Periods: periods, Location: time.UTC}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["alice@example.com"][periods[1].Label]; !ok {
+		t.Errorf("UTC: stats = %v, want March", gb.Stats)
+	}
+}
+
+func TestCollectCache(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("a.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "march")
+
+	periods := MonthPeriods(2, time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC))
+	march, april := periods[1].Label, periods[0].Label
+	opts := Options{Path: repo.Dir, Periods: periods, CacheDir: t.TempDir()}
+	if _, err := Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries still valid for the head are loaded instead of running git log
+	entries, _ := filepath.Glob(filepath.Join(opts.CacheDir, "*.json"))
+	if len(entries) != 2 {
+		t.Fatalf("got %d cache entries, want one per period", len(entries))
+	}
+	for _, file := range entries {
+		data, _ := os.ReadFile(file)
+		os.WriteFile(file, []byte(strings.ReplaceAll(string(data), "alice@example.com", "cached@example.com")), 0o644)
+	}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["cached@example.com"][march]; !ok {
+		t.Errorf("stats = %v, want the cached march", gb.Stats)
+	}
+
+	// A new commit only invalidates its own period
+	repo.write("a.md", "one\ntwo\n")
+	repo.commit("bob@example.com", "2024-04-02T12:00:00Z", "april")
+	if gb, err = Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["cached@example.com"][march]; !ok {
+		t.Errorf("stats = %v, want march still cached", gb.Stats)
+	}
+	if got := gb.Stats["bob@example.com"][april].Insertions; got != 1 {
+		t.Errorf("bob in april = %d, want 1", got)
+	}
+
+	// A late commit authored in march invalidates march
+	repo.write("a.md", "one\ntwo\nthree\n")
+	repo.gitEnv([]string{"GIT_AUTHOR_DATE=2024-03-20T12:00:00Z", "GIT_COMMITTER_DATE=2024-04-03T12:00:00Z"},
+		"-c", "user.name=carol", "-c", "user.email=carol@example.com", "commit", "-q", "-am", "late")
+	if gb, err = Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gb.Stats["cached@example.com"]; ok {
+		t.Errorf("stats = %v, want march recomputed", gb.Stats)
+	}
+	if gb.Stats["alice@example.com"][march].Insertions != 1 || gb.Stats["carol@example.com"][march].Insertions != 1 {
+		t.Errorf("stats = %v, want alice and carol in march", gb.Stats)
+	}
+}
+
+func TestCollectCacheScansOnlyNewCommits(t *testing.T) {
+	repo := newFixtureRepo(t)
+	for day := 1; day <= 3; day++ {
+		repo.write("a.md", strings.Repeat("line\n", day))
+		repo.commit("alice@example.com", fmt.Sprintf("2024-03-%02dT12:00:00Z", day*5), "march")
+	}
+
+	periods := MonthPeriods(2, time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC))
+	commits := 0
+	opts := Options{Path: repo.Dir, Periods: periods, CacheDir: t.TempDir(), Git: logCountingGit{commits: &commits}}
+	if _, err := Collect(opts); err != nil {
+		t.Fatal(err)
+	}
+	if commits != 3 {
+		t.Fatalf("first run: git log emitted %d commits, want 3", commits)
+	}
+
+	repo.write("a.md", "april\n")
+	repo.commit("bob@example.com", "2024-04-10T12:00:00Z", "april")
+	commits = 0
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// march is loaded from the cache, only april is read from git again
+	if commits != 1 {
+		t.Errorf("second run: git log emitted %d commits, want only the new one", commits)
+	}
+	if gb.Stats["alice@example.com"][periods[1].Label].Commits != 3 || gb.Stats["bob@example.com"][periods[0].Label].Commits != 1 {
+		t.Errorf("stats = %v, want alice's 3 commits in march and bob's in april", gb.Stats)
+	}
+}
+
+func TestCollectAllFiles(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "one\ntwo\n")
+	repo.write("Makefile", "all:\n")
+	repo.write("notes.md", "one\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "add files")
+	repo.write("main.go", "one\n")
+	repo.commit("bob@example.com", "2024-03-06T12:00:00Z", "trim")
+
+	period := Period{Label: "march", Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)}
+	gb, err := Collect(Options{Path: repo.Dir, Periods: []Period{period}, Extensions: []string{"go"}, AllFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without extensions every file is analyzed as well
+	byDefault, err := Collect(Options{Path: repo.Dir, Periods: []Period{period}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manual := ParseLog(repo.git(append([]string{"log", "--pretty=%H%x09%aE%x09%at%x09%aN", "--shortstat"}, period.Args()...)...), make(map[string]bool))
+	var want ChangesStats
+	for _, stats := range manual.Stats {
+		want.Insertions += stats.Insertions
+		want.Deletions += stats.Deletions
+		want.Commits += stats.Commits
+	}
+	if got := gb.Totals(); got != want || want.Insertions != 4 {
+		t.Errorf("totals = %+v, want %+v as git log --shortstat reports", got, want)
+	}
+	if got := byDefault.Totals(); got != want {
+		t.Errorf("default totals = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseShortstat(t *testing.T) {
+	for _, tc := range []struct {
+		name                  string
+		line                  string
+		insertions, deletions int
+		ok                    bool
+	}{
+		{"plural", " 3 files changed, 10 insertions(+), 2 deletions(-)", 10, 2, true},
+		{"singular", " 1 file changed, 1 insertion(+), 1 deletion(-)", 1, 1, true},
+		{"insertions only", " 2 files changed, 7 insertions(+)", 7, 0, true},
+		{"deletions only", " 1 file changed, 4 deletions(-)", 0, 4, true},
+		{"no line changes", " 1 file changed, 0 insertions(+), 0 deletions(-)", 0, 0, true},
+		{"not a stat line", "Merge branch 'main'", 0, 0, false},
+		{"blank", "", 0, 0, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ins, del, ok := parseShortstat(tc.line)
+			if ins != tc.insertions || del != tc.deletions || ok != tc.ok {
+				t.Errorf("parseShortstat(%q) = %d, %d, %v, want %d, %d, %v", tc.line, ins, del, ok, tc.insertions, tc.deletions, tc.ok)
+			}
+		})
+	}
+}
+
+func TestParseLogTable(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		output string
+		want   map[string]ChangesStats
+	}{
+		{
+			name: "multi-file commits",
+			output: "c1\talice@example.com\t1710000000\tAlice\n\n 3 files changed, 10 insertions(+), 2 deletions(-)\n" +
+				"c2\talice@example.com\t1710000100\tAlice\n\n 2 files changed, 5 insertions(+), 1 deletion(-)\n",
+			want: map[string]ChangesStats{"alice@example.com": {Insertions: 15, Deletions: 3, Commits: 2}},
+		},
+		{
+			name: "singular and plural",
+			output: "c1\talice@example.com\t1710000000\tAlice\n\n 1 file changed, 1 insertion(+)\n" +
+				"c2\tbob@example.com\t1710000100\tBob\n\n 2 files changed, 1 deletion(-)\n",
+			want: map[string]ChangesStats{
+				"alice@example.com": {Insertions: 1, Commits: 1},
+				"bob@example.com":   {Deletions: 1, Commits: 1},
+			},
+		},
+		{
+			name: "commit without shortstat",
+			output: "c1\talice@example.com\t1710000000\tAlice\n" +
+				"c2\tbob@example.com\t1710000100\tBob\n\n 1 file changed, 2 insertions(+)\n",
+			want: map[string]ChangesStats{
+				"alice@example.com": {Commits: 1},
+				"bob@example.com":   {Insertions: 2, Commits: 1},
+			},
+		},
+		{
+			name:   "blank lines",
+			output: "\n\nc1\talice@example.com\t1710000000\tAlice\n\n\n\n 1 file changed, 3 insertions(+)\n\n",
+			want:   map[string]ChangesStats{"alice@example.com": {Insertions: 3, Commits: 1}},
+		},
+		{
+			name:   "empty output",
+			output: "",
+			want:   map[string]ChangesStats{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := ParseLog(tc.output, make(map[string]bool))
+			if len(result.Stats) != len(tc.want) {
+				t.Errorf("stats = %v, want %v", result.Stats, tc.want)
+			}
+			for author, want := range tc.want {
+				if got := result.Stats[author]; got != want {
+					t.Errorf("%s = %+v, want %+v", author, got, want)
+				}
+			}
+			if len(result.Warnings) != 0 {
+				t.Errorf("unexpected warnings: %v", result.Warnings)
+			}
+		})
+	}
+}
+
+func TestRenderJSONReport(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{
+		Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 3, Deletions: 1, Commits: 2}},
+		Names: map[string]string{"alice@example.com": "Alice", "bot@example.com": "Filtered Bot"},
+	}, "(2024-03) March 2024")
+	gb.Tags["alice@example.com"] = 1
+
+	var buf strings.Builder
+	if err := Render(&buf, *gb, RenderOptions{Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		TotalInsertions int               `json:"totalInsertions"`
+		TotalCommits    int               `json:"totalCommits"`
+		Names           map[string]string `json:"names"`
+		Tags            map[string]int    `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalInsertions != 3 || report.TotalCommits != 2 || report.Tags["alice@example.com"] != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if len(report.Names) != 1 || report.Names["alice@example.com"] != "Alice" {
+		t.Errorf("names = %v, want only the reported alice", report.Names)
+	}
+}
+
+// fakeGit answers git log with canned output and fails every other command.
+type fakeGit struct {
+	log string
+}
+
+func (g fakeGit) Run(args ...string) ([]byte, error) {
+	for _, arg := range args {
+		if arg == "log" {
+			return []byte(g.log), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected command %q", args)
+}
+
+func TestCollectUsesGit(t *testing.T) {
+	git := fakeGit{log: "abc\talice@example.com\t2024-03-05T12:00:00Z\tAlice\tAlice\talice@example.com\t2024-03-05T12:00:00Z\n" +
+		" 1 file changed, 4 insertions(+), 1 deletion(-)\n"}
+	periods := MonthPeriods(1, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	gb, err := NewCollector(Options{Path: t.TempDir(), Periods: periods, Git: git}).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := gb.Stats["alice@example.com"][periods[0].Label]
+	if want := (ChangesStats{Insertions: 4, Deletions: 1, Commits: 1}); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestCollectMergesIdentities(t *testing.T) {
+	git := fakeGit{log: "a1\talice@corp.com\t2024-03-05T12:00:00Z\tAlice\n" +
+		" 1 file changed, 4 insertions(+)\n" +
+		"a2\tAlice@Home.net\t2024-03-06T12:00:00Z\tAlice\n" +
+		" 1 file changed, 2 insertions(+), 1 deletion(-)\n" +
+		"a3\t1234+alice@users.noreply.github.com\t2024-03-07T12:00:00Z\tAli S.\n" +
+		" 1 file changed, 1 insertion(+)\n" +
+		"b1\tbob@corp.com\t2024-03-08T12:00:00Z\tBob\n" +
+		" 1 file changed, 3 insertions(+)\n"}
+	periods := MonthPeriods(1, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	gb, err := Collect(Options{Path: t.TempDir(), Periods: periods, Git: git, Identities: []Identity{
+		{Email: "alice@corp.com", Name: "Alice Smith", Aliases: []string{"alice@home.net", "ali s."}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	month := periods[0].Label
+	if got, want := gb.Stats["alice@corp.com"][month], (ChangesStats{Insertions: 7, Deletions: 1, Commits: 3}); got != want {
+		t.Errorf("alice = %+v, want %+v", got, want)
+	}
+	if got, want := gb.Stats["bob@corp.com"][month], (ChangesStats{Insertions: 3, Commits: 1}); got != want {
+		t.Errorf("bob = %+v, want %+v", got, want)
+	}
+	if len(gb.Stats) != 2 {
+		t.Errorf("authors = %v, want the aliases merged", gb.Stats)
+	}
+	if got := gb.Names["alice@corp.com"]; got != "Alice Smith" {
+		t.Errorf("name = %q, want the configured name", got)
+	}
+	if got := gb.LastCommit["alice@corp.com"]; !got.Equal(time.Date(2024, 3, 7, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("last commit = %v, want the alias's", got)
+	}
+}
+
+func TestRenderRepoMatrix(t *testing.T) {
+	month := "(2024-03) March 2024"
+	api, web, gb := NewGlobalStats(), NewGlobalStats(), NewGlobalStats()
+	api.Add(LogResult{Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 10, Deletions: 2, Commits: 1}}}, month)
+	web.Add(LogResult{Stats: map[string]ChangesStats{"bob@example.com": {Insertions: 30, Commits: 2}}}, month)
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 10, Deletions: 2, Commits: 1},
+		"bob@example.com":   {Insertions: 30, Commits: 2},
+	}}, month)
+	gb.Repos = map[string]*GlobalStats{"api": api, "web": web}
+
+	var buf strings.Builder
+	if err := Render(&buf, *gb, RenderOptions{Format: "markdown", Sort: "net"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	_, matrix, ok := strings.Cut(out, "Net lines by developer and repository:")
+	if !ok {
+		t.Fatalf("missing matrix:\n%s", out)
+	}
+	for _, want := range []string{
+		"| Author            | api | web | Total |",
+		"| bob@example.com   |   - |  30 |    30 |",
+		"| alice@example.com |   8 |   - |     8 |",
+	} {
+		if !strings.Contains(matrix, want) {
+			t.Errorf("matrix lacks %q:\n%s", want, matrix)
+		}
+	}
+}
+
+func TestRenderDelimitedCommits(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 3, Deletions: 1, Commits: 7},
+	}}, "(2024-03) March 2024")
+
+	var buf strings.Builder
+	if err := Render(&buf, *gb, RenderOptions{Format: "delimited", Delimiter: "|", Header: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "author|month|insertions|deletions|commits\nalice@example.com|(2024-03) March 2024|3|1|7\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderSQL(t *testing.T) {
+	repo := NewGlobalStats()
+	repo.Add(LogResult{
+		Stats: map[string]ChangesStats{"o'brien@example.com": {Insertions: 3, Deletions: 1, Commits: 2}},
+		Names: map[string]string{"o'brien@example.com": "Pat O'Brien"},
+	}, "(2024-03) March 2024")
+	gb := *repo
+	gb.Repos = map[string]*GlobalStats{"api": repo}
+
+	var buf strings.Builder
+	if err := Render(&buf, gb, RenderOptions{Format: "sql"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "BEGIN;\nCREATE TABLE IF NOT EXISTS changes (") || !strings.HasSuffix(out, "COMMIT;\n") {
+		t.Errorf("not a transaction creating the table:\n%s", out)
+	}
+	want := "INSERT INTO changes VALUES ('api', 'o''brien@example.com', '2024-03', 'Pat O''Brien', 3, 1, 2) ON CONFLICT (repo, author, period) DO UPDATE"
+	if !strings.Contains(out, want) {
+		t.Errorf("missing upsert %q:\n%s", want, out)
+	}
+	if strings.Count(out, "INSERT") != 1 {
+		t.Errorf("want one row per repository, got:\n%s", out)
+	}
+}
+
+func TestRenderPrometheus(t *testing.T) {
+	repo := NewGlobalStats()
+	repo.Add(LogResult{Stats: map[string]ChangesStats{
+		`"quoted"@example.com`: {Insertions: 3, Deletions: 1, Commits: 2},
+	}}, "(2024-03) March 2024")
+	gb := *repo
+	gb.Repos = map[string]*GlobalStats{"api": repo}
+
+	var buf strings.Builder
+	if err := Render(&buf, gb, RenderOptions{Format: "prometheus"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE gitstats_insertions_total counter\n",
+		`gitstats_insertions_total{author="\"quoted\"@example.com",repo="api",month="2024-03"} 3`,
+		`gitstats_deletions_total{author="\"quoted\"@example.com",repo="api",month="2024-03"} 1`,
+		`gitstats_commits_total{author="\"quoted\"@example.com",repo="api",month="2024-03"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestKeepPeriods(t *testing.T) {
+	repo := NewGlobalStats()
+	repo.Add(LogResult{Stats: map[string]ChangesStats{"alice@example.com": {Insertions: 3}}}, "(2024-02) February 2024")
+	repo.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 5},
+		"bob@example.com":   {Insertions: 7},
+	}}, "(2024-03) March 2024")
+	gb := *repo
+	gb.Repos = map[string]*GlobalStats{"api": repo}
+
+	kept := KeepPeriods(&gb, map[string]bool{"(2024-02) February 2024": true})
+	want := map[string]map[string]ChangesStats{"alice@example.com": {"(2024-02) February 2024": {Insertions: 3}}}
+	if fmt.Sprint(kept.Stats) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", kept.Stats, want)
+	}
+	if fmt.Sprint(kept.Repos["api"].Stats) != fmt.Sprint(want) {
+		t.Errorf("got %v in the repo, want %v", kept.Repos["api"].Stats, want)
+	}
+	if len(gb.Stats["alice@example.com"]) != 2 {
+		t.Errorf("the original stats were modified: %v", gb.Stats)
+	}
+}
+
+func TestNewSummary(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 3, Deletions: 1, Commits: 1},
+		"bob@example.com":   {Insertions: 9, Deletions: 0, Commits: 2},
+	}, Names: map[string]string{"bob@example.com": "Bob"}}, "(2024-03) March 2024")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 10, Deletions: 0, Commits: 1},
+	}}, "(2024-02) February 2024")
+
+	summary := NewSummary(*gb, "net", false)
+	if len(summary.Periods) != 2 || summary.Periods[0].Label != "(2024-02) February 2024" {
+		t.Fatalf("want February then March, got %+v", summary.Periods)
+	}
+	march := summary.Periods[1]
+	if march.Authors[0].Author != "bob@example.com" || march.Authors[0].Name != "Bob" || march.Net != 11 {
+		t.Errorf("want Bob first and 11 net lines, got %+v", march)
+	}
+	if total := summary.Total; total.Authors[0].Author != "alice@example.com" || total.Authors[0].Net != 12 || total.Commits != 4 {
+		t.Errorf("want alice first with 12 net lines over 4 commits, got %+v", total)
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 3, Deletions: 1, Commits: 1},
+		"bob@example.com":   {Insertions: 9, Deletions: 0, Commits: 3},
+	}, Names: map[string]string{"bob@example.com": "Bob"}}, "(2024-03) March 2024")
+
+	tmpl, err := ParseTemplate("report", `{{range .Periods}}{{.Label}}: {{.Net}}
+{{range .Authors}}{{or .Name .Author}} {{percent .Commits $.Total.Commits}}%
+{{end}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := Render(&out, *gb, RenderOptions{Format: "template", Template: tmpl}); err != nil {
+		t.Fatal(err)
+	}
+	want := "(2024-03) March 2024: 11\nBob 75%\nalice@example.com 25%\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	if _, err := ParseTemplate("report", "{{.Periods"); err == nil {
+		t.Error("malformed template: want error")
+	}
+	if err := Render(&out, *gb, RenderOptions{Format: "template"}); err == nil {
+		t.Error("template format without a template: want error")
+	}
+	tmpl, _ = ParseTemplate("report", "{{.Missing}}")
+	if err := Render(&out, *gb, RenderOptions{Format: "template", Template: tmpl}); err == nil {
+		t.Error("unknown field: want error")
+	}
+}
+
+func TestRenderXLSX(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 3, Deletions: 5, Commits: 1},
+		"bob@example.com":   {Insertions: 9, Deletions: 0, Commits: 3},
+	}, Names: map[string]string{"bob@example.com": "Bob & Co"}}, "(2024-03) March 2024")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 10, Deletions: 0, Commits: 1},
+	}}, "(2024-02) February 2024")
+
+	var out bytes.Buffer
+	if err := RenderXLSX(&out, *gb, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string]string)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		// Every part is well-formed XML
+		for d := xml.NewDecoder(bytes.NewReader(data)); ; {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %s", f.Name, err)
+			}
+		}
+		parts[f.Name] = string(data)
+	}
+	for _, want := range []string{`<sheet name="Summary"`, `<sheet name="(2024-02) February 2024"`, `<sheet name="(2024-03) March 2024"`} {
+		if !strings.Contains(parts["xl/workbook.xml"], want) {
+			t.Errorf("workbook misses %s:\n%s", want, parts["xl/workbook.xml"])
+		}
+	}
+	summary := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{"Bob &amp; Co", `<f>SUM(C2:C3)</f><v>5</v>`, `<f>SUM(F2:F3)</f><v>17</v>`, `type="dataBar"`, `operator="lessThan"`} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary sheet misses %s:\n%s", want, summary)
+		}
+	}
+	if _, ok := parts["xl/worksheets/sheet3.xml"]; !ok {
+		t.Errorf("want a sheet per month, got %d parts", len(parts))
+	}
+
+	names := xlsxSheetNames([]SummaryPeriod{{Label: "Total"}, {Label: "Sprint 1/2: a very long name beyond the limit"}, {Label: "Sprint 1/2: a very long name beyond the limit, again"}})
+	if names[1] != "Sprint 1-2- a very long name be" || names[2] != "Sprint 1-2- a very long nam (2)" {
+		t.Errorf("got sheet names %q", names)
+	}
+}
+
+func TestLanguageProfile(t *testing.T) {
+	profile := LanguageProfile(map[string]ChangesStats{
+		"kt":   {Insertions: 500, Deletions: 100},
+		"yml":  {Insertions: 200},
+		"yaml": {Insertions: 50, Deletions: 50},
+		"md":   {Insertions: 96},
+		"foo":  {Insertions: 3},
+		"bar":  {Deletions: 1},
+		"png":  {Commits: 2},
+	})
+	if len(profile) != 5 || profile[0] != (LanguageShare{Language: "Kotlin", Lines: 600, Percent: 60}) || profile[1].Language != "YAML" || profile[1].Lines != 300 {
+		t.Fatalf("got %+v", profile)
+	}
+	if got, want := formatProfile(profile), "60% Kotlin, 30% YAML, 10% Markdown, 1% other"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if LanguageProfile(map[string]ChangesStats{"png": {Commits: 1}}) != nil {
+		t.Error("binary changes only: want no profile")
+	}
+}
+
+func TestGroupByTeam(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@backend.example.com": {Insertions: 3, Deletions: 1, Commits: 1},
+		"bob@backend.example.com":   {Insertions: 5, Deletions: 0, Commits: 2},
+		"carol@example.com":         {Insertions: 7, Deletions: 2, Commits: 1},
+		"dave@example.com":          {Insertions: 1, Deletions: 0, Commits: 1},
+	}}, "(2024-03) March 2024")
+	teams := []Team{
+		{Name: "Backend", Members: []string{"*@backend.example.com"}},
+		{Name: "Frontend", Members: []string{"/^carol@/"}},
+	}
+
+	grouped, err := GroupByTeam(gb, teams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]ChangesStats{
+		"Backend":  {Insertions: 8, Deletions: 1, Commits: 3},
+		"Frontend": {Insertions: 7, Deletions: 2, Commits: 1},
+		NoTeam:     {Insertions: 1, Deletions: 0, Commits: 1},
+	}
+	for team, stats := range want {
+		if got := grouped.Stats[team]["(2024-03) March 2024"]; got != stats {
+			t.Errorf("%s: got %+v, want %+v", team, got, stats)
+		}
+	}
+	if len(grouped.Stats) != len(want) {
+		t.Errorf("got teams %v", grouped.Stats)
+	}
+
+	var buf strings.Builder
+	if err := Render(&buf, *grouped, RenderOptions{Teams: true}); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Total lines by team") || strings.Contains(out, "Author") {
+		t.Errorf("want team headings:\n%s", out)
+	}
+
+	if _, err := GroupByTeam(gb, []Team{{Name: "Broken", Members: []string{"/(/"}}}); err == nil {
+		t.Error("want an error for an invalid member pattern")
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	const month = "(2024-03) March 2024"
+	gb := NewGlobalStats()
+	gb.Add(LogResult{
+		Stats: map[string]ChangesStats{
+			"alice@example.com": {Insertions: 3, Commits: 1},
+			"carol@example.com": {Insertions: 7, Commits: 1},
+		},
+		Names: map[string]string{"alice@example.com": "Alice", "carol@example.com": "Carol"},
+	}, month)
+	gb.PullRequests = []PullRequest{{Number: 4, Author: "carol@example.com"}}
+	gb.Repos = map[string]*GlobalStats{"api": NewGlobalStats()}
+	gb.Repos["api"].Add(LogResult{Stats: map[string]ChangesStats{"carol@example.com": {Insertions: 7, Commits: 1}}}, month)
+
+	pseudonyms := map[string]string{"carol@example.com": "Dev-04"}
+	anonymized := Anonymize(gb, pseudonyms)
+	if want := map[string]string{"alice@example.com": "Dev-05", "carol@example.com": "Dev-04"}; fmt.Sprint(pseudonyms) != fmt.Sprint(want) {
+		t.Errorf("got pseudonyms %v, want %v", pseudonyms, want)
+	}
+	if got := anonymized.Stats["Dev-05"][month]; got != (ChangesStats{Insertions: 3, Commits: 1}) {
+		t.Errorf("Dev-05: got %+v", got)
+	}
+	if got := anonymized.Repos["api"].Stats["Dev-04"][month].Insertions; got != 7 {
+		t.Errorf("api Dev-04: got %d insertions, want 7", got)
+	}
+	if anonymized.PullRequests[0].Author != "Dev-04" || gb.PullRequests[0].Author != "carol@example.com" {
+		t.Errorf("pull request authors: got %q, original %q", anonymized.PullRequests[0].Author, gb.PullRequests[0].Author)
+	}
+
+	var buf strings.Builder
+	if err := Render(&buf, *anonymized, RenderOptions{Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); strings.Contains(out, "example.com") || strings.Contains(out, "Alice") {
+		t.Errorf("personal data left in:\n%s", out)
+	}
+}
+
+func TestRenderPathStatsNet(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 2, Deletions: 5, Commits: 1},
+	}}, "(2024-03) March 2024")
+
+	var buf strings.Builder
+	if err := Render(&buf, *gb, RenderOptions{PathStats: "src"}); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "+2     -5     -3 net") {
+		t.Errorf("missing net lines:\n%s", out)
+	}
+}
+
+func TestCollectRepos(t *testing.T) {
+	api, web := newFixtureRepo(t), newFixtureRepo(t)
+	api.write("a.md", "one\n")
+	api.commit("alice@example.com", "2024-03-05T12:00:00Z", "api")
+	web.write("b.md", "two\nthree\n")
+	web.commit("alice@example.com", "2024-03-06T12:00:00Z", "web")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	periods := []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}
+	missing := filepath.Join(t.TempDir(), "missing")
+	gb, err := Collect(Options{Repos: []string{api.Dir, web.Dir, missing}, Periods: periods})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"]; got != (ChangesStats{Insertions: 3, Commits: 2}) {
+		t.Errorf("alice = %+v, want both repositories", got)
+	}
+	if len(gb.Warnings) != 1 {
+		t.Errorf("warnings = %q, want one for the missing repository", gb.Warnings)
+	}
+}
+
+func TestCollectPullRequestsRepoNames(t *testing.T) {
+	base := t.TempDir()
+	var dirs []string
+	for _, name := range []string{"legacy/api", "team/api"} {
+		repo := newFixtureRepoAt(t, filepath.Join(base, name))
+		repo.write("a.md", "one\n")
+		repo.commit("carol@example.com", "2024-03-05T12:00:00Z", "Fix typo (#12)")
+		dirs = append(dirs, repo.Dir)
+	}
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	periods := []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}
+	gb, err := Collect(Options{Path: base, Repos: dirs, Periods: periods, PullRequests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repos []string
+	for _, pr := range gb.PullRequests {
+		repos = append(repos, pr.Repo)
+	}
+	sort.Strings(repos)
+	if strings.Join(repos, " ") != "legacy/api team/api" {
+		t.Errorf("pull request repositories = %q, want the paths under the base directory", repos)
+	}
+}
+
+func TestCollectDedupesForks(t *testing.T) {
+	upstream, fork := newFixtureRepo(t), newFixtureRepo(t)
+	upstream.write("a.md", "one\n")
+	upstream.commit("alice@example.com", "2024-03-05T12:00:00Z", "first")
+	upstream.write("a.md", "one\ntwo\n")
+	upstream.commit("alice@example.com", "2024-03-06T12:00:00Z", "second")
+	fork.git("fetch", "-q", upstream.Dir, "HEAD")
+	fork.git("reset", "-q", "--hard", "FETCH_HEAD")
+	fork.write("b.md", "three\n")
+	fork.commit("bob@example.com", "2024-03-07T12:00:00Z", "fork only")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	periods := []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}
+	opts := Options{Repos: []string{upstream.Dir, fork.Dir}, Periods: periods, Dedupe: true}
+	gb, err := Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"]; got != (ChangesStats{Insertions: 2, Commits: 2}) {
+		t.Errorf("alice = %+v, want her commits counted once", got)
+	}
+	if got := gb.Stats["bob@example.com"]["march"]; got != (ChangesStats{Insertions: 1, Commits: 1}) {
+		t.Errorf("bob = %+v, want the commit of the fork", got)
+	}
+	if len(gb.Duplicates) != 1 || gb.Duplicates[RepoName("", fork.Dir)] != 2 {
+		t.Errorf("duplicates = %v, want 2 in the fork", gb.Duplicates)
+	}
+
+	opts.Dedupe = false
+	gb, err = Collect(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"]; got.Commits != 4 || gb.Duplicates != nil {
+		t.Errorf("without Dedupe alice = %+v, duplicates = %v, want both repositories counted", got, gb.Duplicates)
+	}
+}
+
+func TestRenderHTMLTop(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"alice@example.com": {Insertions: 10, Commits: 1},
+		"bob@example.com":   {Insertions: 4, Commits: 1},
+		"carol@example.com": {Insertions: 2, Commits: 3},
+	}}, "(2024-03) March 2024")
+
+	var buf strings.Builder
+	if err := Render(&buf, *gb, RenderOptions{Format: "html", Sort: "insertions", Top: 1}); err != nil {
+		t.Fatal(err)
+	}
+	_, developers, _ := strings.Cut(buf.String(), "Total lines by developer")
+	if strings.Contains(developers, "bob@example.com") || !strings.Contains(developers, "… and 2 others") {
+		t.Errorf("developer table not capped at one author:\n%s", developers)
+	}
+	if !strings.Contains(developers, `<td class="num">4</td><td class="num ins">6</td>`) {
+		t.Errorf("others row lacks the summed commits and insertions:\n%s", developers)
+	}
+	if month, _, _ := strings.Cut(buf.String(), "Total lines by developer"); !strings.Contains(month, "bob@example.com") {
+		t.Error("monthly table capped without TopAll")
+	}
+}
+
+func TestAuthorOrder(t *testing.T) {
+	authors := map[string]ChangesStats{
+		"bob@example.com":   {Insertions: 5, Deletions: 5, Commits: 9},
+		"alice@example.com": {Insertions: 10, Deletions: 1, Commits: 1},
+		"carol@example.com": {Insertions: 5, Deletions: 0, Commits: 1},
+	}
+	for _, tt := range []struct {
+		order authorOrder
+		want  string
+	}{
+		{authorOrder{"net", false}, "alice carol bob"},
+		{authorOrder{"insertions", false}, "alice bob carol"},
+		{authorOrder{"commits", false}, "bob alice carol"},
+		{authorOrder{"author", false}, "alice bob carol"},
+		{authorOrder{"author", true}, "carol bob alice"},
+		{authorOrder{"net", true}, "bob carol alice"},
+	} {
+		var names []string
+		for author := range authors {
+			names = append(names, author)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return tt.order.less(names[i], authors[names[i]], names[j], authors[names[j]])
+		})
+		for i := range names {
+			names[i] = strings.TrimSuffix(names[i], "@example.com")
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("%+v: got %s, want %s", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestConditions(t *testing.T) {
+	gb := NewGlobalStats()
+	gb.Add(LogResult{Stats: map[string]ChangesStats{
+		"a@example.com": {Insertions: 300, Deletions: 100, Commits: 4},
+		"b@example.com": {Insertions: 500, Deletions: 50, Commits: 2},
+	}}, "(2024-02) February 2024")
+	gb.Add(LogResult{Stats: map[string]ChangesStats{"a@example.com": {Insertions: 40, Deletions: 10, Commits: 1}}}, "(2024-03) March 2024")
+
+	for _, tc := range []struct {
+		expr  string
+		holds bool
+		value int
+	}{
+		{"total_insertions < 1000", true, 840},
+		{"total_net>=680", true, 680},
+		{"total_commits == 7", true, 7},
+		{"authors < 3", true, 2},
+		{"months != 2", false, 2},
+		{"last_month_insertions > 40", false, 40},
+		{"last_month_net <= 30", true, 30},
+		{"last_month_authors < 2", true, 1},
+		{"last_month_deletions >= -1", true, 10},
+	} {
+		condition, err := ParseCondition(tc.expr)
+		if err != nil {
+			t.Errorf("%s: %s", tc.expr, err)
+			continue
+		}
+		if holds, value := condition.Eval(*gb); holds != tc.holds || value != tc.value {
+			t.Errorf("%s = %v (%d), want %v (%d)", condition, holds, value, tc.holds, tc.value)
+		}
+	}
+
+	for _, expr := range []string{"", "authors", "authors < ", "authors =< 3", "lines < 3", "authors < 3 commits"} {
+		if _, err := ParseCondition(expr); err == nil {
+			t.Errorf("%q: want error", expr)
+		}
+	}
+}
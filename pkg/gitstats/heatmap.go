@@ -0,0 +1,111 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Heatmap counts commits by weekday, Monday first, and hour of the day.
+type Heatmap [7][24]int
+
+// add counts a commit at t.
+func (h *Heatmap) add(t time.Time) {
+	h[(int(t.Weekday())+6)%7][t.Hour()]++
+}
+
+// addHeatmap adds from to the heatmap of author in into.
+func addHeatmap(into map[string]Heatmap, author string, from Heatmap) {
+	into[author] = sumHeatmaps(into[author], from)
+}
+
+// max returns the largest count of h.
+func (h Heatmap) max() int {
+	largest := 0
+	for _, hours := range h {
+		for _, count := range hours {
+			largest = max(largest, count)
+		}
+	}
+	return largest
+}
+
+var weekdays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// heatmapShades are the cells of the text heatmap from no commits to the busiest hour.
+var heatmapShades = []string{"·", "░", "▒", "▓", "█"}
+
+// shade returns the index in heatmapShades of count relative to largest.
+func shade(count, largest int) int {
+	if count == 0 || largest == 0 {
+		return 0
+	}
+	return 1 + (count*(len(heatmapShades)-1)-1)/largest
+}
+
+// heatmapGrid is a titled heatmap of the report.
+type heatmapGrid struct {
+	Title string
+	Heatmap
+}
+
+// heatmapGrids returns the heatmap of all authors, and with byAuthor that of each
+// author sorted like the text report.
+func heatmapGrids(globalStats GlobalStats, order authorOrder, byAuthor bool) []heatmapGrid {
+	var total Heatmap
+	for _, h := range globalStats.Heatmap {
+		total = sumHeatmaps(total, h)
+	}
+	grids := []heatmapGrid{{"Commits by weekday and hour", total}}
+
+	if byAuthor {
+		var authors []string
+		totals := make(map[string]ChangesStats)
+		for author := range globalStats.Heatmap {
+			authors = append(authors, author)
+			totals[author] = sumMonths(globalStats.Stats[author])
+		}
+		sort.Slice(authors, func(i, j int) bool {
+			return order.less(authors[i], totals[authors[i]], authors[j], totals[authors[j]])
+		})
+		for _, author := range authors {
+			grids = append(grids, heatmapGrid{author, globalStats.Heatmap[author]})
+		}
+	}
+	return grids
+}
+
+// printHeatmap prints the commits of all authors by weekday and hour as a grid of
+// shades, and with byAuthor the grid of each author.
+func printHeatmap(w io.Writer, globalStats GlobalStats, order authorOrder, byAuthor bool) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	for _, grid := range heatmapGrids(globalStats, order, byAuthor) {
+		fmt.Fprintf(w, "\n%s%s:%s\n", blue, grid.Title, reset)
+		fmt.Fprintf(w, "       %s\n", "0     6     12    18   23")
+		largest := grid.max()
+		for day, hours := range grid.Heatmap {
+			var cells strings.Builder
+			total := 0
+			for _, count := range hours {
+				cells.WriteString(heatmapShades[shade(count, largest)])
+				total += count
+			}
+			fmt.Fprintf(w, "  %s  %s %5d\n", weekdays[day], cells.String(), total)
+		}
+	}
+	fmt.Fprintf(w, "  %s = busiest hour, %s = none\n", heatmapShades[len(heatmapShades)-1], heatmapShades[0])
+}
+
+// sumHeatmaps returns the counts of a and b added up.
+func sumHeatmaps(a, b Heatmap) Heatmap {
+	for day := range a {
+		for hour := range a[day] {
+			a[day][hour] += b[day][hour]
+		}
+	}
+	return a
+}
@@ -0,0 +1,181 @@
+package gitstats
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+)
+
+// htmlRow is one author of an HTML report section, with the width of its bar in
+// percent of the section's largest contribution.
+type htmlRow struct {
+	Author string
+	ChangesStats
+	Net int
+	Bar int
+}
+
+// htmlSection is one table of the HTML report: a month or the totals per developer.
+type htmlSection struct {
+	Title  string
+	Column string // Heading of the author column
+	Rows   []htmlRow
+	Total  ChangesStats
+	Net    int
+}
+
+// htmlHeatmap is a heatmap of the HTML report, a row of hour cells per weekday.
+type htmlHeatmap struct {
+	Title string
+	Rows  []htmlHeatmapRow
+}
+
+// htmlHeatmapRow is one weekday of an htmlHeatmap.
+type htmlHeatmapRow struct {
+	Day   string
+	Cells []htmlHeatmapCell
+}
+
+// htmlHeatmapCell is the commits of one hour, with its shade from 0 (none) to 4 (the
+// busiest hour of the heatmap).
+type htmlHeatmapCell struct {
+	Count int
+	Shade int
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Git statistics</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+h2 { font-size: 1.2em; margin-top: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.8em; border-bottom: 1px solid #d0d7de; }
+th { text-align: left; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+td.bar { width: 16em; }
+.bar div { background: #2da44e; height: 0.9em; }
+.ins { color: #1a7f37; }
+.del { color: #cf222e; }
+tfoot td { font-weight: bold; }
+table.heatmap td { width: 1.2em; height: 1.2em; padding: 0; border: 1px solid #fff; }
+table.heatmap th { padding: 0 0.5em 0 0; border: none; font-weight: normal; }
+.h0 { background: #ebedf0; }
+.h1 { background: #9be9a8; }
+.h2 { background: #40c463; }
+.h3 { background: #30a14e; }
+.h4 { background: #216e39; }
+</style>
+</head>
+<body>
+<h1>Git statistics</h1>
+{{range .Sections}}
+<h2>{{.Title}}</h2>
+<table>
+<thead><tr><th>{{.Column}}</th><th>Commits</th><th>Insertions</th><th>Deletions</th><th>Net</th><th></th></tr></thead>
+<tbody>
+{{range .Rows}}<tr><td>{{.Author}}</td><td class="num">{{.Commits}}</td><td class="num ins">{{.Insertions}}</td><td class="num del">{{.Deletions}}</td><td class="num">{{.Net}}</td><td class="bar"><div style="width: {{.Bar}}%"></div></td></tr>
+{{end}}</tbody>
+<tfoot><tr><td>Total</td><td class="num">{{.Total.Commits}}</td><td class="num ins">{{.Total.Insertions}}</td><td class="num del">{{.Total.Deletions}}</td><td class="num">{{.Net}}</td><td></td></tr></tfoot>
+</table>
+{{end}}
+{{range .Heatmaps}}
+<h2>{{.Title}}</h2>
+<table class="heatmap">
+{{range .Rows}}<tr><th>{{.Day}}</th>{{range $hour, $cell := .Cells}}<td class="h{{$cell.Shade}}" title="{{$cell.Count}} commits at {{$hour}}:00"></td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// printHTML writes a self-contained HTML page with a table and bar chart per month
+// and for the totals per developer. Authors are sorted like the text report and
+// their bars scaled by metric; author emails are escaped by html/template. With top > 0
+// the developer table lists the first top authors and sums the rest into one row, as
+// do the monthly tables with topAll. With teams the authors are teams. The heatmaps
+// follow the tables, with a cell per weekday and hour.
+func printHTML(w io.Writer, globalStats GlobalStats, order authorOrder, metric func(ChangesStats) int, top int, topAll, teams bool, heatmaps []heatmapGrid) error {
+	group, column := "developer", "Author"
+	if teams {
+		group, column = "team", "Team"
+	}
+	section := func(title string, authors map[string]ChangesStats, limit int) htmlSection {
+		s := htmlSection{Title: title, Column: column}
+		largest := 0
+		for author, stats := range authors {
+			s.Rows = append(s.Rows, htmlRow{Author: author, ChangesStats: stats, Net: stats.Insertions - stats.Deletions})
+			s.Total.Insertions += stats.Insertions
+			s.Total.Deletions += stats.Deletions
+			s.Total.Commits += stats.Commits
+			if metric(stats) > largest {
+				largest = metric(stats)
+			}
+		}
+		s.Net = s.Total.Insertions - s.Total.Deletions
+		sort.Slice(s.Rows, func(i, j int) bool {
+			return order.less(s.Rows[i].Author, s.Rows[i].ChangesStats, s.Rows[j].Author, s.Rows[j].ChangesStats)
+		})
+		if limit > 0 && len(s.Rows) > limit {
+			others := htmlRow{Author: fmt.Sprintf("… and %d others", len(s.Rows)-limit)}
+			for _, row := range s.Rows[limit:] {
+				others.Insertions += row.Insertions
+				others.Deletions += row.Deletions
+				others.Commits += row.Commits
+			}
+			others.Net = others.Insertions - others.Deletions
+			s.Rows = append(s.Rows[:limit], others)
+		}
+		for i, row := range s.Rows {
+			if value := metric(row.ChangesStats); largest > 0 && value > 0 {
+				// The others row may outgrow the largest author
+				s.Rows[i].Bar = min(value*100/largest, 100)
+			}
+		}
+		return s
+	}
+
+	monthLimit := 0
+	if topAll {
+		monthLimit = top
+	}
+	var sections []htmlSection
+	totals := make(map[string]ChangesStats)
+	for _, month := range sortedMonths(globalStats) {
+		authors := make(map[string]ChangesStats)
+		for author, months := range globalStats.Stats {
+			if stats, ok := months[month]; ok {
+				authors[author] = stats
+				total := totals[author]
+				total.Insertions += stats.Insertions
+				total.Deletions += stats.Deletions
+				total.Commits += stats.Commits
+				totals[author] = total
+			}
+		}
+		sections = append(sections, section(month, authors, monthLimit))
+	}
+	sections = append(sections, section("Total lines by "+group, totals, top))
+
+	var grids []htmlHeatmap
+	for _, heatmap := range heatmaps {
+		grid := htmlHeatmap{Title: heatmap.Title}
+		largest := heatmap.max()
+		for day, hours := range heatmap.Heatmap {
+			row := htmlHeatmapRow{Day: weekdays[day]}
+			for _, count := range hours {
+				row.Cells = append(row.Cells, htmlHeatmapCell{count, shade(count, largest)})
+			}
+			grid.Rows = append(grid.Rows, row)
+		}
+		grids = append(grids, grid)
+	}
+
+	return htmlTemplate.Execute(w, struct {
+		Sections []htmlSection
+		Heatmaps []htmlHeatmap
+	}{sections, grids})
+}
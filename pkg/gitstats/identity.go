@@ -0,0 +1,141 @@
+package gitstats
+
+import (
+	"strings"
+	"time"
+)
+
+// Identity merges the emails and display names one person commits under into a single
+// canonical author, on top of what each repository's .mailmap already maps.
+type Identity struct {
+	Email   string   // Canonical email the stats are reported under
+	Name    string   // Display name reported for Email; "" keeps the name of the commits
+	Aliases []string // Other emails or display names of the same person, case-insensitive
+}
+
+// identityMap resolves the emails and names of commits to their Identity.
+type identityMap struct {
+	byEmail map[string]*Identity
+	byName  map[string]*Identity
+}
+
+func newIdentityMap(identities []Identity, caseSensitive bool) identityMap {
+	m := identityMap{byEmail: make(map[string]*Identity), byName: make(map[string]*Identity)}
+	for _, identity := range identities {
+		identity := &identity
+		if !caseSensitive {
+			identity.Email = strings.ToLower(identity.Email)
+		}
+		m.byEmail[strings.ToLower(identity.Email)] = identity
+		for _, alias := range identity.Aliases {
+			alias = strings.ToLower(strings.TrimSpace(alias))
+			if strings.Contains(alias, "@") {
+				m.byEmail[alias] = identity
+			} else if alias != "" {
+				m.byName[alias] = identity
+			}
+		}
+	}
+	return m
+}
+
+// resolve returns the Identity of the author committing as email and name, or nil.
+func (m identityMap) resolve(email, name string) *Identity {
+	if identity := m.byEmail[strings.ToLower(email)]; identity != nil {
+		return identity
+	}
+	return m.byName[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// canonical returns the email the stats of the author committing as email and name are
+// reported under.
+func (m identityMap) canonical(email, name string) string {
+	if identity := m.resolve(email, name); identity != nil {
+		return identity.Email
+	}
+	return email
+}
+
+// merge moves the stats of every alias in result to its canonical email.
+func (m identityMap) merge(result LogResult) LogResult {
+	if len(m.byEmail) == 0 {
+		return result
+	}
+	merged := LogResult{
+		Stats:      make(map[string]ChangesStats),
+		LastCommit: make(map[string]time.Time),
+		Names:      make(map[string]string),
+		Squashes:   make(map[string]ChangesStats),
+		Commits:    result.Commits,
+		Binary:     make(map[string]int),
+		Days:       make(map[string]map[string]bool),
+		Hours:      make(map[string]Heatmap),
+		Warnings:   result.Warnings,
+
+		Sizes:       make(map[string][]int),
+		SquashSizes: make(map[string][]int),
+	}
+	for _, outlier := range result.Outliers {
+		outlier.Author = m.canonical(outlier.Author, result.Names[outlier.Author])
+		merged.Outliers = append(merged.Outliers, outlier)
+	}
+	for _, maps := range [][2]map[string]ChangesStats{{merged.Stats, result.Stats}, {merged.Squashes, result.Squashes}} {
+		for author, stats := range maps[1] {
+			addChanges(maps[0], map[string]ChangesStats{m.canonical(author, result.Names[author]): stats})
+		}
+	}
+	for author, last := range result.LastCommit {
+		canonical := m.canonical(author, result.Names[author])
+		if last.After(merged.LastCommit[canonical]) {
+			merged.LastCommit[canonical] = last
+		}
+	}
+	for author, count := range result.Binary {
+		merged.Binary[m.canonical(author, result.Names[author])] += count
+	}
+	for author, days := range result.Days {
+		addDays(merged.Days, m.canonical(author, result.Names[author]), days)
+	}
+	for author, hours := range result.Hours {
+		addHeatmap(merged.Hours, m.canonical(author, result.Names[author]), hours)
+	}
+	for author, files := range result.Files {
+		if merged.Files == nil {
+			merged.Files = make(map[string]map[string]bool)
+		}
+		addDays(merged.Files, m.canonical(author, result.Names[author]), files)
+	}
+	for _, sizes := range [][2]map[string][]int{{merged.Sizes, result.Sizes}, {merged.SquashSizes, result.SquashSizes}} {
+		for author, authorSizes := range sizes[1] {
+			canonical := m.canonical(author, result.Names[author])
+			sizes[0][canonical] = append(sizes[0][canonical], authorSizes...)
+		}
+	}
+	for _, metrics := range [][2]*map[string]map[string]int{{&merged.Metrics, &result.Metrics}, {&merged.SquashMetrics, &result.SquashMetrics}} {
+		for author, values := range *metrics[1] {
+			if *metrics[0] == nil {
+				*metrics[0] = make(map[string]map[string]int)
+			}
+			addMetrics(*metrics[0], m.canonical(author, result.Names[author]), values)
+		}
+	}
+	// Without a configured name, the canonical email's own name wins over the aliases'
+	nameFrom := make(map[string]string)
+	for author, name := range result.Names {
+		identity := m.resolve(author, name)
+		if identity == nil {
+			merged.Names[author] = name
+			continue
+		}
+		if identity.Name != "" {
+			merged.Names[identity.Email] = identity.Name
+			continue
+		}
+		from, ok := nameFrom[identity.Email]
+		if !ok || author == identity.Email || (from != identity.Email && author < from) {
+			nameFrom[identity.Email] = author
+			merged.Names[identity.Email] = name
+		}
+	}
+	return merged
+}
@@ -0,0 +1,192 @@
+package gitstats
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFileName is the per-repository exclusion file, read from the repo root.
+const ignoreFileName = ".gitstatsignore"
+
+// GeneratedPatterns are the gitignore-style patterns of vendored dependencies,
+// generated code, lock files and minified assets, excluded with ExcludeGenerated. A
+// single dependency update touches thousands of their lines. Their lines are left out
+// of the counts, but the commits changing only them are still counted.
+var GeneratedPatterns = []string{
+	"vendor/",
+	"node_modules/",
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_generated.go",
+	"*.min.js",
+	"*.min.css",
+	"*.js.map",
+	"*.css.map",
+	"go.sum",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"composer.lock",
+}
+
+// generatedFiles returns the matcher of GeneratedPatterns with exclude, and nil
+// otherwise.
+func generatedFiles(exclude bool) func(file string) bool {
+	if !exclude {
+		return nil
+	}
+	return matchPatterns(GeneratedPatterns)
+}
+
+// matchPatterns returns a matcher of the files that the exclude pathspecs of patterns
+// leave out: the files matching a pattern, and those below a matching directory.
+func matchPatterns(patterns []string) func(file string) bool {
+	type glob struct {
+		segments []string
+		dirOnly  bool
+	}
+	var globs []glob
+	for _, pattern := range patterns {
+		if pattern == "" || strings.HasPrefix(pattern, "!") {
+			continue
+		}
+		pattern, dirOnly := gitignorePattern(pattern)
+		globs = append(globs, glob{strings.Split(pattern, "/"), dirOnly})
+	}
+	return func(file string) bool {
+		segments := strings.Split(file, "/")
+		for _, g := range globs {
+			last := len(segments)
+			if g.dirOnly {
+				// Only the directories holding the file
+				last--
+			}
+			for end := 1; end <= last; end++ {
+				if matchSegments(g.segments, segments[:end]) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// matchSegments reports whether the path segments of name match those of a glob
+// pathspec, where ** matches any number of segments and * never crosses a slash.
+func matchSegments(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], name[0])
+	return ok && matchSegments(pattern[1:], name[1:])
+}
+
+// readIgnoreFile returns the patterns of dir's .gitstatsignore, skipping blank
+// lines and comments. A missing file yields no patterns.
+func readIgnoreFile(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ignoreFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// gitignorePattern returns pattern as a glob pathspec matching like in a gitignore
+// file: without a slash at any depth, with a leading slash from the repo root. The
+// trailing slash of a directory is removed and reported.
+func gitignorePattern(pattern string) (glob string, dirOnly bool) {
+	dirOnly = strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if strings.HasPrefix(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else if !strings.Contains(pattern, "/") && !strings.HasPrefix(pattern, "**") {
+		pattern = "**/" + pattern
+	}
+	return pattern, dirOnly
+}
+
+// includePathspecs translates gitignore-style patterns into the git pathspecs of the
+// only files analyzed, matching like excludePathspecs. A directory, a pattern with a
+// trailing slash or ending in /**, selects the files of exts below it, or all of them
+// without exts; other patterns select their matches whatever the extension, and the
+// files below a matching directory as a directory does.
+func includePathspecs(patterns, exts []string) []string {
+	var pathspecs []string
+	below := func(dir string) {
+		if len(exts) == 0 {
+			pathspecs = append(pathspecs, ":(glob)"+dir+"/**")
+			return
+		}
+		for _, ext := range exts {
+			pathspecs = append(pathspecs, ":(glob)"+dir+"/**/*."+ext)
+		}
+	}
+	for _, pattern := range patterns {
+		if pattern == "" || strings.HasPrefix(pattern, "!") {
+			continue
+		}
+		pattern, dirOnly := gitignorePattern(pattern)
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			pattern, dirOnly = dir, true
+		}
+		if !dirOnly {
+			pathspecs = append(pathspecs, ":(glob)"+pattern)
+		}
+		below(pattern)
+	}
+	return pathspecs
+}
+
+// excludePathspecs translates gitignore-style patterns into git exclude pathspecs.
+// Patterns without a slash match at any depth, a trailing slash matches a directory
+// and everything below it, and a leading slash anchors the pattern at the repo root.
+// Negated patterns (!pattern) are not supported and are skipped.
+func excludePathspecs(patterns []string) []string {
+	var pathspecs []string
+	for _, pattern := range patterns {
+		if pattern == "" || strings.HasPrefix(pattern, "!") {
+			continue
+		}
+
+		pattern, dirOnly := gitignorePattern(pattern)
+		if dirOnly {
+			pathspecs = append(pathspecs, ":(exclude,glob)"+pattern+"/**")
+			continue
+		}
+		// Like gitignore, a matching directory excludes its whole content
+		pathspecs = append(pathspecs, ":(exclude,glob)"+pattern, ":(exclude,glob)"+pattern+"/**")
+	}
+	return pathspecs
+}
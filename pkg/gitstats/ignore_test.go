@@ -0,0 +1,220 @@
+package gitstats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExcludePathspecs(t *testing.T) {
+	got := excludePathspecs([]string{"vendor/", "/gen/*.go", "*.lock", "docs/api", "!keep.md"})
+	want := []string{
+		":(exclude,glob)**/vendor/**",
+		":(exclude,glob)gen/*.go", ":(exclude,glob)gen/*.go/**",
+		":(exclude,glob)**/*.lock", ":(exclude,glob)**/*.lock/**",
+		":(exclude,glob)docs/api", ":(exclude,glob)docs/api/**",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestGitstatsignoreExcludesPaths(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write(ignoreFileName, "# generated code\ngenerated/\n")
+	repo.write("generated/api.md", strings.Repeat("line\n", 100))
+	repo.write("README.md", "one\ntwo\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "initial")
+
+	patterns, err := readIgnoreFile(repo.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := append([]string{"log", "--pretty=%H%x09%ae%x09%at%x09%an", "--shortstat", "--", "*.md"},
+		excludePathspecs(patterns)...)
+	result := ParseLog(repo.git(args...), make(map[string]bool))
+
+	if got := result.Stats["alice@example.com"].Insertions; got != 2 {
+		t.Errorf("insertions = %d, want 2 (generated/ excluded)", got)
+	}
+}
+
+func TestExcludeGenerated(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "package main\n")
+	repo.write("go.sum", strings.Repeat("line\n", 50))
+	repo.write("api/api.pb.go", strings.Repeat("line\n", 40))
+	repo.write("web/node_modules/lib/index.js", strings.Repeat("line\n", 30))
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "initial")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		excludeGenerated bool
+		want             int
+	}{{false, 121}, {true, 1}} {
+		gb, err := Collect(Options{
+			Path:             repo.Dir,
+			Periods:          []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+			ExcludeGenerated: tc.excludeGenerated,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["alice@example.com"]["march"].Insertions; got != tc.want {
+			t.Errorf("ExcludeGenerated %t: insertions = %d, want %d", tc.excludeGenerated, got, tc.want)
+		}
+	}
+}
+
+func TestIncludePathspecs(t *testing.T) {
+	got := includePathspecs([]string{"src/**", "docs/", "/Makefile", "*.md", "!keep.md"}, nil)
+	want := []string{
+		":(glob)src/**",
+		":(glob)**/docs/**",
+		":(glob)Makefile", ":(glob)Makefile/**",
+		":(glob)**/*.md", ":(glob)**/*.md/**",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got = includePathspecs([]string{"src/**", "Makefile"}, []string{"go", "kt"})
+	want = []string{
+		":(glob)src/**/*.go", ":(glob)src/**/*.kt",
+		":(glob)**/Makefile", ":(glob)**/Makefile/**/*.go", ":(glob)**/Makefile/**/*.kt",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("with extensions: got %v, want %v", got, want)
+	}
+}
+
+func TestCollectIncludes(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("src/api/server.go", "one\ntwo\n")
+	repo.write("src/api/testdata/big.go", strings.Repeat("line\n", 30))
+	repo.write("src/README.md", "three\n")
+	repo.write("docs/guide.md", strings.Repeat("line\n", 10))
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "initial")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name     string
+		includes []string
+		excludes []string
+		exts     []string
+		want     int
+	}{
+		{"include", []string{"src/**"}, nil, nil, 33},
+		{"include and exclude", []string{"src/**"}, []string{"testdata/"}, nil, 3},
+		{"include with extensions", []string{"src/"}, nil, []string{"go"}, 32},
+		{"include naming files", []string{"*.md"}, nil, []string{"go"}, 11},
+	} {
+		gb, err := Collect(Options{
+			Path:       repo.Dir,
+			Periods:    []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+			Includes:   tc.includes,
+			Excludes:   tc.excludes,
+			Extensions: tc.exts,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["alice@example.com"]["march"].Insertions; got != tc.want {
+			t.Errorf("%s: insertions = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPathStatsFileWithExcludes(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("old.go", "one\ntwo\n")
+	repo.write("vendor/lib.go", strings.Repeat("line\n", 20))
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "initial")
+	repo.git("mv", "old.go", "main.go")
+	repo.write("main.go", "one\ntwo\nthree\n")
+	repo.commit("alice@example.com", "2024-03-06T12:00:00Z", "rename")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:      repo.Dir,
+		Periods:   []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		PathStats: "main.go",
+		Excludes:  []string{"vendor/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file is followed across its rename
+	if got := gb.Stats["alice@example.com"]["march"]; got.Insertions != 3 || got.Commits != 2 {
+		t.Errorf("alice = %+v, want main.go followed across its rename", got)
+	}
+}
+
+func TestMatchPatterns(t *testing.T) {
+	match := matchPatterns([]string{"vendor/", "/gen/*.go", "*.lock", "docs/api", "!keep.md"})
+	for file, want := range map[string]bool{
+		"vendor/lib.go":        true,
+		"web/vendor/a/b.js":    true,
+		"vendor":               false,
+		"gen/api.go":           true,
+		"src/gen/api.go":       false,
+		"gen/sub/api.go":       false,
+		"yarn.lock":            true,
+		"web/Cargo.lock/x":     true,
+		"docs/api":             true,
+		"docs/api/index.md":    true,
+		"src/docs/api":         false,
+		"keep.md":              false,
+		"src/main.go":          false,
+		"node_modules/left.js": false,
+	} {
+		if got := match(file); got != want {
+			t.Errorf("match(%q) = %t, want %t", file, got, want)
+		}
+	}
+}
+
+func TestExcludeGeneratedCountsCommits(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "package main\n")
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "initial")
+	repo.commit("alice@example.com", "2024-03-06T12:00:00Z", "empty")
+	repo.write("go.sum", strings.Repeat("line\n", 50))
+	repo.write("vendor/lib/lib.go", strings.Repeat("line\n", 20))
+	repo.commit("alice@example.com", "2024-03-07T12:00:00Z", "update dependencies")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	periods := []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}}
+	for _, numstat := range []bool{false, true} {
+		gb, err := Collect(Options{Path: repo.Dir, Periods: periods, ExcludeGenerated: true, ByLanguage: true, Numstat: numstat})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := gb.Stats["alice@example.com"]["march"]; got != (ChangesStats{Insertions: 1, Commits: 3}) {
+			t.Errorf("numstat %t: alice = %+v, want the empty and generated-only commits counted without their lines", numstat, got)
+		}
+		if langs := gb.Languages["alice@example.com"]; langs["sum"].Insertions != 0 || langs["go"].Insertions != 1 {
+			t.Errorf("numstat %t: languages = %+v, want the lines of main.go only", numstat, langs)
+		}
+	}
+}
+
+func TestPathStatsFileWithGeneratedExcluded(t *testing.T) {
+	repo := newFixtureRepo(t)
+	repo.write("main.go", "one\n")
+	repo.write("go.sum", strings.Repeat("line\n", 50))
+	repo.commit("alice@example.com", "2024-03-05T12:00:00Z", "initial")
+
+	since := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	gb, err := Collect(Options{
+		Path:             repo.Dir,
+		Periods:          []Period{{Label: "march", Since: since, Until: since.AddDate(0, 1, -1)}},
+		PathStats:        "main.go",
+		ExcludeGenerated: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gb.Stats["alice@example.com"]["march"]; got != (ChangesStats{Insertions: 1, Commits: 1}) {
+		t.Errorf("alice = %+v, want the changes of main.go", got)
+	}
+}
@@ -0,0 +1,119 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// languageNames are the languages of common file extensions, as Languages keys them.
+// Other extensions are their own language.
+var languageNames = map[string]string{
+	"go": "Go", "kt": "Kotlin", "kts": "Kotlin", "java": "Java", "scala": "Scala", "groovy": "Groovy", "gradle": "Gradle",
+	"swift": "Swift", "m": "Objective-C", "mm": "Objective-C", "c": "C", "h": "C", "cc": "C++", "cpp": "C++", "cxx": "C++",
+	"hpp": "C++", "cs": "C#", "rs": "Rust", "py": "Python", "rb": "Ruby", "php": "PHP", "pl": "Perl", "lua": "Lua",
+	"js": "JavaScript", "jsx": "JavaScript", "mjs": "JavaScript", "cjs": "JavaScript", "ts": "TypeScript", "tsx": "TypeScript",
+	"vue": "Vue", "svelte": "Svelte", "dart": "Dart", "ex": "Elixir", "exs": "Elixir", "erl": "Erlang", "hs": "Haskell",
+	"clj": "Clojure", "r": "R", "jl": "Julia", "sh": "Shell", "bash": "Shell", "zsh": "Shell", "ps1": "PowerShell",
+	"sql": "SQL", "proto": "Protocol Buffers", "graphql": "GraphQL", "tf": "Terraform", "hcl": "HCL",
+	"html": "HTML", "htm": "HTML", "css": "CSS", "scss": "SCSS", "sass": "SCSS", "less": "Less",
+	"yaml": "YAML", "yml": "YAML", "json": "JSON", "toml": "TOML", "xml": "XML", "ini": "INI", "properties": "Properties",
+	"md": "Markdown", "markdown": "Markdown", "rst": "reStructuredText", "adoc": "AsciiDoc", "txt": "Text",
+	"dockerfile": "Dockerfile", "makefile": "Makefile", "cmake": "CMake", "mod": "Go modules", "sum": "Go modules",
+}
+
+// LanguageName returns the language of a file extension such as kt, or ext itself when
+// it is not a known one.
+func LanguageName(ext string) string {
+	if name, ok := languageNames[strings.ToLower(ext)]; ok {
+		return name
+	}
+	return ext
+}
+
+// LanguageShare is one language of a LanguageProfile: the lines changed in its files,
+// insertions plus deletions, and their percentage of all lines of the profile.
+type LanguageShare struct {
+	Language string  `json:"language"`
+	Lines    int     `json:"lines"`
+	Percent  float64 `json:"percent"`
+}
+
+// LanguageProfile returns the distribution of the changes per extension of one author,
+// a Languages entry, over languages, largest first. Extensions of the same language
+// are summed, e.g. yaml and yml.
+func LanguageProfile(languages map[string]ChangesStats) []LanguageShare {
+	lines := make(map[string]int)
+	total := 0
+	for ext, stats := range languages {
+		changed := stats.Insertions + stats.Deletions
+		lines[LanguageName(ext)] += changed
+		total += changed
+	}
+	if total == 0 {
+		return nil
+	}
+	var profile []LanguageShare
+	for language, changed := range lines {
+		if changed > 0 {
+			profile = append(profile, LanguageShare{Language: language, Lines: changed, Percent: float64(changed) * 100 / float64(total)})
+		}
+	}
+	sort.Slice(profile, func(i, j int) bool {
+		if profile[i].Lines != profile[j].Lines {
+			return profile[i].Lines > profile[j].Lines
+		}
+		return profile[i].Language < profile[j].Language
+	})
+	return profile
+}
+
+// formatProfile formats profile as "60% Kotlin, 30% YAML, 10% Markdown", summing the
+// languages below one percent into other.
+func formatProfile(profile []LanguageShare) string {
+	var parts []string
+	other := 0.0
+	for _, share := range profile {
+		if math.Round(share.Percent) < 1 {
+			other += share.Percent
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%.0f%% %s", math.Round(share.Percent), share.Language))
+	}
+	if other > 0 {
+		parts = append(parts, fmt.Sprintf("%.0f%% other", math.Max(1, math.Round(other))))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printLanguageProfile prints the language profile of each author over the analyzed
+// periods, in the order of the developer table, and of everyone in the total row.
+func printLanguageProfile(w io.Writer, globalStats GlobalStats, order authorOrder, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	fmt.Fprintf(w, "\n%sLanguage profile (share of lines changed):%s\n", blue, reset)
+	authors := make(map[string]ChangesStats)
+	all := make(map[string]ChangesStats)
+	for author, languages := range globalStats.Languages {
+		var total ChangesStats
+		for _, stats := range globalStats.Stats[author] {
+			total = addStats(total, stats)
+		}
+		authors[author] = total
+		addChanges(all, languages)
+	}
+	ranked := rankedAuthors(authors, order, func(stats ChangesStats) int { return stats.Commits })
+	if len(ranked) == 0 {
+		fmt.Fprintf(w, "  No changes\n")
+		return
+	}
+	t := table{header: []string{"Author", "Languages"}, rightAlign: []bool{false, false}}
+	for _, author := range ranked {
+		t.addRow(author, formatProfile(LanguageProfile(globalStats.Languages[author])))
+	}
+	t.footer = []string{"Total", formatProfile(LanguageProfile(all))}
+	t.render(w, style)
+}
@@ -0,0 +1,219 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileChange is the change of one file by a commit. A binary file has no line counts,
+// and a commit read from --shortstat output has a single change without a path.
+type FileChange struct {
+	Path       string
+	Insertions int
+	Deletions  int
+	Binary     bool
+}
+
+// CommitRecord is a commit of the main git log pass as metrics see it, with the raw
+// changes of its files: outliers are not capped and co-authors are not credited.
+type CommitRecord struct {
+	Hash   string
+	Author string // Email, lowercased unless Options.CaseSensitiveEmails
+	Date   time.Time
+	Squash bool // The commit looks like a squash merge
+	Files  []FileChange
+}
+
+// Metric turns the commits of an author into a named value, summed per period like
+// insertions and deletions, so new figures such as the lines of test files need no
+// aggregation or printing code of their own.
+type Metric interface {
+	Name() string
+	// Value returns what commit adds to the metric of its author
+	Value(commit CommitRecord) int
+}
+
+type funcMetric struct {
+	name  string
+	value func(commit CommitRecord) int
+}
+
+func (m funcMetric) Name() string                  { return m.name }
+func (m funcMetric) Value(commit CommitRecord) int { return m.value(commit) }
+
+// NewMetric returns the Metric called name whose values are computed by value.
+func NewMetric(name string, value func(commit CommitRecord) int) Metric {
+	return funcMetric{name: name, value: value}
+}
+
+// FileMetric returns the Metric called name summing value over the changed files of
+// each commit, e.g. the lines of the files matching a pattern.
+func FileMetric(name string, value func(file FileChange) int) Metric {
+	return NewMetric(name, func(commit CommitRecord) int {
+		total := 0
+		for _, file := range commit.Files {
+			total += value(file)
+		}
+		return total
+	})
+}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   = make(map[string]Metric)
+)
+
+// RegisterMetric makes m available by name to LookupMetric, e.g. for the -metric flag.
+// It panics when the name is empty or already registered.
+func RegisterMetric(m Metric) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	name := m.Name()
+	if name == "" {
+		panic("gitstats: metric without a name")
+	}
+	if _, ok := metrics[name]; ok {
+		panic("gitstats: metric " + name + " registered twice")
+	}
+	metrics[name] = m
+}
+
+// LookupMetric returns the registered metric called name.
+func LookupMetric(name string) (Metric, error) {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+	m, ok := metrics[name]
+	if !ok {
+		names := make([]string, 0, len(metrics))
+		for name := range metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown metric %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return m, nil
+}
+
+// The built-in metrics are the figures of the developer table and the files changed
+func init() {
+	RegisterMetric(FileMetric("insertions", func(file FileChange) int { return file.Insertions }))
+	RegisterMetric(FileMetric("deletions", func(file FileChange) int { return file.Deletions }))
+	RegisterMetric(NewMetric("commits", func(commit CommitRecord) int { return 1 }))
+	RegisterMetric(NewMetric("files", func(commit CommitRecord) int {
+		if len(commit.Files) == 1 && commit.Files[0].Path == "" {
+			return 0
+		}
+		return len(commit.Files)
+	}))
+}
+
+// metricNames returns the names of metrics.
+func metricNames(metrics []Metric) []string {
+	names := make([]string, len(metrics))
+	for i, m := range metrics {
+		names[i] = m.Name()
+	}
+	return names
+}
+
+// newCommitRecord returns the record of the commit whose header and stat lines are
+// lines, authored by author.
+func newCommitRecord(lines []string, author string) CommitRecord {
+	fields := strings.Split(lines[0], "\t")
+	record := CommitRecord{Hash: fields[0], Author: author, Date: parseCommitTime(fields[2])}
+	for _, line := range lines[1:] {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) == 3 && isNumstatCount(fields[0]) && isNumstatCount(fields[1]) {
+			ins, _ := strconv.Atoi(fields[0])
+			del, _ := strconv.Atoi(fields[1])
+			record.Files = append(record.Files, FileChange{
+				Path: numstatPath(fields[2]), Insertions: ins, Deletions: del, Binary: fields[0] == "-" || fields[1] == "-",
+			})
+		} else if ins, del, ok := parseShortstat(line); ok {
+			record.Files = append(record.Files, FileChange{Insertions: ins, Deletions: del})
+		}
+	}
+	return record
+}
+
+// addMetrics adds values, per author and metric name, to metrics.
+func addMetrics(metrics map[string]map[string]int, author string, values map[string]int) {
+	if metrics[author] == nil {
+		metrics[author] = make(map[string]int)
+	}
+	for name, value := range values {
+		metrics[author][name] += value
+	}
+}
+
+// addMetrics accumulates the metric values of author in month.
+func (gb *GlobalStats) addMetrics(author, month string, values map[string]int) {
+	if gb.Metrics == nil {
+		gb.Metrics = make(map[string]map[string]map[string]int)
+	}
+	if gb.Metrics[author] == nil {
+		gb.Metrics[author] = make(map[string]map[string]int)
+	}
+	addMetrics(gb.Metrics[author], month, values)
+}
+
+// metricTotals returns the values of each author over all periods, and the names of
+// the metrics.
+func metricTotals(globalStats GlobalStats) (map[string]map[string]int, []string) {
+	totals := make(map[string]map[string]int)
+	seen := make(map[string]bool)
+	for author, months := range globalStats.Metrics {
+		for _, values := range months {
+			addMetrics(totals, author, values)
+			for name := range values {
+				seen[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return totals, names
+}
+
+// printMetrics prints the value of each metric per author over all periods, in the
+// order of the developer table.
+func printMetrics(w io.Writer, globalStats GlobalStats, order authorOrder, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	fmt.Fprintf(w, "\n%sMetrics:%s\n", blue, reset)
+	totals, names := metricTotals(globalStats)
+	if len(totals) == 0 {
+		fmt.Fprintf(w, "  No commits\n")
+		return
+	}
+
+	authors := make(map[string]ChangesStats)
+	for author := range totals {
+		var total ChangesStats
+		for _, stats := range globalStats.Stats[author] {
+			total = addStats(total, stats)
+		}
+		authors[author] = total
+	}
+	t := table{header: append([]string{"Author"}, names...), rightAlign: []bool{false}}
+	for range names {
+		t.rightAlign = append(t.rightAlign, true)
+	}
+	for _, author := range rankedAuthors(authors, order, func(stats ChangesStats) int { return stats.Commits }) {
+		row := []string{author}
+		for _, name := range names {
+			row = append(row, strconv.Itoa(totals[author][name]))
+		}
+		t.addRow(row...)
+	}
+	t.render(w, style)
+}
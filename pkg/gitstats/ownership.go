@@ -0,0 +1,343 @@
+package gitstats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BusFactorShare is the share of the lines the authors counted by BusFactor own.
+const BusFactorShare = 0.8
+
+// Ownership is the lines of the checked-out tree last changed by each author, the
+// stock of code they own as opposed to the flow of changes of GlobalStats.
+type Ownership struct {
+	Authors  map[string]int            // Lines per author
+	Dirs     map[string]map[string]int // Lines per top-level directory ("." for the root files, below the repository name with several), then author
+	Repos    map[string]*Ownership     // Per repository name, with several repositories
+	Warnings []string                  // Repositories that could not be blamed
+}
+
+func newOwnership() *Ownership {
+	return &Ownership{Authors: make(map[string]int), Dirs: make(map[string]map[string]int)}
+}
+
+// add counts lines of author in the top-level directory dir.
+func (o *Ownership) add(dir, author string, lines int) {
+	o.Authors[author] += lines
+	if o.Dirs[dir] == nil {
+		o.Dirs[dir] = make(map[string]int)
+	}
+	o.Dirs[dir][author] += lines
+}
+
+// BusFactor returns the fewest authors of lines owning at least BusFactorShare of
+// them: how many people the code depends on.
+func BusFactor(lines map[string]int) int {
+	total := 0
+	for _, n := range lines {
+		total += n
+	}
+	owned, factor := 0, 0
+	for _, author := range byLines(lines) {
+		if float64(owned) >= BusFactorShare*float64(total) {
+			break
+		}
+		owned += lines[author]
+		factor++
+	}
+	return factor
+}
+
+// byLines returns the authors of lines, most lines first.
+func byLines(lines map[string]int) []string {
+	var authors []string
+	for author := range lines {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if lines[authors[i]] != lines[authors[j]] {
+			return lines[authors[i]] > lines[authors[j]]
+		}
+		return authors[i] < authors[j]
+	})
+	return authors
+}
+
+// ParseBlame parses `git blame --line-porcelain` output and returns the lines per
+// author email.
+func ParseBlame(output string, caseSensitive bool) map[string]int {
+	lines := make(map[string]int)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024*1024)
+	for scanner.Scan() {
+		if email, ok := strings.CutPrefix(scanner.Text(), "author-mail "); ok {
+			email = strings.TrimSuffix(strings.TrimPrefix(email, "<"), ">")
+			if !caseSensitive {
+				email = strings.ToLower(email)
+			}
+			lines[email]++
+		}
+	}
+	return lines
+}
+
+// Ownership blames every text file of the checked-out tree of the repositories of the
+// Collector selected like Collect does, and returns the lines each author owns.
+// Identities, the author filters and the file selection apply; periods and refs don't.
+func (col *Collector) Ownership() (Ownership, error) {
+	c := col.newCollector()
+	opts := c.opts
+	ownership := newOwnership()
+
+	for _, patterns := range [][]string{opts.Authors, opts.ExcludeAuthors} {
+		if err := checkAuthorPatterns(patterns); err != nil {
+			return *ownership, err
+		}
+	}
+
+	dirs := opts.Repos
+	if len(dirs) == 0 {
+		var err error
+		if dirs, err = repoDirs(opts.Path, opts.All, opts.Depth, opts.SkipDirs, c.debug); err != nil {
+			return *ownership, fmt.Errorf("failed to read directory: %s", err)
+		}
+	}
+	if opts.Submodules != "" {
+		// Submodules are always their own repositories here
+		var warnings []string
+		dirs, warnings = c.withSubmodules(dirs)
+		ownership.Warnings = append(ownership.Warnings, warnings...)
+	}
+	several := opts.All || len(dirs) > 1
+
+	for _, dir := range dirs {
+		patterns, err := readIgnoreFile(dir)
+		if err != nil {
+			ownership.Warnings = append(ownership.Warnings, fmt.Sprintf("Failed to read %s: %s", filepath.Join(dir, ignoreFileName), err))
+			continue
+		}
+		c.repoIgnores[dir] = patterns
+	}
+
+	results := make([]*Ownership, len(dirs))
+	errs := make([]error, len(dirs))
+	c.eachRepo(dirs, func(i int, dir string) {
+		results[i], errs[i] = c.blameDir(dir)
+	})
+
+	if several {
+		ownership.Repos = make(map[string]*Ownership)
+	}
+	for i, dir := range dirs {
+		if errs[i] != nil {
+			if !several {
+				return *ownership, fmt.Errorf("%s: %w", dir, errs[i])
+			}
+			if errors.Is(errs[i], ErrNotRepository) {
+				c.log.Printf("Skipping %s: not a git repository", dir)
+				continue
+			}
+			ownership.Warnings = append(ownership.Warnings, errs[i].Error())
+			continue
+		}
+		name := RepoName(opts.Path, dir)
+		for top, lines := range results[i].Dirs {
+			// The directories of several repositories are told apart by the repository
+			if several {
+				top = path.Join(name, top)
+			}
+			for author, n := range lines {
+				ownership.add(top, author, n)
+			}
+		}
+		if several {
+			ownership.Repos[name] = results[i]
+		}
+	}
+	return *ownership, nil
+}
+
+// blameDir returns the ownership of the text files of dir selected like the log passes
+// select them.
+func (c *collector) blameDir(dir string) (*Ownership, error) {
+	pathspec := c.pathspec(dir)
+	if len(pathspec) > 0 && pathspec[0] == "--follow" {
+		pathspec = pathspec[1:]
+	}
+	// --eol tells text files ("i/lf", "i/crlf") from binary ("i/-text") and empty ones
+	output, err := c.git(append(append(c.gitArgs(dir), "ls-files", "-z", "--eol"), pathspec...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	ownership := newOwnership()
+	for _, entry := range strings.Split(string(output), "\x00") {
+		info, file, ok := strings.Cut(entry, "\t")
+		if !ok || strings.HasPrefix(info, "i/-text") || strings.HasPrefix(info, "i/none") {
+			continue
+		}
+		args := append(c.gitArgs(dir), "blame", "--line-porcelain")
+		if c.opts.IgnoreWhitespace {
+			args = append(args, "-w")
+		}
+		blame, err := c.git(append(args, "HEAD", "--", file)...)
+		if err != nil {
+			// Files added but not committed yet have no history to blame
+			c.log.Printf("Skipping %s in %s: %s", file, dir, err)
+			continue
+		}
+
+		top := "."
+		if i := strings.Index(file, "/"); i >= 0 {
+			top = file[:i]
+		}
+		lines := c.identities.merge(LogResult{Stats: blameStats(ParseBlame(string(blame), c.opts.CaseSensitiveEmails))})
+		for author, stats := range filterAuthors(lines, c.countsAuthor).Stats {
+			ownership.add(top, author, stats.Insertions)
+		}
+	}
+	return ownership, nil
+}
+
+// blameStats returns lines as the insertions of a LogResult's Stats, so that identities
+// merge and authors are filtered like in the log passes.
+func blameStats(lines map[string]int) map[string]ChangesStats {
+	stats := make(map[string]ChangesStats)
+	for author, n := range lines {
+		stats[author] = ChangesStats{Insertions: n}
+	}
+	return stats
+}
+
+// RenderOwnership writes ownership in the text (default), markdown or json format of
+// opts; Border and Color apply as in Render.
+func RenderOwnership(w io.Writer, ownership Ownership, opts RenderOptions) error {
+	if !opts.Color {
+		w = plainWriter{w}
+	}
+	border := opts.Border
+	switch opts.Format {
+	case "json":
+		return printOwnershipJSON(w, ownership)
+	case "markdown":
+		border = "markdown"
+		w = plainWriter{w}
+	case "", "text":
+		if border == "" {
+			border = "none"
+		}
+	default:
+		return fmt.Errorf("unknown format: %s", opts.Format)
+	}
+	style, ok := borderStyles[border]
+	if !ok {
+		return fmt.Errorf("unknown border style: %s", opts.Border)
+	}
+
+	blue := "\033[94m"
+	reset := "\033[0m"
+	percent := func(n, total int) string {
+		if total == 0 {
+			return "0.0%"
+		}
+		return strconv.FormatFloat(float64(n)*100/float64(total), 'f', 1, 64) + "%"
+	}
+	sum := func(lines map[string]int) int {
+		total := 0
+		for _, n := range lines {
+			total += n
+		}
+		return total
+	}
+	// owners prints a table of lines with the bus factor of each key of groups
+	owners := func(title, column string, groups map[string]map[string]int) {
+		totals := make(map[string]int)
+		for key, lines := range groups {
+			totals[key] = sum(lines)
+		}
+		keys := byLines(totals)
+		fmt.Fprintf(w, "\n%s%s%s\n", blue, title, reset)
+		t := table{header: []string{column, "Lines", "Bus factor", "Top owner", "Share"}, rightAlign: []bool{false, true, true, false, true}}
+		for _, key := range keys {
+			top := byLines(groups[key])
+			if len(top) == 0 {
+				continue
+			}
+			t.addRow(key, strconv.Itoa(totals[key]), strconv.Itoa(BusFactor(groups[key])), top[0],
+				percent(groups[key][top[0]], totals[key]))
+		}
+		t.render(w, style)
+	}
+
+	total := sum(ownership.Authors)
+	fmt.Fprintf(w, "%sCode ownership (lines last changed by each author):%s\n", blue, reset)
+	t := table{header: []string{"Author", "Lines", "Share"}, rightAlign: []bool{false, true, true}}
+	for _, author := range byLines(ownership.Authors) {
+		t.addRow(author, strconv.Itoa(ownership.Authors[author]), percent(ownership.Authors[author], total))
+	}
+	t.footer = []string{"Total", strconv.Itoa(total), percent(total, total)}
+	t.render(w, style)
+	fmt.Fprintf(w, "Bus factor: %d (authors owning %.0f%% of the lines)\n", BusFactor(ownership.Authors), BusFactorShare*100)
+
+	if ownership.Repos != nil {
+		repos := make(map[string]map[string]int)
+		for name, repo := range ownership.Repos {
+			repos[name] = repo.Authors
+		}
+		owners("Ownership by repository:", "Repository", repos)
+	}
+	owners("Ownership by directory:", "Directory", ownership.Dirs)
+	return nil
+}
+
+// jsonOwnership is the -format=json document of the ownership subcommand.
+type jsonOwnership struct {
+	Authors      map[string]int           `json:"authors"`
+	TotalLines   int                      `json:"totalLines"`
+	BusFactor    int                      `json:"busFactor"`
+	Directories  map[string]jsonOwnership `json:"directories,omitempty"`
+	Repositories map[string]jsonOwnership `json:"repositories,omitempty"`
+}
+
+func newJSONOwnership(lines map[string]int) jsonOwnership {
+	report := jsonOwnership{Authors: lines, BusFactor: BusFactor(lines)}
+	for _, n := range lines {
+		report.TotalLines += n
+	}
+	return report
+}
+
+func printOwnershipJSON(w io.Writer, ownership Ownership) error {
+	var report func(o *Ownership) jsonOwnership
+	report = func(o *Ownership) jsonOwnership {
+		r := newJSONOwnership(o.Authors)
+		for dir, lines := range o.Dirs {
+			if r.Directories == nil {
+				r.Directories = make(map[string]jsonOwnership)
+			}
+			r.Directories[dir] = newJSONOwnership(lines)
+		}
+		for name, repo := range o.Repos {
+			if r.Repositories == nil {
+				r.Repositories = make(map[string]jsonOwnership)
+			}
+			r.Repositories[name] = report(repo)
+		}
+		return r
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report(&ownership)); err != nil {
+		return fmt.Errorf("failed to encode JSON: %s", err)
+	}
+	return nil
+}
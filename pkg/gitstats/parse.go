@@ -0,0 +1,708 @@
+package gitstats
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	insertionRegex = regexp.MustCompile(`(\d+) insertions?\(\+\)`)
+	deletionRegex  = regexp.MustCompile(`(\d+) deletions?\(-\)`)
+)
+
+// PullRequest is a GitHub pull request recognized from its merge commit message.
+type PullRequest struct {
+	Repo       string `json:"repo,omitempty"`
+	Number     int    `json:"number"`
+	Author     string `json:"author"` // Head branch owner for merge commits, commit author for squash merges
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+}
+
+// OutlierCommit is a commit changing more lines than Options.MaxCommitLines, such as a
+// mass rename or a drop of generated code.
+type OutlierCommit struct {
+	Repo       string    `json:"repo,omitempty"`
+	Hash       string    `json:"hash"`
+	Author     string    `json:"author"`
+	Date       time.Time `json:"date"`
+	Insertions int       `json:"insertions"` // Before capping
+	Deletions  int       `json:"deletions"`
+	Capped     bool      `json:"capped,omitempty"` // Counted with its lines scaled down to the limit instead of left out
+}
+
+var (
+	mergePullRequestRegex  = regexp.MustCompile(`^Merge pull request #(\d+) from ([^/\s]+)`)
+	squashPullRequestRegex = regexp.MustCompile(`\(#(\d+)\)$`)
+)
+
+// ParsePullRequests parses `git log --pretty=%H%x09%aE%x09%s --diff-merges=first-parent --shortstat`
+// output and returns the commits whose subject is a GitHub merge ("Merge pull request #1 from
+// user/branch") or squash merge ("Title (#1)") message. The size of a merge is its diff
+// against the first parent, i.e. everything the pull request brought in.
+func ParsePullRequests(output, repo string) []PullRequest {
+	var prs []PullRequest
+	var current *PullRequest
+
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			// Shortstat line of the previous commit
+			if current != nil {
+				if insertions := insertionRegex.FindStringSubmatch(line); len(insertions) > 0 {
+					fmt.Sscanf(insertions[1], "%d", &current.Insertions)
+				}
+				if deletions := deletionRegex.FindStringSubmatch(line); len(deletions) > 0 {
+					fmt.Sscanf(deletions[1], "%d", &current.Deletions)
+				}
+			}
+			continue
+		}
+
+		current = nil
+		pr := PullRequest{Repo: repo}
+		if match := mergePullRequestRegex.FindStringSubmatch(fields[2]); match != nil {
+			fmt.Sscanf(match[1], "%d", &pr.Number)
+			pr.Author = match[2]
+		} else if match := squashPullRequestRegex.FindStringSubmatch(fields[2]); match != nil {
+			fmt.Sscanf(match[1], "%d", &pr.Number)
+			pr.Author = fields[1]
+		} else {
+			continue
+		}
+		prs = append(prs, pr)
+		current = &prs[len(prs)-1]
+	}
+	return prs
+}
+
+// LogResult holds the stats parsed from a single git log pass.
+type LogResult struct {
+	Stats      map[string]ChangesStats
+	LastCommit map[string]time.Time
+	Names      map[string]string
+	Squashes   map[string]ChangesStats    // Stats of commits that look like squash merges
+	Commits    int                        // Commits parsed, excluding duplicates
+	Binary     map[string]int             // Binary files changed per author, from --numstat output
+	Days       map[string]map[string]bool // Dates (YYYY-MM-DD) each author committed on
+	Hours      map[string]Heatmap         // Commits per author by weekday and hour
+	Files      map[string]map[string]bool `json:",omitempty"` // Paths each author changed, with breadth
+	Outliers   []OutlierCommit            `json:",omitempty"` // Commits above the line limit
+	Warnings   []string                   // Lines that could not be parsed
+
+	// Sizes holds the lines changed by each commit of Stats per author, SquashSizes
+	// those of the commits of Squashes.
+	Sizes       map[string][]int
+	SquashSizes map[string][]int
+
+	// Metrics holds the values of the metrics per author and metric name for the commits
+	// of Stats, SquashMetrics those for the commits of Squashes.
+	Metrics       map[string]map[string]int `json:",omitempty"`
+	SquashMetrics map[string]map[string]int `json:",omitempty"`
+
+	// Extensions splits the result by the extension of the changed files, from --numstat
+	// output. A commit counts once for every extension it touches. Components does the
+	// same by directory.
+	Extensions map[string]*LogResult `json:",omitempty"`
+	Components map[string]*LogResult `json:",omitempty"`
+}
+
+// isNumstatCount reports whether field is a --numstat line count: digits, or "-" for
+// a binary file.
+func isNumstatCount(field string) bool {
+	if field == "-" {
+		return true
+	}
+	_, err := strconv.Atoi(field)
+	return err == nil
+}
+
+// platformCommitters are committer emails used by hosting platforms when they
+// create a commit on the author's behalf, e.g. squash merges from the web UI.
+var platformCommitters = map[string]bool{
+	"noreply@github.com": true,
+	"noreply@gitlab.com": true,
+}
+
+// isSquashMerge guesses whether a commit was squash-merged by a platform: the
+// committer is a known platform account or differs from the author. Rebased and
+// cherry-picked commits also differ in committer, so they are flagged too.
+func isSquashMerge(authorEmail, committerName, committerEmail string) bool {
+	if platformCommitters[strings.ToLower(committerEmail)] || committerName == "GitHub" {
+		return true
+	}
+	return committerEmail != "" && !strings.EqualFold(committerEmail, authorEmail)
+}
+
+// ParseLog parses git log output with headers of --pretty=format: and
+// %H%x09%aE%x09%at%x09%aN%x09%cN%x09%cE, %aI may replace %at to keep the author's
+// offset. That is --numstat -z output, where each commit is its header, a newline and
+// its NUL-terminated numstat entries followed by a NUL, or failing that the
+// newline-separated --numstat or --shortstat lines remote Gits serve; binary files are
+// counted in Binary. Commits whose hash is already in seen are skipped, and newly
+// parsed hashes are added; with a nil seen only the repeats within output are skipped.
+// Commits that look like squash merges are collected in Squashes instead of Stats, and
+// author emails are trimmed and lowercased.
+func ParseLog(output string, seen map[string]bool) LogResult {
+	if seen == nil {
+		seen = make(map[string]bool)
+	}
+	// Reading a string never fails
+	results, _ := parseLogBuckets(strings.NewReader(output), seen, nil, parseOptions{})
+	return results[""]
+}
+
+// parseOptions are the settings of parseLogBuckets, taken from the Options of a
+// collection. The zero value parses like ParseLog.
+type parseOptions struct {
+	maxCommits    int                      // Commits counted per bucket, the newest (0 = all)
+	maxLines      int                      // Lines changed above which a commit is an outlier (0 = no limit)
+	capLines      bool                     // Count outliers scaled down to maxLines rather than leave them out
+	caseSensitive bool                     // Keep the case of emails
+	byExtension   bool                     // Split --numstat lines into Extensions
+	breadth       bool                     // Collect the paths of --numstat lines into Files
+	component     func(path string) string // Components key of a path, or nil
+	location      *time.Location           // Location of Days and Hours, nil for the offset of each commit
+	coAuthors     string                   // split or duplicate to credit co-authors, "" to ignore them
+	metrics       []Metric                 // Metrics measured for each counted commit
+	exclude       func(path string) bool   // Files whose --numstat lines are skipped, or nil
+}
+
+// newParseOptions returns the parseOptions of opts, with component for Components.
+func newParseOptions(opts Options, component func(path string) string) parseOptions {
+	return parseOptions{
+		maxCommits:    opts.MaxCommits,
+		maxLines:      opts.MaxCommitLines,
+		capLines:      opts.CapCommitLines,
+		caseSensitive: opts.CaseSensitiveEmails,
+		byExtension:   opts.ByLanguage && opts.Numstat,
+		breadth:       opts.Breadth,
+		component:     component,
+		location:      opts.Location,
+		coAuthors:     opts.CoAuthors,
+		metrics:       opts.Metrics,
+		exclude:       generatedFiles(opts.ExcludeGenerated),
+	}
+}
+
+// bucketFunc returns the label of the period a commit belongs to given its author and
+// committer dates, and false for commits outside every period.
+type bucketFunc func(authored, committed time.Time) (string, bool)
+
+func newLogResult() *LogResult {
+	return &LogResult{
+		Stats:      make(map[string]ChangesStats),
+		LastCommit: make(map[string]time.Time),
+		Names:      make(map[string]string),
+		Squashes:   make(map[string]ChangesStats),
+		Binary:     make(map[string]int),
+		Days:       make(map[string]map[string]bool),
+		Hours:      make(map[string]Heatmap),
+
+		Sizes:       make(map[string][]int),
+		SquashSizes: make(map[string][]int),
+	}
+}
+
+// numstatZReader translates `git log -z --numstat --pretty=format:...` output into the
+// line format parseLogBuckets reads. Each commit is its header, a newline and its
+// NUL-terminated numstat entries, followed by a NUL; a commit without changes is its
+// header alone. Renamed files are an entry with an empty path followed by the old and
+// new paths, and read as changes to the new path. Paths are unquoted, so a newline or
+// tab in one is read as a space.
+type numstatZReader struct {
+	r       *bufio.Reader
+	header  bool // The next field starts a commit
+	pending []byte
+	err     error
+}
+
+func (z *numstatZReader) Read(p []byte) (int, error) {
+	for len(z.pending) == 0 {
+		if z.err != nil {
+			return 0, z.err
+		}
+		field, err := z.field()
+		z.err = err
+		switch {
+		case field == "" && err == nil:
+			// The end of a commit
+			z.header = true
+		case z.header:
+			header, entry, ok := strings.Cut(field, "\n")
+			z.pending = append(z.pending, header+"\n"...)
+			if ok {
+				z.header = false
+				z.entry(entry)
+			}
+		case field != "":
+			z.entry(field)
+		}
+	}
+	n := copy(p, z.pending)
+	z.pending = z.pending[n:]
+	return n, nil
+}
+
+// field returns the next NUL-terminated field.
+func (z *numstatZReader) field() (string, error) {
+	field, err := z.r.ReadString(0)
+	return strings.TrimSuffix(field, "\x00"), err
+}
+
+// entry adds the numstat line of the entry starting with field.
+func (z *numstatZReader) entry(field string) {
+	counts := strings.SplitN(field, "\t", 3)
+	if len(counts) != 3 {
+		return
+	}
+	file := counts[2]
+	if file == "" {
+		// A rename: the old path, then the new one
+		if _, err := z.field(); err != nil {
+			z.err = err
+			return
+		}
+		var err error
+		if file, err = z.field(); err != nil && file == "" {
+			z.err = err
+			return
+		}
+	}
+	file = strings.NewReplacer("\n", " ", "\t", " ").Replace(file)
+	z.pending = append(z.pending, counts[0]+"\t"+counts[1]+"\t"+file+"\n"...)
+}
+
+// parseShortstat returns the insertions and deletions of a --shortstat summary line such
+// as " 2 files changed, 5 insertions(+), 1 deletion(-)", and whether line is one. Either
+// count is left out by git when it is zero.
+func parseShortstat(line string) (insertions, deletions int, ok bool) {
+	if !strings.Contains(line, "files changed") && !strings.Contains(line, "file changed") {
+		return 0, 0, false
+	}
+	if match := insertionRegex.FindStringSubmatch(line); match != nil {
+		insertions, _ = strconv.Atoi(match[1])
+	}
+	if match := deletionRegex.FindStringSubmatch(line); match != nil {
+		deletions, _ = strconv.Atoi(match[1])
+	}
+	return insertions, deletions, true
+}
+
+// numstatPath returns the path of a --numstat line after a rename, such as new.md for
+// "docs/{old => new}.md" or "b.go => c.go".
+func numstatPath(field string) string {
+	if open := strings.Index(field, "{"); open >= 0 {
+		if end := strings.Index(field[open:], "}"); end >= 0 {
+			if _, renamed, ok := strings.Cut(field[open+1:open+end], " => "); ok {
+				return path.Clean(field[:open] + renamed + field[open+end+1:])
+			}
+		}
+	}
+	if _, renamed, ok := strings.Cut(field, " => "); ok {
+		return renamed
+	}
+	return field
+}
+
+// fileExtension returns the lowercase extension of file without the dot, or its name
+// when it has none, e.g. makefile.
+func fileExtension(file string) string {
+	base := path.Base(file)
+	if ext := path.Ext(base); ext != "" && ext != base {
+		return strings.ToLower(ext[1:])
+	}
+	return strings.ToLower(base)
+}
+
+// parseCoAuthor returns the name and email of a Co-authored-by trailer value such as
+// "Alice <alice@example.com>", and false for a value without an email.
+func parseCoAuthor(value string) (name, email string, ok bool) {
+	open := strings.LastIndex(value, "<")
+	if open < 0 || !strings.HasSuffix(strings.TrimSpace(value), ">") {
+		return "", "", false
+	}
+	email = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value[open+1:]), ">"))
+	if !strings.Contains(email, "@") {
+		return "", "", false
+	}
+	return strings.TrimSpace(value[:open]), email, true
+}
+
+// parseCommitTime parses a %at unix time, or a %aI strict ISO date keeping the offset
+// of its author. Malformed dates are the zero time.
+func parseCommitTime(value string) time.Time {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0)
+	}
+	t, _ := time.Parse(time.RFC3339, value)
+	return t
+}
+
+// excludedNumstat reports whether line is the --numstat line of a file exclude matches.
+func excludedNumstat(line string, exclude func(path string) bool) bool {
+	fields := strings.SplitN(line, "\t", 3)
+	return len(fields) == 3 && isNumstatCount(fields[0]) && isNumstatCount(fields[1]) && exclude(numstatPath(fields[2]))
+}
+
+// commitLines returns the insertions and deletions of the stat lines starting lines, up
+// to the next commit line.
+func commitLines(lines []string) (insertions, deletions int) {
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) >= 4 {
+			break
+		}
+		if len(fields) == 3 && isNumstatCount(fields[0]) && isNumstatCount(fields[1]) {
+			ins, _ := strconv.Atoi(fields[0])
+			del, _ := strconv.Atoi(fields[1])
+			insertions, deletions = insertions+ins, deletions+del
+		} else if ins, del, ok := parseShortstat(line); ok {
+			insertions, deletions = insertions+ins, deletions+del
+		}
+	}
+	return insertions, deletions
+}
+
+// parseLogBuckets parses git log output like ParseLog as it is read, one commit at a
+// time, so the memory used doesn't grow with the history. Output holding a NUL byte is
+// taken as --numstat -z and turned back into lines by numstatZReader; anything else is
+// read line by line as --numstat or --shortstat output. Each commit goes to the result
+// of the label bucket returns for its author date and the committer date of an optional
+// seventh %ct or %cI field, or to a single "" result when bucket is nil. Commits outside
+// every period and those beyond the first opts.maxCommits of a bucket are skipped. The
+// rest of opts decides how commits are counted: the case of emails, the outliers over
+// maxLines, the location of Days and Hours, the splits into Extensions, Components,
+// Files and Metrics, and the co-authors of an optional eighth field,
+// %(trailers:key=Co-authored-by,valueonly,separator=%x1f).
+func parseLogBuckets(output io.Reader, seen map[string]bool, bucket bucketFunc, opts parseOptions) (map[string]LogResult, error) {
+	results := map[string]*LogResult{"": newLogResult()}
+	result := results[""]
+
+	author := ""
+	skip := false
+	stats := result.Stats
+	squash := false
+	var commitTime time.Time
+	capped := 0                      // Lines of the current commit when it is capped to maxLines
+	sizes := result.Sizes            // Sizes or SquashSizes of the current commit
+	var credited []string            // The author and co-authors of the current commit
+	touched := make(map[string]bool) // Extensions of the current commit
+	touchedComponents := make(map[string]bool)
+
+	// credit adds the changes of the current commit to its credited authors, split
+	// among them with coAuthors=split; the author gets the remainder. With sized, they
+	// also grow the size of the commit
+	credit := func(stats map[string]ChangesStats, ins, del int, sized bool) {
+		if capped > 0 {
+			ins = int(int64(ins) * int64(opts.maxLines) / int64(capped))
+			del = int(int64(del) * int64(opts.maxLines) / int64(capped))
+		}
+		n := len(credited)
+		for i, a := range credited {
+			changes := stats[a]
+			shareIns, shareDel := ins, del
+			if opts.coAuthors == "split" {
+				shareIns, shareDel = ins/n, del/n
+				if i == 0 {
+					shareIns += ins % n
+					shareDel += del % n
+				}
+			}
+			changes.Insertions += shareIns
+			changes.Deletions += shareDel
+			stats[a] = changes
+			if sized && len(sizes[a]) > 0 {
+				sizes[a][len(sizes[a])-1] += shareIns + shareDel
+			}
+		}
+	}
+
+	// split adds the changes of a --numstat line to the result of key in splits, where the
+	// commit counts once per key
+	split := func(splits *map[string]*LogResult, touched map[string]bool, key string, ins, del int, binary bool) {
+		if *splits == nil {
+			*splits = make(map[string]*LogResult)
+		}
+		splitResult := (*splits)[key]
+		if splitResult == nil {
+			splitResult = newLogResult()
+			(*splits)[key] = splitResult
+		}
+		splitStats := splitResult.Stats
+		if squash {
+			splitStats = splitResult.Squashes
+		}
+		if !touched[key] {
+			touched[key] = true
+			splitResult.Commits++
+			for _, a := range credited {
+				changes := splitStats[a]
+				changes.Commits++
+				splitStats[a] = changes
+				if commitTime.After(splitResult.LastCommit[a]) {
+					splitResult.LastCommit[a] = commitTime
+				}
+				splitResult.Names[a] = result.Names[a]
+			}
+		}
+		if binary {
+			splitResult.Binary[author]++
+		}
+		credit(splitStats, ins, del, false)
+	}
+
+	// parse reads the lines of one commit, its header and stat lines; state such as the
+	// current author carries over to the next
+	parse := func(lines []string) {
+		for i, line := range lines {
+			if line == "" {
+				continue
+			}
+
+			// Commit lines are recognized by their tab-separated fields first, so an author
+			// email or name containing "file changed" is never mistaken for a stat line:
+			// "hash<TAB>email<TAB>unix time<TAB>name[<TAB>committer name<TAB>committer email[<TAB>unix time]]"
+			fields := strings.Split(line, "\t")
+			if len(fields) >= 4 {
+				hash := fields[0]
+				skip = seen[hash]
+				if skip {
+					continue
+				}
+
+				commitTime = parseCommitTime(fields[2])
+				capped = 0
+				clear(touched)
+				clear(touchedComponents)
+
+				if bucket != nil {
+					var committed time.Time
+					if len(fields) >= 7 {
+						committed = parseCommitTime(fields[6])
+					}
+					label, ok := bucket(commitTime, committed)
+					if !ok {
+						skip = true
+						continue
+					}
+					if results[label] == nil {
+						results[label] = newLogResult()
+					}
+					result = results[label]
+				}
+				if opts.maxCommits > 0 && result.Commits >= opts.maxCommits {
+					// git lists the newest commits first, so the newest of the bucket are kept
+					skip = true
+					continue
+				}
+
+				// Misconfigured repos can carry emails with stray spaces; key on the trimmed email
+				author = strings.TrimSpace(fields[1])
+				committerEmail := ""
+				if len(fields) >= 6 {
+					committerEmail = strings.TrimSpace(fields[5])
+				}
+				if !opts.caseSensitive {
+					author, committerEmail = strings.ToLower(author), strings.ToLower(committerEmail)
+				}
+				if author == "" || !strings.Contains(author, "@") || strings.ContainsAny(author, " \t") {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("suspicious author email %q in commit %s", fields[1], hash))
+				}
+
+				seen[hash] = true
+				if opts.maxLines > 0 {
+					if ins, del := commitLines(lines[i+1:]); ins+del > opts.maxLines {
+						result.Outliers = append(result.Outliers, OutlierCommit{
+							Hash: hash, Author: author, Date: commitTime, Insertions: ins, Deletions: del, Capped: opts.capLines,
+						})
+						if !opts.capLines {
+							skip = true
+							continue
+						}
+						capped = ins + del
+					}
+				}
+				result.Commits++
+
+				stats, sizes = result.Stats, result.Sizes
+				squash = len(fields) >= 6 && isSquashMerge(author, fields[4], committerEmail)
+				if squash {
+					stats, sizes = result.Squashes, result.SquashSizes
+				}
+				result.Names[author] = fields[3]
+				credited = append(credited[:0], author)
+				if opts.coAuthors != "" && len(fields) >= 8 {
+					for _, trailer := range strings.Split(fields[7], "\x1f") {
+						coName, coEmail, ok := parseCoAuthor(trailer)
+						if !ok {
+							continue
+						}
+						if !opts.caseSensitive {
+							coEmail = strings.ToLower(coEmail)
+						}
+						if slices.Contains(credited, coEmail) {
+							continue
+						}
+						credited = append(credited, coEmail)
+						if result.Names[coEmail] == "" {
+							result.Names[coEmail] = coName
+						}
+					}
+				}
+
+				day := commitTime
+				if opts.location != nil {
+					day = day.In(opts.location)
+				}
+				for _, a := range credited {
+					userStats := stats[a]
+					userStats.Commits++
+					stats[a] = userStats
+					sizes[a] = append(sizes[a], 0)
+					if commitTime.After(result.LastCommit[a]) {
+						result.LastCommit[a] = commitTime
+					}
+					if result.Days[a] == nil {
+						result.Days[a] = make(map[string]bool)
+					}
+					result.Days[a][day.Format("2006-01-02")] = true
+					hours := result.Hours[a]
+					hours.add(day)
+					result.Hours[a] = hours
+				}
+			} else if len(fields) == 3 && isNumstatCount(fields[0]) && isNumstatCount(fields[1]) {
+				// --numstat: "insertions<TAB>deletions<TAB>path", with "-" counts for binary files
+				if skip {
+					continue
+				}
+				ins, _ := strconv.Atoi(fields[0])
+				del, _ := strconv.Atoi(fields[1])
+				binary := fields[0] == "-" || fields[1] == "-"
+				if opts.byExtension {
+					split(&result.Extensions, touched, fileExtension(numstatPath(fields[2])), ins, del, binary)
+				}
+				if opts.component != nil {
+					split(&result.Components, touchedComponents, opts.component(numstatPath(fields[2])), ins, del, binary)
+				}
+				if opts.breadth {
+					if result.Files == nil {
+						result.Files = make(map[string]map[string]bool)
+					}
+					for _, a := range credited {
+						if result.Files[a] == nil {
+							result.Files[a] = make(map[string]bool)
+						}
+						result.Files[a][numstatPath(fields[2])] = true
+					}
+				}
+				if binary {
+					result.Binary[author]++
+					continue
+				}
+
+				credit(stats, ins, del, true)
+
+			} else if ins, del, ok := parseShortstat(line); ok {
+				if skip {
+					continue
+				}
+
+				credit(stats, ins, del, true)
+
+			} else {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("unexpected line: %q", line))
+				author = strings.TrimSpace(line)
+				credited = append(credited[:0], author)
+				skip = false
+				stats = result.Stats
+			}
+		}
+
+	}
+
+	// measure adds the values of metrics for the commit of lines to its credited authors,
+	// split like its lines with -co-authors split, once parse counted it
+	measure := func(lines []string) {
+		if len(opts.metrics) == 0 || skip || strings.Count(lines[0], "\t") < 3 {
+			return
+		}
+		record := newCommitRecord(lines, author)
+		record.Squash = squash
+		values := make(map[string]int, len(opts.metrics))
+		for _, m := range opts.metrics {
+			values[m.Name()] += m.Value(record)
+		}
+		target := &result.Metrics
+		if squash {
+			target = &result.SquashMetrics
+		}
+		if *target == nil {
+			*target = make(map[string]map[string]int)
+		}
+		n := len(credited)
+		for i, a := range credited {
+			shares := values
+			if opts.coAuthors == "split" && n > 1 {
+				shares = make(map[string]int, len(values))
+				for name, value := range values {
+					shares[name] = value / n
+					if i == 0 {
+						shares[name] += value % n
+					}
+				}
+			}
+			addMetrics(*target, a, shares)
+		}
+	}
+
+	// git log is read one commit at a time, so the memory used doesn't grow with the
+	// history
+	reader := bufio.NewReader(output)
+	if peek, _ := reader.Peek(reader.Size()); bytes.IndexByte(peek, 0) >= 0 {
+		reader = bufio.NewReader(&numstatZReader{r: reader, header: true})
+	}
+	var commit []string
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			if strings.Count(line, "\t") >= 3 && len(commit) > 0 {
+				parse(commit)
+				measure(commit)
+				commit = commit[:0]
+			} else if opts.exclude != nil && excludedNumstat(line, opts.exclude) {
+				// The commit is still counted, without the lines of the file
+				continue
+			}
+			commit = append(commit, line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(commit) > 0 {
+		parse(commit)
+		measure(commit)
+	}
+
+	final := make(map[string]LogResult)
+	for label, result := range results {
+		final[label] = *result
+	}
+	return final, nil
+}
@@ -0,0 +1,238 @@
+package gitstats
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period is a date range analyzed as one bucket of the report.
+type Period struct {
+	Label string
+	Since time.Time // First day; zero for no lower bound
+	Until time.Time // Last day, inclusive
+}
+
+// Args returns the git log date range arguments of the period in git's local time. git
+// fills in the current time of day for a bare date, so the bounds carry the start and
+// end of their day.
+func (p Period) Args() []string {
+	return p.ArgsIn(nil)
+}
+
+// ArgsIn returns the git log date range arguments of the period with its days in loc,
+// as ISO timestamps with the offset of loc on each bound, or like Args when loc is nil.
+func (p Period) ArgsIn(loc *time.Location) []string {
+	var args []string
+	if !p.Since.IsZero() {
+		args = append(args, "--since="+dateArg(p.Since, "00:00:00", loc))
+	}
+	return append(args, "--until="+dateArg(p.Until, "23:59:59", loc))
+}
+
+// dateArg returns the git date of day at clock (15:04:05) in loc with its offset, or
+// without an offset for git's local time when loc is nil.
+func dateArg(day time.Time, clock string, loc *time.Location) string {
+	local := day.Format("2006-01-02") + " " + clock
+	if loc == nil {
+		return local
+	}
+	t, _ := time.ParseInLocation("2006-01-02 15:04:05", local, loc)
+	return t.Format(time.RFC3339)
+}
+
+// MonthPeriods returns the current and the previous n-1 calendar months, newest first.
+func MonthPeriods(n int, now time.Time) []Period {
+	return MonthPeriodsFrom(n, 1, now)
+}
+
+// MonthPeriodsFrom returns the current and the previous n-1 months starting on
+// startDay (1 to 28) of a calendar month, newest first, e.g. reporting periods from
+// the 26th to the 25th. A month is labeled with the calendar month most of its days
+// fall in: the one it ends in when it starts after the 15th.
+func MonthPeriodsFrom(n, startDay int, now time.Time) []Period {
+	year, month, day := now.Date()
+	if day < startDay {
+		month--
+	}
+	var periods []Period
+	for i := 0; i < n; i++ {
+		since := time.Date(year, month-time.Month(i), startDay, 0, 0, 0, 0, time.UTC)
+		until := since.AddDate(0, 1, -1)
+		p := Period{Since: since, Until: until, Label: since.Format("(2006-01) January 2006")}
+		if startDay > 1 {
+			named := since
+			if startDay > 15 {
+				named = until
+			}
+			p.Label = named.Format("(2006-01) ") + since.Format("2 Jan 2006") + " - " + until.Format("2 Jan 2006")
+		}
+		periods = append(periods, p)
+	}
+	return periods
+}
+
+// WeekPeriods returns the current and the previous n-1 ISO weeks (Monday to Sunday),
+// newest first.
+func WeekPeriods(n int, now time.Time) []Period {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	// Weekday counts from Sunday; ISO weeks start on Monday
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+
+	var periods []Period
+	for i := 0; i < n; i++ {
+		since := monday.AddDate(0, 0, -7*i)
+		isoYear, week := since.ISOWeek()
+		periods = append(periods, Period{
+			Label: fmt.Sprintf("(%d-W%02d) %s", isoYear, week, since.Format("2 Jan 2006")),
+			Since: since,
+			Until: since.AddDate(0, 0, 6),
+		})
+	}
+	return periods
+}
+
+// DayPeriods returns today and the previous n-1 days, newest first.
+func DayPeriods(n int, now time.Time) []Period {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	var periods []Period
+	for i := 0; i < n; i++ {
+		date := today.AddDate(0, 0, -i)
+		periods = append(periods, Period{
+			Label: date.Format("(2006-01-02) Monday"),
+			Since: date,
+			Until: date,
+		})
+	}
+	return periods
+}
+
+// Periods returns the current and the previous n-1 periods of the granularity day,
+// week or month, newest first.
+func Periods(granularity string, n int, now time.Time) ([]Period, error) {
+	return PeriodsFrom(granularity, 1, n, now)
+}
+
+// PeriodsFrom is Periods with months starting on startDay, see MonthPeriodsFrom.
+func PeriodsFrom(granularity string, startDay, n int, now time.Time) ([]Period, error) {
+	if startDay < 1 || startDay > 28 {
+		return nil, fmt.Errorf("invalid period start day %d, expected 1 to 28", startDay)
+	}
+	if startDay != 1 && granularity != "month" && granularity != "" {
+		return nil, fmt.Errorf("a period start day needs month periods, not %s", granularity)
+	}
+	switch granularity {
+	case "day":
+		return DayPeriods(n, now), nil
+	case "week":
+		return WeekPeriods(n, now), nil
+	case "month", "":
+		return MonthPeriodsFrom(n, startDay, now), nil
+	}
+	return nil, fmt.Errorf("unknown granularity: %s", granularity)
+}
+
+// relativeDateRegex matches git-style relative dates such as "3.weeks.ago" or "2 days ago".
+var relativeDateRegex = regexp.MustCompile(`^(\d+)[.\s]+(day|week|month|year)s?[.\s]+ago$`)
+
+// ParseDate parses a YYYY-MM-DD date or a date relative to now: "today", "yesterday"
+// or "N.days.ago" with days, weeks, months or years (spaces instead of dots work too).
+func ParseDate(value string, now time.Time) (time.Time, error) {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	if match := relativeDateRegex.FindStringSubmatch(value); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "day":
+			return today.AddDate(0, 0, -n), nil
+		case "week":
+			return today.AddDate(0, 0, -7*n), nil
+		case "month":
+			return today.AddDate(0, -n, 0), nil
+		default:
+			return today.AddDate(-n, 0, 0), nil
+		}
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// CustomPeriod returns the single period of -since/-until (see ParseDate, either may be
+// empty). A missing until means today, a missing since means no lower bound.
+func CustomPeriod(sinceStr, untilStr string, now time.Time) (Period, error) {
+	var p Period
+	var err error
+	if sinceStr != "" {
+		if p.Since, err = ParseDate(sinceStr, now); err != nil {
+			return p, fmt.Errorf("invalid -since date %q, expected YYYY-MM-DD or e.g. 3.weeks.ago", sinceStr)
+		}
+	}
+	if untilStr != "" {
+		if p.Until, err = ParseDate(untilStr, now); err != nil {
+			return p, fmt.Errorf("invalid -until date %q, expected YYYY-MM-DD or e.g. 3.weeks.ago", untilStr)
+		}
+	} else {
+		p.Until, _ = ParseDate("today", now)
+	}
+	if !p.Since.IsZero() && p.Until.Before(p.Since) {
+		return p, fmt.Errorf("-until %s is before -since %s", p.Until.Format("2006-01-02"), p.Since.Format("2006-01-02"))
+	}
+
+	if p.Since.IsZero() {
+		p.Label = "(until " + p.Until.Format("2006-01-02") + ")"
+	} else {
+		p.Label = "(" + p.Since.Format("2006-01-02") + " - " + p.Until.Format("2006-01-02") + ")"
+	}
+	return p, nil
+}
+
+// RangePeriods returns the periods of the granularity day, week or month overlapping
+// the -since/-until range, newest first, with the first and last cut to the range. Without
+// a since date the range has no lower bound and is a single period like CustomPeriod.
+func RangePeriods(granularity, sinceStr, untilStr string, now time.Time) ([]Period, error) {
+	return RangePeriodsFrom(granularity, 1, sinceStr, untilStr, now)
+}
+
+// RangePeriodsFrom is RangePeriods with months starting on startDay, see
+// MonthPeriodsFrom.
+func RangePeriodsFrom(granularity string, startDay int, sinceStr, untilStr string, now time.Time) ([]Period, error) {
+	r, err := CustomPeriod(sinceStr, untilStr, now)
+	if err != nil {
+		return nil, err
+	}
+	if r.Since.IsZero() {
+		return []Period{r}, nil
+	}
+
+	// A day is the shortest period, so the range spans at most this many
+	days := int(r.Until.Sub(r.Since).Hours()/24) + 1
+	all, err := PeriodsFrom(granularity, startDay, days, r.Until)
+	if err != nil {
+		return nil, err
+	}
+	var periods []Period
+	for _, p := range all {
+		if p.Until.Before(r.Since) {
+			break
+		}
+		if p.Since.Before(r.Since) {
+			p.Since = r.Since
+		}
+		if p.Until.After(r.Until) {
+			p.Until = r.Until
+		}
+		periods = append(periods, p)
+	}
+	return periods, nil
+}
@@ -0,0 +1,67 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// prometheusLabel escapes value for a label of the Prometheus text format.
+var prometheusLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// printPrometheus writes the changes per author, repository and period as counters in
+// the Prometheus text exposition format. Series are per repository when Repos is set
+// and under the repository "" otherwise; month is the key of the period, e.g. 2024-03.
+func printPrometheus(w io.Writer, globalStats GlobalStats) error {
+	repos := globalStats.Repos
+	if repos == nil {
+		repos = map[string]*GlobalStats{"": &globalStats}
+	}
+	var names []string
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := []struct {
+		name, help string
+		value      func(ChangesStats) int
+	}{
+		{"gitstats_insertions_total", "Lines inserted per author, repository and period.", func(s ChangesStats) int { return s.Insertions }},
+		{"gitstats_deletions_total", "Lines deleted per author, repository and period.", func(s ChangesStats) int { return s.Deletions }},
+		{"gitstats_commits_total", "Commits per author, repository and period.", func(s ChangesStats) int { return s.Commits }},
+	}
+
+	var b strings.Builder
+	for _, metric := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", metric.name, metric.help, metric.name)
+		for _, name := range names {
+			stats := repos[name]
+			var authors []string
+			for author := range stats.Stats {
+				authors = append(authors, author)
+			}
+			sort.Strings(authors)
+
+			for _, author := range authors {
+				var months []string
+				for month := range stats.Stats[author] {
+					months = append(months, month)
+				}
+				sort.Strings(months)
+
+				for _, month := range months {
+					fmt.Fprintf(&b, "%s{author=\"%s\",repo=\"%s\",month=\"%s\"} %d\n", metric.name,
+						prometheusLabel.Replace(author), prometheusLabel.Replace(name),
+						prometheusLabel.Replace(periodKey(month)), metric.value(stats.Stats[author][month]))
+				}
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write metrics: %s", err)
+	}
+	return nil
+}
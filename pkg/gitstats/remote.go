@@ -0,0 +1,182 @@
+package gitstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// remoteCommit is a commit with its line counts as reported by a hosting API.
+type remoteCommit struct {
+	Hash           string
+	AuthorEmail    string
+	AuthorName     string
+	Authored       time.Time
+	CommitterName  string
+	CommitterEmail string
+	Committed      time.Time
+	Insertions     int
+	Deletions      int
+	Files          int
+	Parents        int
+}
+
+// logRequest is the selection of a git log command sent to a remote Git: the
+// repository of -C, the revision (empty for the default branch) and the --since and
+// --until dates, zero when missing.
+type logRequest struct {
+	repo     string
+	rev      string
+	since    time.Time
+	until    time.Time
+	noMerges bool
+}
+
+// remoteLogFormat is the --pretty format of the main log pass, the one a remote Git
+// serves. Remote Gits answer it with --shortstat style lines rather than -z output.
+const remoteLogFormat = "--pretty=format:%H%x09%aE%x09%aI%x09%aN%x09%cN%x09%cE%x09%cI"
+
+// parseRemoteCommand returns the repository of -C, the git subcommand and its
+// arguments from the arguments of Git.Run.
+func parseRemoteCommand(args []string) (repo, command string, rest []string) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--no-pager":
+		case "-C":
+			i++
+			if i < len(args) {
+				repo = args[i]
+			}
+		case "-c":
+			i++
+		default:
+			return repo, args[i], args[i+1:]
+		}
+	}
+	return repo, "", nil
+}
+
+// parseLogRequest returns the selection of the git log arguments of the main log pass
+// of Collect. Other log passes, such as those of PullRequests, fail as the hosting APIs
+// only report the totals of each commit, and so do file selections after "--", such as
+// those of Extensions, Paths, Includes and Excludes.
+func parseLogRequest(repo string, args []string) (logRequest, error) {
+	request := logRequest{repo: repo}
+	format := false
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			if files := args[i+1:]; len(files) > 0 {
+				return request, fmt.Errorf("git log -- %s is not available remotely: only the totals of each commit are known", strings.Join(files, " "))
+			}
+			return request, checkFormat(format)
+		case arg == remoteLogFormat:
+			format = true
+		case strings.HasPrefix(arg, "--pretty="):
+			return request, fmt.Errorf("git log %s is not available remotely", arg)
+		case arg == "--all", strings.HasPrefix(arg, "--grep="):
+			return request, fmt.Errorf("git log %s is not available remotely", arg)
+		case arg == "--no-merges":
+			request.noMerges = true
+		case strings.HasPrefix(arg, "--since="):
+			t, err := parseDateArg(strings.TrimPrefix(arg, "--since="))
+			if err != nil {
+				return request, err
+			}
+			request.since = t
+		case strings.HasPrefix(arg, "--until="):
+			t, err := parseDateArg(strings.TrimPrefix(arg, "--until="))
+			if err != nil {
+				return request, err
+			}
+			request.until = t
+		case strings.HasPrefix(arg, "-"):
+			// Diff options such as -M, -w and --shortstat don't change the API totals
+		default:
+			request.rev = arg
+		}
+	}
+	return request, checkFormat(format)
+}
+
+func checkFormat(ok bool) error {
+	if !ok {
+		return fmt.Errorf("git log without %s is not available remotely", remoteLogFormat)
+	}
+	return nil
+}
+
+// parseDateArg parses a --since or --until date of dateArg: RFC 3339, or a local time
+// without offset.
+func parseDateArg(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("failed to parse date %q: %s", value, err)
+	}
+	return t, nil
+}
+
+// formatLog returns commits, newest first, as the output of the log pass of Collect
+// with --shortstat: remote commits are parsed like local ones. Merge commits have no
+// stat line, as git log shows no diff for them.
+func formatLog(commits []remoteCommit, noMerges bool) []byte {
+	var b bytes.Buffer
+	for _, commit := range commits {
+		if noMerges && commit.Parents > 1 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", commit.Hash, commit.AuthorEmail, commit.Authored.Format(time.RFC3339),
+			commit.AuthorName, commit.CommitterName, commit.CommitterEmail, commit.Committed.Format(time.RFC3339))
+		if commit.Parents <= 1 && (commit.Insertions > 0 || commit.Deletions > 0) {
+			fmt.Fprintf(&b, " %d files changed, %d insertions(+), %d deletions(-)\n", max(commit.Files, 1), commit.Insertions, commit.Deletions)
+		}
+	}
+	return b.Bytes()
+}
+
+// requestJSON sends an API request with body encoded as JSON unless nil, the token in
+// header, and decodes the JSON response into v.
+func requestJSON(client *http.Client, method, url, header, token string, body any, v any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %s", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %s", err)
+	}
+	if token != "" {
+		req.Header.Set(header, token)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to request %s: %s %s", url, resp.Status, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %s", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,1550 @@
+package gitstats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RenderOptions controls the report written by Render.
+type RenderOptions struct {
+	Format     string // text (default), json, delimited, csv, markdown, html, sql, prometheus or template
+	Delimiter  string // Field delimiter of the delimited format
+	Header     bool   // Print a header record in the delimited and csv formats
+	MaxAuthors int    // Keep the top N authors by Sort in the json, delimited, csv, sql and prometheus formats and sum the rest into OthersAuthor
+	PathStats  string // Report who changed this path instead of the author tables
+
+	Template *template.Template // Executed on the Summary of the stats by the template format, see ParseTemplate
+
+	NetOnly bool   // Collapse figures to insertions minus deletions
+	Sort    string // Column authors are sorted by: net (default), insertions, deletions, commits or author
+	Reverse bool   // List authors in the opposite order of Sort
+	Border  string // Table style: none (default), ascii or unicode-box
+	Top     int    // Authors listed in the developer table, 0 for all
+	TopAll  bool   // Apply Top to the per-month tables as well
+	Color   bool   // Keep the ANSI colors of the text report
+	Teams   bool   // The authors are teams, see GroupByTeam
+	Days    bool   // Add the active days and commits per active day to the developer table
+	Sizes   bool   // Add the mean and median lines changed per commit to the developer table
+	Shares  bool   // Add each author's share of commits to the tables and the concentration of insertions, top 3 share and Gini coefficient, below them
+
+	HeatmapByAuthor bool // Also print the heatmap of each author when Heatmap was collected
+	LanguageProfile bool // Also print each author's share of lines changed per language when Languages was collected
+
+	Rolling      int       // Also report an N-month rolling average of insertions
+	Trend        bool      // Also report the trend of insertions per month of each author, flagging steep rises and drops
+	Chart        bool      // Chart total insertions per month
+	Bars         bool      // Add a bar of each author's insertions to the developer table and a sparkline of the months
+	ChartWidth   int       // Width of the chart, 80 when 0
+	Tags         bool      // Report tags created per person
+	Binary       bool      // Report binary files changed per person
+	PullRequests bool      // Report contributions per pull request
+	ActivityGap  bool      // Report days since the last commit per author
+	GapDays      int       // Authors inactive for longer are flagged by ActivityGap
+	Now          time.Time // Reference time of ActivityGap and Tenure, the current time when zero
+
+	Tenure         bool // Report the first and last active day of each author, flagging the new and the inactive ones
+	InactiveMonths int  // Authors without commits for this many months are flagged inactive by Tenure, 3 when 0
+}
+
+// Render writes the report of globalStats in the selected format. The text report is
+// preceded by a section per repository when Repos is set and followed by the optional
+// sections selected in opts.
+func Render(w io.Writer, globalStats GlobalStats, opts RenderOptions) error {
+	if !opts.Color {
+		w = plainWriter{w}
+	}
+
+	// metric is the figure authors are ranked by
+	metric := func(stats ChangesStats) int {
+		if opts.NetOnly {
+			return stats.Insertions - stats.Deletions
+		}
+		return stats.Insertions
+	}
+
+	order := authorOrder{opts.Sort, opts.Reverse}
+	stats := globalStats
+	switch opts.Format {
+	case "json", "delimited", "csv", "sql", "prometheus":
+		if opts.MaxAuthors > 0 {
+			// The authors kept are the top rows of the report, ranked by the Sort column
+			rank := func(stats ChangesStats) int { return sortValue(stats, opts.Sort) }
+			stats = *CapAuthors(&globalStats, opts.MaxAuthors, rank)
+			if globalStats.Repos != nil {
+				stats.Repos = make(map[string]*GlobalStats)
+				for name, repo := range globalStats.Repos {
+					stats.Repos[name] = CapAuthors(repo, opts.MaxAuthors, rank)
+				}
+			}
+		}
+	}
+	switch opts.Format {
+	case "json":
+		return printJSON(w, stats, stats.Repos)
+	case "delimited":
+		printDelimited(w, stats, opts.Delimiter, opts.Header, opts.Rolling)
+		return nil
+	case "csv":
+		return printCSV(w, stats, opts.Header)
+	case "markdown":
+		style := borderStyles["markdown"]
+		printReport(plainWriter{w}, globalStats, "", reportOptions{NetOnly: opts.NetOnly, Sort: opts.Sort, Reverse: opts.Reverse, Border: style, Top: opts.Top, TopAll: opts.TopAll, Teams: opts.Teams, Days: opts.Days, Shares: opts.Shares})
+		if globalStats.Repos != nil {
+			printRepoMatrix(plainWriter{w}, globalStats, order, style, opts.Teams)
+		}
+		return nil
+	case "html":
+		var heatmaps []heatmapGrid
+		if globalStats.Heatmap != nil {
+			heatmaps = heatmapGrids(globalStats, order, opts.HeatmapByAuthor)
+		}
+		return printHTML(w, globalStats, order, metric, opts.Top, opts.TopAll, opts.Teams, heatmaps)
+	case "sql":
+		return printSQL(w, stats)
+	case "prometheus":
+		return printPrometheus(w, stats)
+	case "template":
+		return printTemplate(w, globalStats, opts.Template, order)
+	case "", "text":
+	default:
+		return fmt.Errorf("unknown format: %s", opts.Format)
+	}
+
+	border := opts.Border
+	if border == "" {
+		border = "none"
+	}
+	style, ok := borderStyles[border]
+	if !ok {
+		return fmt.Errorf("unknown border style: %s", opts.Border)
+	}
+	reportOpts := reportOptions{NetOnly: opts.NetOnly, Sort: opts.Sort, Reverse: opts.Reverse, Border: style, Top: opts.Top, TopAll: opts.TopAll, Teams: opts.Teams, Days: opts.Days, Sizes: opts.Sizes, Bars: opts.Bars, Shares: opts.Shares}
+
+	printReport(w, globalStats, opts.PathStats, reportOpts)
+
+	if globalStats.Repos != nil && opts.PathStats == "" {
+		printRepoMatrix(w, globalStats, order, style, opts.Teams)
+	}
+
+	if globalStats.Languages != nil {
+		printLanguages(w, globalStats, order)
+		if opts.LanguageProfile {
+			printLanguageProfile(w, globalStats, order, style)
+		}
+	}
+
+	if globalStats.Churn != nil {
+		printChurn(w, globalStats, style)
+	}
+
+	if globalStats.Tickets != nil {
+		printTickets(w, globalStats, style)
+	}
+
+	if globalStats.Components != nil {
+		printComponents(w, globalStats, style)
+	}
+
+	if globalStats.Owners != nil {
+		printOwners(w, globalStats, style)
+	}
+
+	if globalStats.Metrics != nil {
+		printMetrics(w, globalStats, order, style)
+	}
+
+	if len(globalStats.Outliers) > 0 {
+		printOutliers(w, globalStats, style)
+	}
+
+	if len(globalStats.Duplicates) > 0 {
+		printDuplicates(w, globalStats, style)
+	}
+
+	if globalStats.Heatmap != nil {
+		printHeatmap(w, globalStats, order, opts.HeatmapByAuthor)
+	}
+
+	if globalStats.Squashes != nil {
+		fmt.Fprintf(w, "\nSquash merges (committed by a platform or a different committer):\n")
+		printReport(w, *globalStats.Squashes, opts.PathStats, reportOpts)
+	}
+
+	if opts.Rolling > 0 {
+		printRolling(w, globalStats, opts.Rolling, style)
+	}
+
+	if opts.Trend {
+		printTrends(w, globalStats, order, style)
+	}
+
+	if opts.Chart {
+		width := opts.ChartWidth
+		if width <= 0 {
+			width = 80
+		}
+		printChart(w, globalStats, width)
+	}
+
+	if opts.Tags {
+		printTags(w, globalStats)
+	}
+
+	if opts.Binary {
+		printBinary(w, globalStats)
+	}
+
+	if opts.PullRequests {
+		printPullRequests(w, globalStats)
+	}
+
+	if globalStats.Reviews != nil {
+		printReviews(w, globalStats, style)
+	}
+
+	if opts.ActivityGap {
+		now := opts.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		printActivityGap(w, globalStats, opts.GapDays, now)
+	}
+
+	if opts.Tenure {
+		now := opts.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		months := opts.InactiveMonths
+		if months <= 0 {
+			months = 3
+		}
+		printTenure(w, globalStats, months, now, style)
+	}
+	return nil
+}
+
+// printReport prints the author tables (or the -path-stats report) of globalStats,
+// preceded by a section per repository when Repos is set.
+func printReport(w io.Writer, globalStats GlobalStats, path string, opts reportOptions) {
+	var names []string
+	for name := range globalStats.Repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if opts.Border.markdown {
+			fmt.Fprintf(w, "\n## Repository %s\n", name)
+		} else {
+			fmt.Fprintf(w, "\033[94m=== Repository %s ===\033[0m\n", name)
+		}
+		if path != "" {
+			printPathStats(w, *globalStats.Repos[name], path)
+		} else {
+			printStats(w, *globalStats.Repos[name], opts)
+		}
+	}
+	if globalStats.Repos != nil {
+		if opts.Border.markdown {
+			fmt.Fprintf(w, "\n## All repositories\n")
+		} else {
+			fmt.Fprintf(w, "\033[94m=== All repositories ===\033[0m\n")
+		}
+	}
+	if path != "" {
+		printPathStats(w, globalStats, path)
+	} else {
+		printStats(w, globalStats, opts)
+	}
+}
+
+// PrintMergedNames lists the emails merged under each display name by MergeByName.
+func PrintMergedNames(w io.Writer, merged map[string][]string) {
+	if len(merged) == 0 {
+		return
+	}
+	var names []string
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Merged authors by name:\n")
+	for _, name := range names {
+		emails := merged[name]
+		fmt.Fprintf(w, "  %s: %s -> %s\n", name, strings.Join(emails, ", "), emails[0])
+	}
+}
+
+// jsonReport is the -format=json document: stats keyed by author email, then month,
+// with the same breakdown per repository name under -by-repo.
+type jsonReport struct {
+	Authors         map[string]map[string]ChangesStats `json:"authors"`
+	TotalInsertions int                                `json:"totalInsertions"`
+	TotalDeletions  int                                `json:"totalDeletions"`
+	TotalCommits    int                                `json:"totalCommits"`
+	Names           map[string]string                  `json:"names,omitempty"`
+	Languages       map[string]map[string]ChangesStats `json:"languages,omitempty"`
+	Tags            map[string]int                     `json:"tags,omitempty"`
+	Binary          map[string]int                     `json:"binary,omitempty"`
+	PullRequests    []PullRequest                      `json:"pullRequests,omitempty"`
+	Churn           map[string]Churn                   `json:"churn,omitempty"`
+	ActiveDays      map[string]int                     `json:"activeDays,omitempty"`
+	CommitSizes     map[string]CommitSize              `json:"commitSizes,omitempty"`
+	CommitSize      *CommitSize                        `json:"commitSize,omitempty"`
+	Heatmap         map[string]Heatmap                 `json:"heatmap,omitempty"`
+	Tickets         map[string]map[string]ChangesStats `json:"tickets,omitempty"`
+	Components      map[string]map[string]ChangesStats `json:"components,omitempty"`
+	Owners          map[string]map[string]ChangesStats `json:"owners,omitempty"`
+	Reviews         map[string]map[string]ReviewStats  `json:"reviews,omitempty"`
+	Outliers        []OutlierCommit                    `json:"outliers,omitempty"`
+	Duplicates      map[string]int                     `json:"duplicates,omitempty"`
+	Merged          map[string][]string                `json:"merged,omitempty"`
+	Squashes        *jsonReport                        `json:"squashes,omitempty"`
+	Repositories    map[string]jsonReport              `json:"repositories,omitempty"`
+
+	Metrics      map[string]map[string]map[string]int `json:"metrics,omitempty"`
+	Breadth      map[string]map[string]Breadth        `json:"breadth,omitempty"`      // Per author and period
+	TotalBreadth map[string]Breadth                   `json:"totalBreadth,omitempty"` // Per author over all periods
+
+	LanguageProfiles map[string][]LanguageShare `json:"languageProfiles,omitempty"`
+}
+
+func newJSONReport(globalStats GlobalStats) jsonReport {
+	totals := globalStats.Totals()
+	report := jsonReport{
+		Authors:         globalStats.Stats,
+		TotalInsertions: totals.Insertions,
+		TotalDeletions:  totals.Deletions,
+		TotalCommits:    totals.Commits,
+		Languages:       globalStats.Languages,
+		Tags:            globalStats.Tags,
+		Binary:          globalStats.Binary,
+		PullRequests:    globalStats.PullRequests,
+		Churn:           globalStats.Churn,
+		Heatmap:         globalStats.Heatmap,
+		Tickets:         globalStats.Tickets,
+		Components:      globalStats.Components,
+		Owners:          globalStats.Owners,
+		Reviews:         globalStats.Reviews,
+		Metrics:         globalStats.Metrics,
+		Outliers:        globalStats.Outliers,
+		Duplicates:      globalStats.Duplicates,
+		Merged:          globalStats.Merged,
+	}
+	for author, days := range globalStats.ActiveDays {
+		if _, ok := globalStats.Stats[author]; !ok {
+			continue
+		}
+		if report.ActiveDays == nil {
+			report.ActiveDays = make(map[string]int)
+		}
+		report.ActiveDays[author] = len(days)
+	}
+	for author, periods := range globalStats.Files {
+		if _, ok := globalStats.Stats[author]; !ok {
+			continue
+		}
+		if report.Breadth == nil {
+			report.Breadth, report.TotalBreadth = make(map[string]map[string]Breadth), make(map[string]Breadth)
+		}
+		report.Breadth[author] = make(map[string]Breadth)
+		for period, files := range periods {
+			report.Breadth[author][period] = newBreadth(files)
+		}
+		report.TotalBreadth[author] = globalStats.Breadth(author, "")
+	}
+	for author, languages := range globalStats.Languages {
+		if profile := LanguageProfile(languages); len(profile) > 0 {
+			if report.LanguageProfiles == nil {
+				report.LanguageProfiles = make(map[string][]LanguageShare)
+			}
+			report.LanguageProfiles[author] = profile
+		}
+	}
+	for author, sizes := range globalStats.CommitSizes {
+		if _, ok := globalStats.Stats[author]; !ok || len(sizes) == 0 {
+			continue
+		}
+		if report.CommitSizes == nil {
+			report.CommitSizes = make(map[string]CommitSize)
+		}
+		report.CommitSizes[author] = newCommitSize(sizes)
+	}
+	if sizes := allCommitSizes(globalStats.CommitSizes); len(sizes) > 0 {
+		size := newCommitSize(sizes)
+		report.CommitSize = &size
+	}
+	// Names also holds authors filtered out later, e.g. by Options.Authors
+	authors := []map[string]map[string]ChangesStats{globalStats.Stats}
+	if globalStats.Squashes != nil {
+		authors = append(authors, globalStats.Squashes.Stats)
+	}
+	for _, stats := range authors {
+		for author := range stats {
+			if name, ok := globalStats.Names[author]; ok {
+				if report.Names == nil {
+					report.Names = make(map[string]string)
+				}
+				report.Names[author] = name
+			}
+		}
+	}
+	if globalStats.Squashes != nil {
+		squashes := newJSONReport(*globalStats.Squashes)
+		report.Squashes = &squashes
+	}
+	return report
+}
+
+func printJSON(w io.Writer, globalStats GlobalStats, repos map[string]*GlobalStats) error {
+	report := newJSONReport(globalStats)
+	if repos != nil {
+		report.Repositories = make(map[string]jsonReport)
+		for name, repo := range repos {
+			report.Repositories[name] = newJSONReport(*repo)
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JSON: %s", err)
+	}
+	return nil
+}
+
+// printDelimited writes one record per author-month joined by delimiter.
+// Fields are not quoted, so a delimiter occurring inside an author breaks the record.
+// With rolling > 0 a rolling_insertions column carries the author's rolling average.
+func printDelimited(w io.Writer, globalStats GlobalStats, delimiter string, header bool, rolling int) {
+	if header {
+		fields := []string{"author", "month", "insertions", "deletions", "commits"}
+		if rolling > 0 {
+			fields = append(fields, "rolling_insertions")
+		}
+		fmt.Fprintln(w, strings.Join(fields, delimiter))
+	}
+
+	allMonths := analyzedMonths(globalStats)
+	monthIndex := make(map[string]int)
+	for i, month := range allMonths {
+		monthIndex[month] = i
+	}
+
+	var authors []string
+	for author := range globalStats.Stats {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+
+	for _, author := range authors {
+		var months []string
+		for month := range globalStats.Stats[author] {
+			months = append(months, month)
+		}
+		sort.Strings(months)
+
+		var averages []float64
+		if rolling > 0 {
+			averages = rollingAverage(insertionSeries(globalStats, author, allMonths), rolling)
+		}
+
+		for _, month := range months {
+			stats := globalStats.Stats[author][month]
+			fields := []string{author, month, fmt.Sprint(stats.Insertions), fmt.Sprint(stats.Deletions), fmt.Sprint(stats.Commits)}
+			if rolling > 0 {
+				fields = append(fields, strconv.FormatFloat(averages[monthIndex[month]], 'f', 1, 64))
+			}
+			fmt.Fprintln(w, strings.Join(fields, delimiter))
+		}
+	}
+}
+
+// printCSV writes one author,month,insertions,deletions,commits record per author and month,
+// sorted by author then month.
+func printCSV(w io.Writer, globalStats GlobalStats, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		cw.Write([]string{"author", "month", "insertions", "deletions", "commits"})
+	}
+
+	var authors []string
+	for author := range globalStats.Stats {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+
+	for _, author := range authors {
+		var months []string
+		for month := range globalStats.Stats[author] {
+			months = append(months, month)
+		}
+		sort.Strings(months)
+
+		for _, month := range months {
+			stats := globalStats.Stats[author][month]
+			cw.Write([]string{author, month, strconv.Itoa(stats.Insertions), strconv.Itoa(stats.Deletions), strconv.Itoa(stats.Commits)})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %s", err)
+	}
+	return nil
+}
+
+// sortedMonths returns every month present in the stats in chronological order.
+func sortedMonths(globalStats GlobalStats) []string {
+	uniqueMonths := make(map[string]bool)
+	for _, months := range globalStats.Stats {
+		for month := range months {
+			uniqueMonths[month] = true
+		}
+	}
+	var monthsOrdered []string
+	for month := range uniqueMonths {
+		monthsOrdered = append(monthsOrdered, month)
+	}
+	sort.Strings(monthsOrdered)
+	return monthsOrdered
+}
+
+// analyzedMonths returns every analyzed month in chronological order, including
+// months without any changes, so series over them have no gaps.
+func analyzedMonths(globalStats GlobalStats) []string {
+	uniqueMonths := make(map[string]bool)
+	for month := range globalStats.Periods {
+		uniqueMonths[month] = true
+	}
+	for _, month := range sortedMonths(globalStats) {
+		uniqueMonths[month] = true
+	}
+	var monthsOrdered []string
+	for month := range uniqueMonths {
+		monthsOrdered = append(monthsOrdered, month)
+	}
+	sort.Strings(monthsOrdered)
+	return monthsOrdered
+}
+
+// insertionSeries returns the insertions of author (all authors when empty) for each
+// of months, 0 for months without changes.
+func insertionSeries(globalStats GlobalStats, author string, months []string) []int {
+	series := make([]int, len(months))
+	for i, month := range months {
+		for a, authorMonths := range globalStats.Stats {
+			if author == "" || a == author {
+				series[i] += authorMonths[month].Insertions
+			}
+		}
+	}
+	return series
+}
+
+// rollingAverage returns the average of each value and the n-1 values before it.
+// At the start of the series the window is partial and only covers the values so far.
+func rollingAverage(values []int, n int) []float64 {
+	averages := make([]float64, len(values))
+	sum := 0
+	for i, v := range values {
+		sum += v
+		if i >= n {
+			sum -= values[i-n]
+		}
+		averages[i] = float64(sum) / float64(min(i+1, n))
+	}
+	return averages
+}
+
+// reportOptions controls how the text report is rendered.
+type reportOptions struct {
+	NetOnly bool        // Collapse figures to insertions minus deletions
+	Sort    string      // Column authors are sorted by: net, insertions, deletions, commits or author
+	Reverse bool        // List authors in the opposite order of Sort
+	Border  borderStyle // Table style of the per-month and developer tables
+	Top     int         // Authors listed in the developer table, 0 for all
+	TopAll  bool        // Apply Top to the per-month tables as well
+	Teams   bool        // The authors are teams
+	Days    bool        // Add the active days and commits per active day to the developer table
+	Sizes   bool        // Add the mean and median lines changed per commit to the developer table
+	Bars    bool        // Add insertion bars to the developer table and a sparkline of the months
+	Shares  bool        // Add the share of commits to the tables and the concentration of insertions below them
+}
+
+// sortValue returns the figure authors are ranked by for the given -sort column, net
+// lines for the alphabetical "author" order.
+func sortValue(stats ChangesStats, column string) int {
+	switch column {
+	case "insertions":
+		return stats.Insertions
+	case "deletions":
+		return stats.Deletions
+	case "commits":
+		return stats.Commits
+	default:
+		return stats.Insertions - stats.Deletions
+	}
+}
+
+// authorOrder is the order of the author lists: by the figure of a -sort column, most
+// first, or alphabetically for "author", with ties listed alphabetically.
+type authorOrder struct {
+	column  string
+	reverse bool
+}
+
+// less reports whether author a with stats sa is listed before author b with sb.
+func (o authorOrder) less(a string, sa ChangesStats, b string, sb ChangesStats) bool {
+	if o.column != "author" {
+		if va, vb := sortValue(sa, o.column), sortValue(sb, o.column); va != vb {
+			return (va > vb) != o.reverse
+		}
+	}
+	return (a < b) != o.reverse
+}
+
+// printStats prints the per-month and per-developer tables with insertions, deletions
+// and net lines. With NetOnly, every figure is collapsed to the net column, negative
+// values in red.
+func printStats(w io.Writer, globalStats GlobalStats, opts reportOptions) {
+	red := "\033[31m"
+	green := "\033[32m"
+	yellow := "\033[33m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	if len(globalStats.Stats) == 0 {
+		return
+	}
+
+	type authorStats struct {
+		Author string
+		ChangesStats
+	}
+
+	// header and columns lay out the figures of one table row
+	header := []string{"Author", "Commits", "Insertions", "Ins %", "Deletions", "Net", "Net %"}
+	if opts.NetOnly {
+		header = []string{"Author", "Commits", "Net lines", "Net %"}
+	}
+	group := "developer"
+	if opts.Teams {
+		header[0], group = "Team", "team"
+	}
+	rightAlign := []bool{false, true, true, true, true, true, true}
+	if opts.Shares {
+		header = append(header[:2], append([]string{"Commits %"}, header[2:]...)...)
+		rightAlign = append(rightAlign, true)
+	}
+	net := func(v int) string {
+		if v < 0 {
+			return fmt.Sprintf("%s%d%s", red, v, reset)
+		}
+		return fmt.Sprintf("%s%+d%s", green, v, reset)
+	}
+	// columns lays out stats with their shares of total, the stats of the whole table
+	columns := func(label string, stats, total ChangesStats) []string {
+		netShare := percent(stats.Insertions-stats.Deletions, total.Insertions-total.Deletions)
+		row := []string{label, strconv.Itoa(stats.Commits)}
+		if opts.Shares {
+			row = append(row, percent(stats.Commits, total.Commits))
+		}
+		if opts.NetOnly {
+			return append(row, net(stats.Insertions-stats.Deletions), netShare)
+		}
+		return append(row,
+			fmt.Sprintf("%s%d%s", green, stats.Insertions, reset),
+			percent(stats.Insertions, total.Insertions),
+			fmt.Sprintf("%s%d%s", red, stats.Deletions, reset),
+			net(stats.Insertions-stats.Deletions),
+			netShare,
+		)
+	}
+	// concentration prints how evenly the insertions of list are spread, below its table
+	concentration := func(list []authorStats) {
+		if !opts.Shares || len(list) == 0 {
+			return
+		}
+		values := make([]int, len(list))
+		for i, stats := range list {
+			values[i] = stats.Insertions
+		}
+		if opts.Border.markdown {
+			// A line right below a Markdown table would be one of its rows
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Concentration of insertions: %s\n", NewConcentration(values).describe(group))
+	}
+	order := authorOrder{opts.Sort, opts.Reverse}
+	sortAuthors := func(list []authorStats) {
+		sort.Slice(list, func(i, j int) bool {
+			return order.less(list[i].Author, list[i].ChangesStats, list[j].Author, list[j].ChangesStats)
+		})
+	}
+	// addRows adds the sorted authors to t as laid out by row, collapsing everyone
+	// past the first limit authors into a single row. A limit of 0 lists them all.
+	addRows := func(t *table, list []authorStats, limit int, row func(label string, stats ChangesStats) []string) {
+		if limit <= 0 || len(list) <= limit {
+			limit = len(list)
+		}
+		for _, stats := range list[:limit] {
+			t.addRow(row(stats.Author, stats.ChangesStats)...)
+		}
+		if rest := list[limit:]; len(rest) > 0 {
+			var others ChangesStats
+			for _, stats := range rest {
+				others.Insertions += stats.Insertions
+				others.Deletions += stats.Deletions
+				others.Commits += stats.Commits
+			}
+			t.addRow(row(fmt.Sprintf("… and %d others", len(rest)), others)...)
+		}
+	}
+	separator := func(color string) {
+		if opts.Border.noBorder {
+			fmt.Fprintf(w, "%s-----------------------------%s\n", color, reset)
+		}
+	}
+	// heading prints a section title, as a Markdown heading with the markdown style
+	heading := func(color, title string) {
+		if opts.Border.markdown {
+			fmt.Fprintf(w, "\n### %s\n\n", strings.TrimSuffix(title, ":"))
+			return
+		}
+		fmt.Fprintf(w, "%s%s%s\n", color, title, reset)
+	}
+
+	monthsOrdered := sortedMonths(globalStats)
+
+	// Step 3: Aggregate and print data per month
+	for _, month := range monthsOrdered {
+		separator("")
+		heading(yellow, month)
+		var monthTotal ChangesStats
+
+		var monthStats []authorStats
+		for author, monthsStats := range globalStats.Stats {
+			if stats, exists := monthsStats[month]; exists {
+				monthStats = append(monthStats, authorStats{author, stats})
+				monthTotal.Insertions += stats.Insertions
+				monthTotal.Deletions += stats.Deletions
+				monthTotal.Commits += stats.Commits
+			}
+		}
+		sortAuthors(monthStats)
+
+		// Print sorted stats for the month
+		monthTable := table{header: header, rightAlign: rightAlign}
+		if globalStats.Files != nil {
+			monthTable.header = append(header[:len(header):len(header)], "Files", "Dirs")
+			monthTable.rightAlign = append(rightAlign[:len(header):len(header)], true, true)
+		}
+		monthLimit := 0
+		if opts.TopAll {
+			monthLimit = opts.Top
+		}
+		addRows(&monthTable, monthStats, monthLimit, func(label string, stats ChangesStats) []string {
+			row := columns(label, stats, monthTotal)
+			if globalStats.Files != nil {
+				row = append(row, breadthColumns(globalStats, label, month)...)
+			}
+			return row
+		})
+		monthTable.footer = columns(yellow+"Summary"+reset, monthTotal, monthTotal)
+		if globalStats.Files != nil {
+			monthTable.footer = append(monthTable.footer, breadthColumns(globalStats, "", month)...)
+		}
+		monthTable.render(w, opts.Border)
+		concentration(monthStats)
+	}
+	if !opts.Border.markdown {
+		fmt.Fprintln(w)
+	}
+	separator(blue)
+
+	// Aggregate totals by author
+	authorTotals := make(map[string]ChangesStats)
+	for author, months := range globalStats.Stats {
+		for _, stats := range months {
+			total := authorTotals[author]
+			total.Insertions += stats.Insertions
+			total.Deletions += stats.Deletions
+			total.Commits += stats.Commits
+			authorTotals[author] = total
+		}
+	}
+	var sortedAuthors []authorStats
+	for author, total := range authorTotals {
+		sortedAuthors = append(sortedAuthors, authorStats{author, total})
+	}
+	sortAuthors(sortedAuthors)
+
+	// Print the sorted summary by developers
+	heading(blue, "Total lines by "+group+":")
+	// The developer table adds the average insertions per commit, to spot outliers
+	developerTable := table{header: append(header[:len(header):len(header)], "Lines/commit"), rightAlign: append(rightAlign[:len(header):len(header)], true)}
+	if opts.Days {
+		developerTable.header = append(developerTable.header, "Active days", "Commits/day")
+		developerTable.rightAlign = append(developerTable.rightAlign, true, true)
+	}
+	if opts.Sizes {
+		developerTable.header = append(developerTable.header, "Mean size", "Median size")
+		developerTable.rightAlign = append(developerTable.rightAlign, true, true)
+	}
+	if globalStats.Files != nil {
+		developerTable.header = append(developerTable.header, "Files", "Dirs")
+		developerTable.rightAlign = append(developerTable.rightAlign, true, true)
+	}
+	if opts.Bars {
+		developerTable.header = append(developerTable.header, "")
+		developerTable.rightAlign = append(developerTable.rightAlign, false)
+	}
+	// Bars are scaled to the author with the most insertions, net lines with NetOnly
+	barValue := func(stats ChangesStats) int {
+		if opts.NetOnly {
+			return stats.Insertions - stats.Deletions
+		}
+		return stats.Insertions
+	}
+	largest := 0
+	for _, stats := range sortedAuthors {
+		largest = max(largest, barValue(stats.ChangesStats))
+	}
+	totals := globalStats.Totals()
+	developerRow := func(label string, stats ChangesStats) []string {
+		row := append(columns(label, stats, totals), averageLines(stats))
+		if opts.Days {
+			// The others row mixes the days of several people
+			days, ok := globalStats.ActiveDays[label]
+			if label == "Total summary" {
+				days, ok = unionDays(globalStats.ActiveDays), true
+			}
+			if !ok || len(days) == 0 {
+				return append(row, "-", "-")
+			}
+			row = append(row, strconv.Itoa(len(days)), fmt.Sprintf("%.1f", float64(stats.Commits)/float64(len(days))))
+		}
+		if opts.Sizes {
+			sizes, ok := globalStats.CommitSizes[label]
+			if label == "Total summary" {
+				sizes, ok = allCommitSizes(globalStats.CommitSizes), true
+			}
+			if !ok || len(sizes) == 0 {
+				row = append(row, "-", "-")
+			} else {
+				size := newCommitSize(sizes)
+				row = append(row, fmt.Sprintf("%.1f", size.Mean), fmt.Sprintf("%.1f", size.Median))
+			}
+		}
+		if globalStats.Files != nil {
+			author := label
+			if label == "Total summary" {
+				author = ""
+			}
+			row = append(row, breadthColumns(globalStats, author, "")...)
+		}
+		if opts.Bars {
+			row = append(row, green+bar(barValue(stats), largest, barWidth)+reset)
+		}
+		return row
+	}
+	addRows(&developerTable, sortedAuthors, opts.Top, developerRow)
+	developerTable.footer = developerRow("Total summary", totals)
+	if opts.Bars {
+		developerTable.footer[len(developerTable.footer)-1] = ""
+	}
+	developerTable.render(w, opts.Border)
+	concentration(sortedAuthors)
+	if opts.Bars && len(monthsOrdered) > 1 {
+		var values []int
+		for _, month := range monthsOrdered {
+			total := 0
+			for _, months := range globalStats.Stats {
+				total += barValue(months[month])
+			}
+			values = append(values, total)
+		}
+		label := "Insertions"
+		if opts.NetOnly {
+			label = "Net lines"
+		}
+		fmt.Fprintf(w, "%s per month: %s%s%s  %s - %s\n", label, green, sparkline(values), reset,
+			periodKey(monthsOrdered[0]), periodKey(monthsOrdered[len(monthsOrdered)-1]))
+	}
+	separator(blue)
+}
+
+// barWidth is the width of the bars of the developer table of RenderOptions.Bars.
+const barWidth = 20
+
+// barBlocks are the eighths of a cell the bars end with.
+var barBlocks = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// bar returns a bar of value scaled so that largest fills width cells, in eighths of a
+// cell; negative values have none.
+func bar(value, largest, width int) string {
+	if value <= 0 || largest <= 0 {
+		return ""
+	}
+	eighths := int(int64(value) * int64(width*8) / int64(largest))
+	if eighths == 0 {
+		// A contribution too small to show still gets a sliver
+		eighths = 1
+	}
+	return strings.Repeat("█", eighths/8) + barBlocks[eighths%8]
+}
+
+// sparkBlocks are the levels of a sparkline, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline returns a block per value, scaled between the lowest level for zero (or
+// less) and the highest for the largest value.
+func sparkline(values []int) string {
+	largest := 0
+	for _, v := range values {
+		largest = max(largest, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if v > 0 && largest > 0 {
+			level = int(int64(v) * int64(len(sparkBlocks)-1) / int64(largest))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// unionDays returns every date any author committed on.
+func unionDays(days map[string]map[string]bool) map[string]bool {
+	union := make(map[string]bool)
+	for _, authorDays := range days {
+		for day := range authorDays {
+			union[day] = true
+		}
+	}
+	return union
+}
+
+// averageLines formats the insertions per commit of stats, "0.0" without commits.
+func averageLines(stats ChangesStats) string {
+	if stats.Commits == 0 {
+		return "0.0"
+	}
+	return fmt.Sprintf("%.1f", float64(stats.Insertions)/float64(stats.Commits))
+}
+
+// percent formats part as a share of total, "0.0%" when total is zero.
+func percent(part, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(part)*100/float64(total))
+}
+
+// printLanguages prints the insertions and deletions of each author per file extension,
+// authors in the order of the developer table and languages with the most insertions
+// first.
+func printLanguages(w io.Writer, globalStats GlobalStats, order authorOrder) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	type authorStats struct {
+		Author string
+		ChangesStats
+	}
+	var authors []authorStats
+	for author, months := range globalStats.Stats {
+		var total ChangesStats
+		for _, stats := range months {
+			total.Insertions += stats.Insertions
+			total.Deletions += stats.Deletions
+		}
+		authors = append(authors, authorStats{author, total})
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		return order.less(authors[i].Author, authors[i].ChangesStats, authors[j].Author, authors[j].ChangesStats)
+	})
+
+	fmt.Fprintf(w, "\n%sLines by language:%s\n", blue, reset)
+	for _, author := range authors {
+		languages := globalStats.Languages[author.Author]
+		var names []string
+		for language := range languages {
+			names = append(names, language)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if languages[names[i]].Insertions != languages[names[j]].Insertions {
+				return languages[names[i]].Insertions > languages[names[j]].Insertions
+			}
+			return names[i] < names[j]
+		})
+
+		parts := make([]string, len(names))
+		for i, language := range names {
+			parts[i] = fmt.Sprintf("%s: +%d -%d", language, languages[language].Insertions, languages[language].Deletions)
+		}
+		fmt.Fprintf(w, "  %s  %s\n", author.Author, strings.Join(parts, ", "))
+	}
+}
+
+// printRepoMatrix prints an author × repository table of the sort column's figure for
+// each author in each repository, with the author's total across repositories last.
+// With teams the authors are teams.
+func printRepoMatrix(w io.Writer, globalStats GlobalStats, order authorOrder, style borderStyle, teams bool) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	var repos []string
+	for name := range globalStats.Repos {
+		repos = append(repos, name)
+	}
+	sort.Strings(repos)
+
+	totals := make(map[string]ChangesStats)
+	for author, months := range globalStats.Stats {
+		totals[author] = sumMonths(months)
+	}
+	authors := make([]string, 0, len(totals))
+	for author := range totals {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		return order.less(authors[i], totals[authors[i]], authors[j], totals[authors[j]])
+	})
+
+	title := "Net lines"
+	switch order.column {
+	case "insertions":
+		title = "Insertions"
+	case "deletions":
+		title = "Deletions"
+	case "commits":
+		title = "Commits"
+	}
+	group, column := "developer", "Author"
+	if teams {
+		group, column = "team", "Team"
+	}
+	fmt.Fprintf(w, "\n%s%s by %s and repository:%s\n", blue, title, group, reset)
+	t := table{header: append(append([]string{column}, repos...), "Total")}
+	t.rightAlign = make([]bool, len(t.header))
+	for i := 1; i < len(t.header); i++ {
+		t.rightAlign[i] = true
+	}
+	for _, author := range authors {
+		row := []string{author}
+		for _, repo := range repos {
+			months, ok := globalStats.Repos[repo].Stats[author]
+			if !ok {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, strconv.Itoa(sortValue(sumMonths(months), order.column)))
+		}
+		t.addRow(append(row, strconv.Itoa(sortValue(totals[author], order.column)))...)
+	}
+	t.render(w, style)
+}
+
+// sumMonths returns the stats of months added up.
+func sumMonths(months map[string]ChangesStats) ChangesStats {
+	var total ChangesStats
+	for _, stats := range months {
+		total.Insertions += stats.Insertions
+		total.Deletions += stats.Deletions
+		total.Commits += stats.Commits
+	}
+	return total
+}
+
+// printChurn prints the added and churned lines of each author, highest churn first,
+// followed by the totals of each repository when Repos is set.
+func printChurn(w io.Writer, globalStats GlobalStats, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	row := func(t *table, label string, churn Churn) {
+		t.addRow(label, strconv.Itoa(churn.Added), strconv.Itoa(churn.Churned), strconv.FormatFloat(churn.Percent(), 'f', 1, 64)+"%")
+	}
+	byChurn := func(churns map[string]Churn) []string {
+		var keys []string
+		for key := range churns {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if pi, pj := churns[keys[i]].Percent(), churns[keys[j]].Percent(); pi != pj {
+				return pi > pj
+			}
+			return keys[i] < keys[j]
+		})
+		return keys
+	}
+
+	fmt.Fprintf(w, "\n%sChurn (added lines deleted again shortly after):%s\n", blue, reset)
+	t := table{header: []string{"Author", "Added", "Churned", "Churn %"}, rightAlign: []bool{false, true, true, true}}
+	var total Churn
+	for _, author := range byChurn(globalStats.Churn) {
+		row(&t, author, globalStats.Churn[author])
+		total.Added += globalStats.Churn[author].Added
+		total.Churned += globalStats.Churn[author].Churned
+	}
+	t.footer = []string{"Total", strconv.Itoa(total.Added), strconv.Itoa(total.Churned), strconv.FormatFloat(total.Percent(), 'f', 1, 64) + "%"}
+	t.render(w, style)
+
+	if globalStats.Repos == nil {
+		return
+	}
+	repos := make(map[string]Churn)
+	for name, repo := range globalStats.Repos {
+		var sum Churn
+		for _, churn := range repo.Churn {
+			sum.Added += churn.Added
+			sum.Churned += churn.Churned
+		}
+		repos[name] = sum
+	}
+	fmt.Fprintf(w, "\n%sChurn by repository:%s\n", blue, reset)
+	t = table{header: []string{"Repository", "Added", "Churned", "Churn %"}, rightAlign: []bool{false, true, true, true}}
+	for _, name := range byChurn(repos) {
+		row(&t, name, repos[name])
+	}
+	t.render(w, style)
+}
+
+// printRolling prints the raw and rolling average insertions per month, in total and per author.
+func printRolling(w io.Writer, globalStats GlobalStats, n int, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	months := analyzedMonths(globalStats)
+	if len(months) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%sInsertions with %d-month rolling average:%s\n", blue, n, reset)
+	totals := insertionSeries(globalStats, "", months)
+	totalAverages := rollingAverage(totals, n)
+	totalTable := table{header: []string{"Month", "Insertions", "Rolling avg"}, rightAlign: []bool{false, true, true}}
+	for i, month := range months {
+		totalTable.addRow(month, strconv.Itoa(totals[i]), strconv.FormatFloat(totalAverages[i], 'f', 1, 64))
+	}
+	totalTable.render(w, style)
+
+	var authors []string
+	for author := range globalStats.Stats {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+
+	fmt.Fprintf(w, "%sPer developer:%s\n", blue, reset)
+	authorTable := table{header: []string{"Author", "Month", "Insertions", "Rolling avg"}, rightAlign: []bool{false, false, true, true}}
+	for _, author := range authors {
+		series := insertionSeries(globalStats, author, months)
+		averages := rollingAverage(series, n)
+		for i, month := range months {
+			authorTable.addRow(author, month, strconv.Itoa(series[i]), strconv.FormatFloat(averages[i], 'f', 1, 64))
+		}
+	}
+	authorTable.render(w, style)
+}
+
+// printPathStats prints the focused -path-stats report: commits, insertions, deletions
+// and net lines per author for each month, then per-author totals.
+func printPathStats(w io.Writer, globalStats GlobalStats, path string) {
+	green := "\033[32m"
+	red := "\033[31m"
+	yellow := "\033[33m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	fmt.Fprintf(w, "%sHistory of %s%s\n", blue, path, reset)
+	if len(globalStats.Stats) == 0 {
+		fmt.Fprintf(w, "  No changes in the analyzed window\n")
+		return
+	}
+
+	monthsOrdered := sortedMonths(globalStats)
+
+	type authorStats struct {
+		Author string
+		ChangesStats
+	}
+	sortAuthors := func(list []authorStats) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Commits != list[j].Commits {
+				return list[i].Commits > list[j].Commits
+			}
+			return list[i].Author < list[j].Author
+		})
+	}
+	printRow := func(stats authorStats) {
+		fmt.Fprintf(w, "  %-30s %5d commits %s%6s%s %s%6s%s %6s net\n", stats.Author, stats.Commits,
+			green, "+"+strconv.Itoa(stats.Insertions), reset, red, "-"+strconv.Itoa(stats.Deletions), reset,
+			fmt.Sprintf("%+d", stats.Insertions-stats.Deletions))
+	}
+
+	totals := make(map[string]ChangesStats)
+	for _, month := range monthsOrdered {
+		fmt.Fprintf(w, "-----------------------------\n")
+		fmt.Fprintf(w, "%s%s%s\n", yellow, month, reset)
+
+		var monthStats []authorStats
+		for author, monthsStats := range globalStats.Stats {
+			if stats, exists := monthsStats[month]; exists {
+				monthStats = append(monthStats, authorStats{author, stats})
+				total := totals[author]
+				total.Insertions += stats.Insertions
+				total.Deletions += stats.Deletions
+				total.Commits += stats.Commits
+				totals[author] = total
+			}
+		}
+		sortAuthors(monthStats)
+		for _, stats := range monthStats {
+			printRow(stats)
+		}
+	}
+
+	var sortedAuthors []authorStats
+	for author, stats := range totals {
+		sortedAuthors = append(sortedAuthors, authorStats{author, stats})
+	}
+	sortAuthors(sortedAuthors)
+
+	fmt.Fprintf(w, "\n%sChanges to %s by developer:%s\n", blue, path, reset)
+	for _, stats := range sortedAuthors {
+		printRow(stats)
+	}
+	fmt.Fprintf(w, "%s-----------------------------%s\n", blue, reset)
+}
+
+func printPullRequests(w io.Writer, globalStats GlobalStats) {
+	green := "\033[32m"
+	red := "\033[31m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	fmt.Fprintf(w, "\n%sPull requests:%s\n", blue, reset)
+	if len(globalStats.PullRequests) == 0 {
+		fmt.Fprintf(w, "  No pull requests found (no GitHub-style merge commit messages)\n")
+		return
+	}
+
+	prs := append([]PullRequest(nil), globalStats.PullRequests...)
+	sort.Slice(prs, func(i, j int) bool {
+		if prs[i].Repo != prs[j].Repo {
+			return prs[i].Repo < prs[j].Repo
+		}
+		return prs[i].Number > prs[j].Number
+	})
+
+	for _, pr := range prs {
+		fmt.Fprintf(w, "  %-30s %-30s %s%6s%s %s%6s%s\n", pr.Repo+"#"+strconv.Itoa(pr.Number), pr.Author,
+			green, "+"+strconv.Itoa(pr.Insertions), reset, red, "-"+strconv.Itoa(pr.Deletions), reset)
+	}
+	fmt.Fprintf(w, "%s-----------------------------%s\n", blue, reset)
+}
+
+// printOutliers prints the commits above the line limit, largest first, and whether
+// they were left out or capped.
+func printOutliers(w io.Writer, globalStats GlobalStats, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	outliers := append([]OutlierCommit(nil), globalStats.Outliers...)
+	sort.Slice(outliers, func(i, j int) bool {
+		if li, lj := outliers[i].Insertions+outliers[i].Deletions, outliers[j].Insertions+outliers[j].Deletions; li != lj {
+			return li > lj
+		}
+		return outliers[i].Hash < outliers[j].Hash
+	})
+
+	fmt.Fprintf(w, "\n%sOutlier commits (above the lines per commit limit):%s\n", blue, reset)
+	t := table{
+		header:     []string{"Commit", "Repository", "Author", "Date", "Insertions", "Deletions", "Counted"},
+		rightAlign: []bool{false, false, false, false, true, true, false},
+	}
+	for _, outlier := range outliers {
+		counted := "no"
+		if outlier.Capped {
+			counted = "capped"
+		}
+		t.addRow(outlier.Hash[:min(len(outlier.Hash), 10)], outlier.Repo, outlier.Author, outlier.Date.Format("2006-01-02"),
+			strconv.Itoa(outlier.Insertions), strconv.Itoa(outlier.Deletions), counted)
+	}
+	t.render(w, style)
+}
+
+// printBreakdown prints the changes per key of breakdown, a map of the changes per
+// author and key such as Tickets, most net lines first, with the authors of each.
+func printBreakdown(w io.Writer, breakdown map[string]map[string]ChangesStats, title, column, empty string, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	totals := make(map[string]ChangesStats)
+	authors := make(map[string]map[string]ChangesStats) // Per key
+	for author, changes := range breakdown {
+		for key, stats := range changes {
+			totals[key] = addStats(totals[key], stats)
+			if authors[key] == nil {
+				authors[key] = make(map[string]ChangesStats)
+			}
+			authors[key][author] = stats
+		}
+	}
+
+	fmt.Fprintf(w, "\n%s%s%s\n", blue, title, reset)
+	if len(totals) == 0 {
+		fmt.Fprintf(w, "  %s\n", empty)
+		return
+	}
+	keys := rankedAuthors(totals, authorOrder{"net", false}, func(stats ChangesStats) int { return stats.Commits })
+	t := table{
+		header:     []string{column, "Commits", "Insertions", "Deletions", "Net", "Authors"},
+		rightAlign: []bool{false, true, true, true, true, false},
+	}
+	for _, key := range keys {
+		stats := totals[key]
+		names := make([]string, 0, len(authors[key]))
+		for author := range authors[key] {
+			names = append(names, author)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return authorOrder{"net", false}.less(names[i], authors[key][names[i]], names[j], authors[key][names[j]])
+		})
+		for i, author := range names {
+			names[i] = fmt.Sprintf("%s (%+d)", author, authors[key][author].Insertions-authors[key][author].Deletions)
+		}
+		t.addRow(key, strconv.Itoa(stats.Commits), strconv.Itoa(stats.Insertions), strconv.Itoa(stats.Deletions),
+			strconv.Itoa(stats.Insertions-stats.Deletions), strings.Join(names, ", "))
+	}
+	t.render(w, style)
+}
+
+func printActivityGap(w io.Writer, globalStats GlobalStats, gapDays int, now time.Time) {
+	red := "\033[31m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	if len(globalStats.LastCommit) == 0 {
+		return
+	}
+
+	type authorGap struct {
+		Author     string
+		LastCommit time.Time
+		Days       int
+	}
+	var gaps []authorGap
+	for author, last := range globalStats.LastCommit {
+		days := int(now.Sub(last).Hours() / 24)
+		gaps = append(gaps, authorGap{Author: author, LastCommit: last, Days: days})
+	}
+
+	// Most inactive authors first
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Days != gaps[j].Days {
+			return gaps[i].Days > gaps[j].Days
+		}
+		return gaps[i].Author < gaps[j].Author
+	})
+
+	fmt.Fprintf(w, "\n%sDays since last commit:%s\n", blue, reset)
+	for _, gap := range gaps {
+		if gap.Days > gapDays {
+			fmt.Fprintf(w, "  %-30s %s%5d%s days (last %s) inactive\n", gap.Author, red, gap.Days, reset,
+				gap.LastCommit.Format("2006-01-02"))
+		} else {
+			fmt.Fprintf(w, "  %-30s %5d days (last %s)\n", gap.Author, gap.Days,
+				gap.LastCommit.Format("2006-01-02"))
+		}
+	}
+	fmt.Fprintf(w, "%s-----------------------------%s\n", blue, reset)
+}
+
+// printTenure prints the first and last active day of each author in the analyzed
+// history, earliest first. Authors whose first changes fall in the last period are
+// flagged new, those without a commit in the months before now inactive.
+func printTenure(w io.Writer, globalStats GlobalStats, inactiveMonths int, now time.Time, style borderStyle) {
+	blue := "\033[94m"
+	green := "\033[32m"
+	red := "\033[31m"
+	reset := "\033[0m"
+
+	months := sortedMonths(globalStats)
+	type tenure struct {
+		Author      string
+		First, Last string
+		New         bool
+	}
+	var tenures []tenure
+	for author, days := range globalStats.ActiveDays {
+		if len(days) == 0 {
+			continue
+		}
+		t := tenure{Author: author}
+		for day := range days {
+			if t.First == "" || day < t.First {
+				t.First = day
+			}
+			t.Last = max(t.Last, day)
+		}
+		// Months is ordered, so the first period with changes is the author's first
+		if len(months) > 1 {
+			for _, month := range months {
+				if _, ok := globalStats.Stats[author][month]; ok {
+					t.New = month == months[len(months)-1]
+					break
+				}
+			}
+		}
+		tenures = append(tenures, t)
+	}
+	sort.Slice(tenures, func(i, j int) bool {
+		if tenures[i].First != tenures[j].First {
+			return tenures[i].First < tenures[j].First
+		}
+		return tenures[i].Author < tenures[j].Author
+	})
+
+	cutoff := now.AddDate(0, -inactiveMonths, 0).Format("2006-01-02")
+	fmt.Fprintf(w, "\n%sContributor tenure:%s\n", blue, reset)
+	t := table{
+		header:     []string{"Author", "First commit", "Last commit", "Tenure (days)", "Status"},
+		rightAlign: []bool{false, false, false, true, false},
+	}
+	newAuthors, inactive := 0, 0
+	for _, tenure := range tenures {
+		first, _ := time.Parse("2006-01-02", tenure.First)
+		last, _ := time.Parse("2006-01-02", tenure.Last)
+		status := ""
+		switch {
+		case tenure.Last < cutoff:
+			status = fmt.Sprintf("%sinactive for %d+ months%s", red, inactiveMonths, reset)
+			inactive++
+		case tenure.New:
+			status = green + "new in " + periodKey(months[len(months)-1]) + reset
+			newAuthors++
+		}
+		t.addRow(tenure.Author, tenure.First, tenure.Last, strconv.Itoa(int(last.Sub(first).Hours()/24)+1), status)
+	}
+	t.render(w, style)
+	fmt.Fprintf(w, "%d new in the last period, %d inactive for %d months or more\n", newAuthors, inactive, inactiveMonths)
+}
+
+func printTags(w io.Writer, globalStats GlobalStats) {
+	green := "\033[32m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	type kv struct {
+		Author string
+		Tags   int
+	}
+	var sortedTaggers []kv
+	for tagger, tags := range globalStats.Tags {
+		sortedTaggers = append(sortedTaggers, kv{tagger, tags})
+	}
+	sort.Slice(sortedTaggers, func(i, j int) bool {
+		if sortedTaggers[i].Tags != sortedTaggers[j].Tags {
+			return sortedTaggers[i].Tags > sortedTaggers[j].Tags
+		}
+		return sortedTaggers[i].Author < sortedTaggers[j].Author
+	})
+
+	fmt.Fprintf(w, "\n%sTags by person:%s\n", blue, reset)
+	for _, kv := range sortedTaggers {
+		fmt.Fprintf(w, "  %-30s %s%5d%s tags\n", kv.Author, green, kv.Tags, reset)
+	}
+	fmt.Fprintf(w, "%s-----------------------------%s\n", blue, reset)
+}
+
+// printBinary prints the number of binary files changed per person, which add no lines.
+func printBinary(w io.Writer, globalStats GlobalStats) {
+	green := "\033[32m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	type kv struct {
+		Author string
+		Files  int
+	}
+	var sortedAuthors []kv
+	for author, files := range globalStats.Binary {
+		sortedAuthors = append(sortedAuthors, kv{author, files})
+	}
+	sort.Slice(sortedAuthors, func(i, j int) bool {
+		if sortedAuthors[i].Files != sortedAuthors[j].Files {
+			return sortedAuthors[i].Files > sortedAuthors[j].Files
+		}
+		return sortedAuthors[i].Author < sortedAuthors[j].Author
+	})
+
+	fmt.Fprintf(w, "\n%sBinary files changed by person:%s\n", blue, reset)
+	for _, kv := range sortedAuthors {
+		fmt.Fprintf(w, "  %-30s %s%5d%s files\n", kv.Author, green, kv.Files, reset)
+	}
+	fmt.Fprintf(w, "%s-----------------------------%s\n", blue, reset)
+}
+
+// printChart renders total insertions per month as horizontal bars scaled so the
+// largest month fills the available width.
+func printChart(w io.Writer, globalStats GlobalStats, width int) {
+	green := "\033[32m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	monthTotals := make(map[string]int)
+	for _, months := range globalStats.Stats {
+		for month, stats := range months {
+			monthTotals[month] += stats.Insertions
+		}
+	}
+
+	fmt.Fprintf(w, "\n%sInsertions per month:%s\n", blue, reset)
+	if len(monthTotals) < 2 {
+		fmt.Fprintf(w, "  Not enough months to chart a trend\n")
+		fmt.Fprintf(w, "%s-----------------------------%s\n", blue, reset)
+		return
+	}
+
+	var monthsOrdered []string
+	maxTotal := 0
+	labelWidth := 0
+	for month, total := range monthTotals {
+		monthsOrdered = append(monthsOrdered, month)
+		if total > maxTotal {
+			maxTotal = total
+		}
+		if len(month) > labelWidth {
+			labelWidth = len(month)
+		}
+	}
+	sort.Strings(monthsOrdered)
+
+	valueWidth := len(strconv.Itoa(maxTotal))
+	barWidth := width - labelWidth - valueWidth - 6
+	if barWidth < 10 {
+		barWidth = 10
+	}
+
+	for _, month := range monthsOrdered {
+		total := monthTotals[month]
+		bar := 0
+		if maxTotal > 0 {
+			bar = int(int64(total) * int64(barWidth) / int64(maxTotal))
+		}
+		fmt.Fprintf(w, "  %-*s %s%s%s %*d\n", labelWidth, month, green, strings.Repeat("#", bar), reset,
+			barWidth-bar+valueWidth, total)
+	}
+	fmt.Fprintf(w, "%s-----------------------------%s\n", blue, reset)
+}
@@ -0,0 +1,279 @@
+package gitstats
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RepoName names the repository in dir by its path relative to baseDir, or by its own
+// directory name when dir is baseDir itself. A bare repository is named without its
+// .git suffix and the .git directory of a clone after the clone.
+func RepoName(baseDir, dir string) string {
+	if filepath.Base(dir) == ".git" {
+		dir = filepath.Dir(dir)
+	}
+	name := filepath.Base(dir)
+	if rel, err := filepath.Rel(baseDir, dir); err == nil && rel != "." {
+		name = filepath.ToSlash(rel)
+	} else if abs, err := filepath.Abs(dir); err == nil {
+		name = filepath.Base(abs)
+	}
+	if trimmed := strings.TrimSuffix(name, ".git"); trimmed != "" && !strings.HasSuffix(trimmed, "/") {
+		return trimmed
+	}
+	return name
+}
+
+// isGitDir reports whether dir is a git directory rather than a working tree: a bare
+// repository such as a mirror's repo.git, or the .git directory of a clone.
+func isGitDir(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return false
+	}
+	for _, sub := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultAutoExtSkip lists lockfiles, generated and binary types ignored by -auto-ext.
+const DefaultAutoExtSkip = "lock,sum,min.js,min.css,map,pb.go,svg,png,jpg,jpeg,gif,ico,pdf,zip,jar," +
+	"package-lock.json,pnpm-lock.yaml"
+
+// detectExtensions returns the extensions that each make up at least 5% of the files
+// tracked in dir, most frequent first and at most 8. Files matching skip (an
+// extension or a whole file name) and files without an extension are not counted.
+func (c *collector) detectExtensions(dir string, skip []string) ([]string, error) {
+	args := []string{"--no-pager", "-C", dir, "ls-files"}
+	if isGitDir(dir) {
+		// A git directory has no index: list the files of HEAD instead
+		args = []string{"--no-pager", "-C", dir, "ls-tree", "-r", "--name-only", "HEAD"}
+	}
+
+	output, err := c.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	for _, file := range strings.Split(string(output), "\n") {
+		base := path.Base(file)
+		ext := strings.TrimPrefix(path.Ext(base), ".")
+		if file == "" || ext == "" || ext == base[1:] {
+			continue
+		}
+
+		skipped := false
+		for _, pattern := range skip {
+			pattern = strings.TrimPrefix(strings.TrimSpace(pattern), ".")
+			if pattern != "" && (base == pattern || strings.HasSuffix(base, "."+pattern)) {
+				skipped = true
+				break
+			}
+		}
+		if skipped {
+			continue
+		}
+
+		counts[ext]++
+		total++
+	}
+
+	var exts []string
+	for ext, count := range counts {
+		if count*20 >= total {
+			exts = append(exts, ext)
+		}
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if counts[exts[i]] != counts[exts[j]] {
+			return counts[exts[i]] > counts[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+	if len(exts) > 8 {
+		exts = exts[:8]
+	}
+	return exts, nil
+}
+
+// currentBranch returns the checked-out branch of dir, or "HEAD" when detached.
+func (c *collector) currentBranch(dir string) string {
+	output, err := c.opts.Git.Run("-C", dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// repoDirs returns the directories to analyze: baseDir itself, or with all set, every
+// git repository below it up to depth levels deep (negative for no limit). Directories
+// containing a .git entry and bare repositories are repositories and are not descended
+// into; other directories are skipped silently. Directories whose name or slash-separated path below baseDir
+// matches one of the skip globs are neither analyzed nor descended into.
+func repoDirs(baseDir string, all bool, depth int, skip []string, debug *slog.Logger) ([]string, error) {
+	if !all {
+		return []string{baseDir}, nil
+	}
+
+	skipped := func(dirPath, name string) bool {
+		rel, err := filepath.Rel(baseDir, dirPath)
+		if err != nil {
+			rel = name
+		}
+		for _, pattern := range skip {
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+			if matched, _ := path.Match(pattern, filepath.ToSlash(rel)); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	var dirs []string
+	var walk func(dir string, level int) error
+	walk = func(dir string, level int) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			dirPath := filepath.Join(dir, entry.Name())
+			if skipped(dirPath, entry.Name()) {
+				debug.Debug("skipping directory", "dir", dirPath)
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(dirPath, ".git")); err == nil || isGitDir(dirPath) {
+				dirs = append(dirs, dirPath)
+			} else if depth < 0 || level < depth {
+				if err := walk(dirPath, level+1); err != nil {
+					debug.Debug("skipping unreadable directory", "dir", dirPath, "err", err)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(baseDir, 1); err != nil {
+		return nil, err
+	}
+	return dirs, nil
+}
+
+// withSubmodules returns dirs with the initialized submodules of each repository,
+// recursively, following their parent. The top-level repository of each submodule is
+// recorded in parents; failures to list them are returned as warnings.
+func (c *collector) withSubmodules(dirs []string) ([]string, []string) {
+	var all, warnings []string
+	var add func(dir, top string)
+	add = func(dir, top string) {
+		all = append(all, dir)
+		if top != dir {
+			c.parents[dir] = top
+		}
+		if isGitDir(dir) {
+			// A bare repository checks out no submodules
+			return
+		}
+		subs, err := c.submodules(dir)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: failed to list submodules: %s", dir, err))
+			return
+		}
+		for _, sub := range subs {
+			add(sub, top)
+		}
+	}
+	for _, dir := range dirs {
+		add(dir, dir)
+	}
+	return all, warnings
+}
+
+// submodules returns the directories of the initialized submodules of dir, the gitlink
+// entries ("160000 <hash> <stage>\t<path>") of its index with a checkout.
+func (c *collector) submodules(dir string) ([]string, error) {
+	output, err := c.git(append(c.gitArgs(dir), "ls-files", "--stage", "-z")...)
+	if err != nil {
+		return nil, err
+	}
+	var subs []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		info, file, ok := strings.Cut(entry, "\t")
+		if !ok || !strings.HasPrefix(info, "160000 ") {
+			continue
+		}
+		sub := filepath.Join(dir, filepath.FromSlash(file))
+		if _, err := os.Stat(filepath.Join(sub, ".git")); err == nil {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+// repoOf returns the repository the stats of dir are counted in: the top-level
+// repository of a submodule with Submodules set to parent, or else dir itself.
+func (c *collector) repoOf(dir string) string {
+	if parent, ok := c.parents[dir]; ok && c.opts.Submodules == "parent" {
+		return parent
+	}
+	return dir
+}
+
+// processTags counts annotated tags created in [since, until) per tagger email.
+// Lightweight tags carry no tagger and are skipped.
+func (c *collector) processTags(globalStats *GlobalStats, dir string, since, until time.Time, pattern string) error {
+	args := []string{"--no-pager", "-C", dir, "for-each-ref",
+		"--format=%(taggeremail)%09%(creatordate:unix)%09%(taggername)%09%(refname:short)",
+		"refs/tags",
+	}
+
+	output, err := c.git(args...)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 4 || fields[0] == "" {
+			continue
+		}
+
+		if pattern != "" {
+			if matched, _ := path.Match(pattern, fields[3]); !matched {
+				continue
+			}
+		}
+
+		var unix int64
+		fmt.Sscanf(fields[1], "%d", &unix)
+		created := time.Unix(unix, 0)
+		if created.Before(since) || !created.Before(until) {
+			continue
+		}
+
+		tagger := strings.TrimSpace(strings.Trim(fields[0], "<>"))
+		if !c.opts.CaseSensitiveEmails {
+			tagger = strings.ToLower(tagger)
+		}
+		globalStats.Tags[tagger]++
+		if _, exists := globalStats.Names[tagger]; !exists {
+			globalStats.Names[tagger] = fields[2]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Kinds of ReviewEvent.
+const (
+	ReviewOpened   = "opened"   // The author opened a pull request
+	ReviewMerged   = "merged"   // A pull request of the author was merged
+	ReviewGiven    = "review"   // The author reviewed the pull request of someone else
+	ReviewApproved = "approval" // The review approved the pull request
+	ReviewComment  = "comment"  // The author commented on the pull request of someone else
+)
+
+// ReviewEvent is something a person did on the pull requests of a repository, for
+// Options.Reviews.
+type ReviewEvent struct {
+	Kind   string    // ReviewOpened, ReviewMerged, ReviewGiven, ReviewApproved or ReviewComment
+	Author string    // Login on the hosting service, as emails are not shown there
+	Time   time.Time // When it happened
+}
+
+// ReviewSource is a Git that can also list the pull request activity of a repository,
+// such as GitHubGit and GitLabGit.
+type ReviewSource interface {
+	// ReviewEvents returns the events of repo from since until until
+	ReviewEvents(repo string, since, until time.Time) ([]ReviewEvent, error)
+}
+
+// ReviewStats are the code review figures of a person: the pull requests they opened
+// and got merged, and the reviews, approvals and comments they gave on those of others.
+type ReviewStats struct {
+	Opened    int `json:"opened"`
+	Merged    int `json:"merged"`
+	Reviews   int `json:"reviews"`
+	Approvals int `json:"approvals"`
+	Comments  int `json:"comments"`
+}
+
+// add counts an event of kind.
+func (s ReviewStats) add(kind string) ReviewStats {
+	switch kind {
+	case ReviewOpened:
+		s.Opened++
+	case ReviewMerged:
+		s.Merged++
+	case ReviewGiven:
+		s.Reviews++
+	case ReviewApproved:
+		s.Approvals++
+	case ReviewComment:
+		s.Comments++
+	}
+	return s
+}
+
+// addReviewStats returns the sum of a and b.
+func addReviewStats(a, b ReviewStats) ReviewStats {
+	return ReviewStats{
+		Opened: a.Opened + b.Opened, Merged: a.Merged + b.Merged, Reviews: a.Reviews + b.Reviews,
+		Approvals: a.Approvals + b.Approvals, Comments: a.Comments + b.Comments,
+	}
+}
+
+// addReview counts an event of kind by author in period.
+func (gb *GlobalStats) addReview(author, period, kind string) {
+	if gb.Reviews == nil {
+		gb.Reviews = make(map[string]map[string]ReviewStats)
+	}
+	if gb.Reviews[author] == nil {
+		gb.Reviews[author] = make(map[string]ReviewStats)
+	}
+	gb.Reviews[author][period] = gb.Reviews[author][period].add(kind)
+}
+
+// reviewRow returns the cells of stats after label.
+func reviewRow(label string, stats ReviewStats) []string {
+	return []string{label, strconv.Itoa(stats.Opened), strconv.Itoa(stats.Merged), strconv.Itoa(stats.Reviews),
+		strconv.Itoa(stats.Approvals), strconv.Itoa(stats.Comments)}
+}
+
+// printReviews prints the review figures of each person over all periods, most reviews
+// first, and of everyone per month when several were analyzed.
+func printReviews(w io.Writer, globalStats GlobalStats, style borderStyle) {
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	fmt.Fprintf(w, "\n%sCode reviews (pull requests opened and merged, reviews, approvals and comments given):%s\n", blue, reset)
+	totals := make(map[string]ReviewStats)
+	months := make(map[string]ReviewStats)
+	var total ReviewStats
+	for person, periods := range globalStats.Reviews {
+		for period, stats := range periods {
+			totals[person] = addReviewStats(totals[person], stats)
+			months[period] = addReviewStats(months[period], stats)
+			total = addReviewStats(total, stats)
+		}
+	}
+	if len(totals) == 0 {
+		fmt.Fprintf(w, "  No pull request activity\n")
+		return
+	}
+
+	people := make([]string, 0, len(totals))
+	for person := range totals {
+		people = append(people, person)
+	}
+	sort.Slice(people, func(i, j int) bool {
+		a, b := totals[people[i]], totals[people[j]]
+		if a.Reviews != b.Reviews {
+			return a.Reviews > b.Reviews
+		}
+		if a.Opened != b.Opened {
+			return a.Opened > b.Opened
+		}
+		return people[i] < people[j]
+	})
+	header := []string{"Login", "Opened", "Merged", "Reviews", "Approvals", "Comments"}
+	rightAlign := []bool{false, true, true, true, true, true}
+	t := table{header: header, rightAlign: rightAlign}
+	for _, person := range people {
+		t.addRow(reviewRow(person, totals[person])...)
+	}
+	t.footer = reviewRow("Total", total)
+	t.render(w, style)
+
+	analyzed := analyzedMonths(globalStats)
+	if len(analyzed) < 2 {
+		return
+	}
+	fmt.Fprintf(w, "\n%sCode reviews per month:%s\n", blue, reset)
+	t = table{header: append([]string{"Month"}, header[1:]...), rightAlign: rightAlign}
+	for _, month := range analyzed {
+		t.addRow(reviewRow(periodKey(month), months[month])...)
+	}
+	t.render(w, style)
+}
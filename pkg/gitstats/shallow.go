@@ -0,0 +1,80 @@
+package gitstats
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkShallow returns the date the history of dir starts when it is a shallow clone,
+// and a warning when that is after the start of the analyzed window, so its stats are
+// likely truncated. With Unshallow, such a clone is first deepened to the window with
+// git fetch, or fully unshallowed when the window has no start.
+func (c *collector) checkShallow(dir string) (time.Time, string) {
+	start, err := c.historyStart(dir)
+	if err != nil || start.IsZero() || (!c.window.Since.IsZero() && !start.After(c.window.Since)) {
+		return start, ""
+	}
+
+	if c.opts.Unshallow {
+		args := append(c.gitArgs(dir), "fetch", "--quiet")
+		if c.window.Since.IsZero() {
+			args = append(args, "--unshallow")
+		} else {
+			args = append(args, "--shallow-since="+c.window.Since.Format("2006-01-02"))
+		}
+		if _, err := c.git(args...); err != nil {
+			return start, fmt.Sprintf("%s: failed to deepen the shallow clone: %s", dir, err)
+		}
+		c.progress.Printf("Deepened the shallow clone %s", dir)
+		// The first commit of the window may well be after its start
+		start, _ = c.historyStart(dir)
+		return start, ""
+	}
+
+	window := "all history"
+	if !c.window.Since.IsZero() {
+		window = "the window from " + c.window.Since.Format("2006-01-02")
+	}
+	return start, fmt.Sprintf("%s: shallow clone with history only from %s, the stats of %s may be truncated; use -unshallow to fetch the missing history",
+		dir, start.Format("2006-01-02"), window)
+}
+
+// historyStart returns the committer date of the newest shallow boundary commit of
+// dir, after which its history is complete, or the zero time for a full clone.
+func (c *collector) historyStart(dir string) (time.Time, error) {
+	output, err := c.git(append(c.gitArgs(dir), "rev-parse", "--is-shallow-repository")...)
+	if err != nil || strings.TrimSpace(string(output)) != "true" {
+		return time.Time{}, err
+	}
+	if output, err = c.git(append(c.gitArgs(dir), "rev-parse", "--git-path", "shallow")...); err != nil {
+		return time.Time{}, err
+	}
+	file := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return time.Time{}, err
+	}
+	boundary := strings.Fields(string(data))
+	if len(boundary) == 0 {
+		return time.Time{}, nil
+	}
+
+	output, err = c.git(append(append(c.gitArgs(dir), "show", "--no-patch", "--format=%ct"), boundary...)...)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var start time.Time
+	for _, line := range strings.Fields(string(output)) {
+		if unix, err := strconv.ParseInt(line, 10, 64); err == nil && time.Unix(unix, 0).After(start) {
+			start = time.Unix(unix, 0)
+		}
+	}
+	return start, nil
+}
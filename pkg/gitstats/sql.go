@@ -0,0 +1,90 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// sqlSchema creates the table of the sql format. A row is keyed by the repository,
+// author and period it counts, so loading a later run of the same period replaces it.
+const sqlSchema = `CREATE TABLE IF NOT EXISTS changes (
+  repo TEXT NOT NULL,
+  author TEXT NOT NULL,
+  period TEXT NOT NULL,
+  name TEXT NOT NULL DEFAULT '',
+  insertions INTEGER NOT NULL,
+  deletions INTEGER NOT NULL,
+  commits INTEGER NOT NULL,
+  PRIMARY KEY (repo, author, period)
+);
+`
+
+// periodKey returns the sortable key of a period label, the text in its leading
+// parentheses such as 2024-03 for "(2024-03) March 2024", or the label itself.
+func periodKey(label string) string {
+	if strings.HasPrefix(label, "(") {
+		if end := strings.Index(label, ")"); end > 0 {
+			return label[1:end]
+		}
+	}
+	return label
+}
+
+// sqlString quotes value as an SQL string literal.
+func sqlString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// printSQL writes an SQLite script upserting one row per repository, author and
+// period into the changes table, creating it on the first run. Rows are per repository
+// when Repos is set and under the repository "" otherwise. Periods without changes of
+// an author have no row, and rows of earlier runs are kept, so the table accumulates
+// the history of every run.
+func printSQL(w io.Writer, globalStats GlobalStats) error {
+	repos := globalStats.Repos
+	if repos == nil {
+		repos = map[string]*GlobalStats{"": &globalStats}
+	}
+	var names []string
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("BEGIN;\n")
+	b.WriteString(sqlSchema)
+	for _, name := range names {
+		stats := repos[name]
+		var authors []string
+		for author := range stats.Stats {
+			authors = append(authors, author)
+		}
+		sort.Strings(authors)
+
+		for _, author := range authors {
+			var months []string
+			for month := range stats.Stats[author] {
+				months = append(months, month)
+			}
+			sort.Strings(months)
+
+			for _, month := range months {
+				changes := stats.Stats[author][month]
+				fmt.Fprintf(&b, "INSERT INTO changes VALUES (%s, %s, %s, %s, %d, %d, %d)"+
+					" ON CONFLICT (repo, author, period) DO UPDATE SET name = excluded.name,"+
+					" insertions = excluded.insertions, deletions = excluded.deletions, commits = excluded.commits;\n",
+					sqlString(name), sqlString(author), sqlString(periodKey(month)), sqlString(stats.Names[author]),
+					changes.Insertions, changes.Deletions, changes.Commits)
+			}
+		}
+	}
+	b.WriteString("COMMIT;\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write SQL: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+// Package gitstats collects insertions, deletions and commits per author and month
+// from git repositories and renders them as text, JSON or delimited reports.
+//
+// Based on
+// git --no-pager log --pretty="%an" --shortstat --since="2024-03-01" --until="2024-03-31"
+package gitstats
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChangesStats are the changes of one author in one period.
+type ChangesStats struct {
+	Insertions int `json:"insertions"`
+	Deletions  int `json:"deletions"`
+	Commits    int `json:"commits"`
+}
+
+// GlobalStats are the collected stats, keyed by author email and then period label.
+type GlobalStats struct {
+	Stats        map[string]map[string]ChangesStats
+	LastCommit   map[string]time.Time // Most recent commit date per author
+	Names        map[string]string    // Display name per author email
+	Tags         map[string]int       // Annotated tags created per tagger email
+	Binary       map[string]int       // Binary files changed per author
+	PullRequests []PullRequest
+	Periods      map[string]bool                    // Every analyzed month, including months without changes
+	Languages    map[string]map[string]ChangesStats // Changes per author and file extension over all periods, with Options.ByLanguage
+	Churn        map[string]Churn                   // Added and soon deleted lines per author over all periods, with Options.ChurnDays
+	ActiveDays   map[string]map[string]bool         // Dates (YYYY-MM-DD) each author committed on over all periods
+	CommitSizes  map[string][]int                   // Lines changed by each commit of each author over all periods
+	Heatmap      map[string]Heatmap                 // Commits per author by weekday and hour, with Options.Heatmap
+	Tickets      map[string]map[string]ChangesStats // Changes per author and ticket ID over all periods, with Options.TicketPattern
+	Components   map[string]map[string]ChangesStats // Changes per author and directory over all periods, with Options.PathDepth or PathPrefixes
+	Owners       map[string]map[string]ChangesStats // Changes per author and CODEOWNERS owners over all periods, with Options.CodeOwners
+	Reviews      map[string]map[string]ReviewStats  // Pull request activity per login and period, with Options.Reviews
+	Outliers     []OutlierCommit                    // Commits above Options.MaxCommitLines, left out or capped
+	Duplicates   map[string]int                     // Commits skipped per repository name as another one counted them, with Options.Dedupe
+
+	// Metrics holds the values of Options.Metrics per author, period and metric name
+	Metrics map[string]map[string]map[string]int
+	// Files holds the paths changed per author and period, with Options.Breadth
+	Files map[string]map[string]map[string]bool
+
+	Repos    map[string]*GlobalStats // Stats per repository name, with Options.ByRepo
+	Squashes *GlobalStats            // Squash merges, with Options.SquashMerges "separate"
+	Merged   map[string][]string     // Emails merged per display name, with Options.MergeByName
+	Warnings []string                // Problems that skipped part of the analysis
+}
+
+// CommitSize is the mean and median lines changed, insertions plus deletions, per
+// commit.
+type CommitSize struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+}
+
+// newCommitSize returns the CommitSize of the lines changed by each of a non-empty list
+// of commits.
+func newCommitSize(sizes []int) CommitSize {
+	sorted := slices.Clone(sizes)
+	slices.Sort(sorted)
+	total := 0
+	for _, size := range sorted {
+		total += size
+	}
+	median := float64(sorted[len(sorted)/2])
+	if len(sorted)%2 == 0 {
+		median = float64(sorted[len(sorted)/2-1]+sorted[len(sorted)/2]) / 2
+	}
+	return CommitSize{Mean: float64(total) / float64(len(sorted)), Median: median}
+}
+
+// allCommitSizes returns the sizes of the commits of every author.
+func allCommitSizes(sizes map[string][]int) []int {
+	var all []int
+	for _, authorSizes := range sizes {
+		all = append(all, authorSizes...)
+	}
+	return all
+}
+
+// NewGlobalStats returns empty stats.
+func NewGlobalStats() *GlobalStats {
+	return &GlobalStats{
+		Stats:      make(map[string]map[string]ChangesStats),
+		LastCommit: make(map[string]time.Time),
+		Names:      make(map[string]string),
+		Tags:       make(map[string]int),
+		Binary:     make(map[string]int),
+		Periods:    make(map[string]bool),
+		ActiveDays: make(map[string]map[string]bool),
+	}
+}
+
+// Totals sums the changes of every author and month. It is computed from Stats rather
+// than kept as a running counter, so the grand total always matches the tables.
+func (gb *GlobalStats) Totals() ChangesStats {
+	var total ChangesStats
+	for _, months := range gb.Stats {
+		for _, stats := range months {
+			total.Insertions += stats.Insertions
+			total.Deletions += stats.Deletions
+			total.Commits += stats.Commits
+		}
+	}
+	return total
+}
+
+// Add accumulates the result of one git log pass under the given month.
+func (gb *GlobalStats) Add(result LogResult, monthStr string) {
+	gb.Periods[monthStr] = true
+	for author, last := range result.LastCommit {
+		if last.After(gb.LastCommit[author]) {
+			gb.LastCommit[author] = last
+		}
+	}
+	for author, name := range result.Names {
+		gb.Names[author] = name
+	}
+	for author, files := range result.Binary {
+		gb.Binary[author] += files
+	}
+	for author, days := range result.Days {
+		addDays(gb.ActiveDays, author, days)
+	}
+	if gb.CommitSizes == nil {
+		gb.CommitSizes = make(map[string][]int)
+	}
+	for author, sizes := range result.Sizes {
+		gb.CommitSizes[author] = append(gb.CommitSizes[author], sizes...)
+	}
+	for author, values := range result.Metrics {
+		gb.addMetrics(author, monthStr, values)
+	}
+	for author, files := range result.Files {
+		gb.addFiles(author, monthStr, files)
+	}
+	gb.Outliers = append(gb.Outliers, result.Outliers...)
+	if gb.Heatmap != nil {
+		for author, hours := range result.Hours {
+			addHeatmap(gb.Heatmap, author, hours)
+		}
+	}
+
+	for author, counts := range result.Stats {
+		if _, exists := gb.Stats[author]; !exists {
+			gb.Stats[author] = make(map[string]ChangesStats)
+		}
+		authorMonthStats := gb.Stats[author][monthStr]
+		authorMonthStats.Insertions += counts.Insertions
+		authorMonthStats.Deletions += counts.Deletions
+		authorMonthStats.Commits += counts.Commits
+		gb.Stats[author][monthStr] = authorMonthStats
+	}
+}
+
+// addLanguage accumulates the changes per author of one language.
+func (gb *GlobalStats) addLanguage(language string, stats map[string]ChangesStats) {
+	for author, counts := range stats {
+		if gb.Languages == nil {
+			gb.Languages = make(map[string]map[string]ChangesStats)
+		}
+		if _, exists := gb.Languages[author]; !exists {
+			gb.Languages[author] = make(map[string]ChangesStats)
+		}
+		addChanges(gb.Languages[author], map[string]ChangesStats{language: counts})
+	}
+}
+
+// MergeByName folds authors whose display names match exactly (case-insensitive, trimmed)
+// into a single entry keyed by the alphabetically first email. It returns the emails
+// merged under each name.
+func MergeByName(globalStats *GlobalStats) map[string][]string {
+	emailsByName := make(map[string][]string)
+	emails := make(map[string]bool)
+	for email := range globalStats.Stats {
+		emails[email] = true
+	}
+	for email := range globalStats.Tags {
+		emails[email] = true
+	}
+	for email := range emails {
+		name := strings.ToLower(strings.TrimSpace(globalStats.Names[email]))
+		if name == "" {
+			continue
+		}
+		emailsByName[name] = append(emailsByName[name], email)
+	}
+
+	merged := make(map[string][]string)
+	for _, emails := range emailsByName {
+		if len(emails) < 2 {
+			continue
+		}
+		sort.Strings(emails)
+		canonical := emails[0]
+		for _, email := range emails[1:] {
+			for month, stats := range globalStats.Stats[email] {
+				if _, exists := globalStats.Stats[canonical]; !exists {
+					globalStats.Stats[canonical] = make(map[string]ChangesStats)
+				}
+				monthStats := globalStats.Stats[canonical][month]
+				monthStats.Insertions += stats.Insertions
+				monthStats.Deletions += stats.Deletions
+				monthStats.Commits += stats.Commits
+				globalStats.Stats[canonical][month] = monthStats
+			}
+			delete(globalStats.Stats, email)
+
+			if last, ok := globalStats.LastCommit[email]; ok {
+				if last.After(globalStats.LastCommit[canonical]) {
+					globalStats.LastCommit[canonical] = last
+				}
+				delete(globalStats.LastCommit, email)
+			}
+			if days, ok := globalStats.ActiveDays[email]; ok {
+				addDays(globalStats.ActiveDays, canonical, days)
+				delete(globalStats.ActiveDays, email)
+			}
+			if sizes, ok := globalStats.CommitSizes[email]; ok {
+				globalStats.CommitSizes[canonical] = append(globalStats.CommitSizes[canonical], sizes...)
+				delete(globalStats.CommitSizes, email)
+			}
+			if hours, ok := globalStats.Heatmap[email]; ok {
+				addHeatmap(globalStats.Heatmap, canonical, hours)
+				delete(globalStats.Heatmap, email)
+			}
+		}
+		for _, email := range emails[1:] {
+			if languages, ok := globalStats.Languages[email]; ok {
+				if _, exists := globalStats.Languages[canonical]; !exists {
+					globalStats.Languages[canonical] = make(map[string]ChangesStats)
+				}
+				addChanges(globalStats.Languages[canonical], languages)
+				delete(globalStats.Languages, email)
+			}
+			if components, ok := globalStats.Components[email]; ok {
+				for component, stats := range components {
+					globalStats.addComponent(component, map[string]ChangesStats{canonical: stats})
+				}
+				delete(globalStats.Components, email)
+			}
+			if tickets, ok := globalStats.Tickets[email]; ok {
+				globalStats.addTickets(canonical, tickets)
+				delete(globalStats.Tickets, email)
+			}
+			if owners, ok := globalStats.Owners[email]; ok {
+				globalStats.addOwners(canonical, owners)
+				delete(globalStats.Owners, email)
+			}
+			if months, ok := globalStats.Metrics[email]; ok {
+				for month, values := range months {
+					globalStats.addMetrics(canonical, month, values)
+				}
+				delete(globalStats.Metrics, email)
+			}
+			if periods, ok := globalStats.Files[email]; ok {
+				for period, files := range periods {
+					globalStats.addFiles(canonical, period, files)
+				}
+				delete(globalStats.Files, email)
+			}
+		}
+		for _, email := range emails[1:] {
+			if tags, ok := globalStats.Tags[email]; ok {
+				globalStats.Tags[canonical] += tags
+				delete(globalStats.Tags, email)
+			}
+			if files, ok := globalStats.Binary[email]; ok {
+				globalStats.Binary[canonical] += files
+				delete(globalStats.Binary, email)
+			}
+		}
+		for i, outlier := range globalStats.Outliers {
+			if slices.Contains(emails[1:], outlier.Author) {
+				globalStats.Outliers[i].Author = canonical
+			}
+		}
+		merged[globalStats.Names[canonical]] = emails
+	}
+	return merged
+}
+
+// OthersAuthor is the author key that collects authors beyond a -json-max-authors cap.
+const OthersAuthor = "others"
+
+// CapAuthors returns a copy of globalStats keeping the top n authors by metric over the
+// whole window, with all other authors summed per month into OthersAuthor. Totals are
+// unchanged, so they still reconcile with the per-author rows.
+func CapAuthors(globalStats *GlobalStats, n int, metric func(ChangesStats) int) *GlobalStats {
+	if len(globalStats.Stats) <= n {
+		return globalStats
+	}
+
+	type kv struct {
+		Author string
+		Value  int
+	}
+	var ranked []kv
+	for author, months := range globalStats.Stats {
+		var total ChangesStats
+		for _, stats := range months {
+			total.Insertions += stats.Insertions
+			total.Deletions += stats.Deletions
+			total.Commits += stats.Commits
+		}
+		ranked = append(ranked, kv{author, metric(total)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Value != ranked[j].Value {
+			return ranked[i].Value > ranked[j].Value
+		}
+		return ranked[i].Author < ranked[j].Author
+	})
+
+	capped := *globalStats
+	capped.Stats = make(map[string]map[string]ChangesStats)
+	others := make(map[string]ChangesStats)
+	for i, kv := range ranked {
+		if i < n {
+			capped.Stats[kv.Author] = globalStats.Stats[kv.Author]
+			continue
+		}
+		addChanges(others, globalStats.Stats[kv.Author])
+	}
+	capped.Stats[OthersAuthor] = others
+
+	if globalStats.Languages != nil {
+		capped.Languages = make(map[string]map[string]ChangesStats)
+		otherLanguages := make(map[string]ChangesStats)
+		for i, kv := range ranked {
+			if i < n {
+				if languages, ok := globalStats.Languages[kv.Author]; ok {
+					capped.Languages[kv.Author] = languages
+				}
+				continue
+			}
+			addChanges(otherLanguages, globalStats.Languages[kv.Author])
+		}
+		capped.Languages[OthersAuthor] = otherLanguages
+	}
+	return &capped
+}
+
+// KeepPeriods returns a copy of globalStats, and of its Repos and Squashes, with only
+// the stats of the periods whose label is in labels. Figures that are not split by
+// period, such as Tags and Languages, are kept whole.
+func KeepPeriods(globalStats *GlobalStats, labels map[string]bool) *GlobalStats {
+	kept := *globalStats
+	kept.Stats = make(map[string]map[string]ChangesStats)
+	for author, months := range globalStats.Stats {
+		for month, stats := range months {
+			if !labels[month] {
+				continue
+			}
+			if kept.Stats[author] == nil {
+				kept.Stats[author] = make(map[string]ChangesStats)
+			}
+			kept.Stats[author][month] = stats
+		}
+	}
+	kept.Periods = make(map[string]bool)
+	for month := range globalStats.Periods {
+		if labels[month] {
+			kept.Periods[month] = true
+		}
+	}
+	if globalStats.Repos != nil {
+		kept.Repos = make(map[string]*GlobalStats)
+		for name, repo := range globalStats.Repos {
+			kept.Repos[name] = KeepPeriods(repo, labels)
+		}
+	}
+	if globalStats.Squashes != nil {
+		kept.Squashes = KeepPeriods(globalStats.Squashes, labels)
+	}
+	return &kept
+}
+
+// addDays adds days to the dates of author in into.
+func addDays(into map[string]map[string]bool, author string, days map[string]bool) {
+	if into[author] == nil {
+		into[author] = make(map[string]bool)
+	}
+	for day := range days {
+		into[author][day] = true
+	}
+}
+
+// addChanges adds the changes of from to into, key by key.
+func addChanges(into, from map[string]ChangesStats) {
+	for key, stats := range from {
+		sum := into[key]
+		sum.Insertions += stats.Insertions
+		sum.Deletions += stats.Deletions
+		sum.Commits += stats.Commits
+		into[key] = sum
+	}
+}
@@ -0,0 +1,63 @@
+package gitstats
+
+import "sort"
+
+// Summary holds the figures of a report ready for a text/template, such as the
+// message posted to a chat.
+type Summary struct {
+	Periods []SummaryPeriod // In chronological order
+	Total   SummaryPeriod   // Totals per developer over all periods
+}
+
+// SummaryPeriod is the changes of one period, or of all of them, per author.
+type SummaryPeriod struct {
+	Label   string
+	Authors []SummaryAuthor // Sorted like the text report
+	ChangesStats
+	Net int
+}
+
+// SummaryAuthor is the changes of one author in a SummaryPeriod.
+type SummaryAuthor struct {
+	Author string
+	Name   string // Display name, empty when unknown
+	ChangesStats
+	Net int
+}
+
+// NewSummary returns the summary of globalStats with authors sorted by the column
+// named like RenderOptions.Sort, or in the opposite order with reverse.
+func NewSummary(globalStats GlobalStats, sortColumn string, reverse bool) Summary {
+	order := authorOrder{sortColumn, reverse}
+	period := func(label string, authors map[string]ChangesStats) SummaryPeriod {
+		p := SummaryPeriod{Label: label}
+		for author, stats := range authors {
+			p.Authors = append(p.Authors, SummaryAuthor{
+				Author: author, Name: globalStats.Names[author], ChangesStats: stats, Net: stats.Insertions - stats.Deletions,
+			})
+			p.Insertions += stats.Insertions
+			p.Deletions += stats.Deletions
+			p.Commits += stats.Commits
+		}
+		p.Net = p.Insertions - p.Deletions
+		sort.Slice(p.Authors, func(i, j int) bool {
+			return order.less(p.Authors[i].Author, p.Authors[i].ChangesStats, p.Authors[j].Author, p.Authors[j].ChangesStats)
+		})
+		return p
+	}
+
+	var summary Summary
+	totals := make(map[string]ChangesStats)
+	for _, month := range sortedMonths(globalStats) {
+		authors := make(map[string]ChangesStats)
+		for author, months := range globalStats.Stats {
+			if stats, ok := months[month]; ok {
+				authors[author] = stats
+			}
+		}
+		addChanges(totals, authors)
+		summary.Periods = append(summary.Periods, period(month, authors))
+	}
+	summary.Total = period("Total", totals)
+	return summary
+}
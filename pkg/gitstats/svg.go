@@ -0,0 +1,228 @@
+package gitstats
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Charts are the charts RenderChart draws: the insertions and deletions per month, the
+// top contributors and the contributions per repository, stacked by author.
+var Charts = []string{"monthly", "contributors", "repositories"}
+
+// chartAuthors is the number of authors of the contributors chart.
+const chartAuthors = 10
+
+// chartColors are the colors of the authors of the repositories chart, the last one
+// for the others.
+var chartColors = []string{"#0969da", "#2da44e", "#bf8700", "#cf222e", "#8250df", "#1b7c83", "#bc4c00", "#6e7781"}
+
+// RenderChart writes chart, one of Charts, of globalStats as a standalone SVG image
+// that can be embedded in slides or web pages. The bars show insertions, or net lines
+// with opts.NetOnly, of the first authors in the order of opts.Sort and opts.Reverse.
+// The repositories chart needs the Repos of Options.ByRepo.
+func RenderChart(w io.Writer, globalStats GlobalStats, chart string, opts RenderOptions) error {
+	value := func(stats ChangesStats) int {
+		if opts.NetOnly {
+			return max(stats.Insertions-stats.Deletions, 0)
+		}
+		return stats.Insertions
+	}
+	order := authorOrder{opts.Sort, opts.Reverse}
+	svg := &svgWriter{}
+	switch chart {
+	case "monthly":
+		monthlyChart(svg, globalStats)
+	case "contributors":
+		contributorsChart(svg, globalStats, order, value)
+	case "repositories":
+		if globalStats.Repos == nil {
+			return fmt.Errorf("the repositories chart needs the stats per repository")
+		}
+		repositoriesChart(svg, globalStats, order, value)
+	default:
+		return fmt.Errorf("unknown chart: %s", chart)
+	}
+	_, err := io.WriteString(w, svg.String())
+	return err
+}
+
+// svgWriter collects the elements of an SVG image of a width and height grown to
+// contain them.
+type svgWriter struct {
+	elements      strings.Builder
+	width, height int
+}
+
+func (s *svgWriter) grow(x, y int) {
+	s.width, s.height = max(s.width, x), max(s.height, y)
+}
+
+// rect draws a rectangle with a tooltip, nothing when it is empty.
+func (s *svgWriter) rect(x, y, width, height int, color, title string) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	fmt.Fprintf(&s.elements, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"><title>%s</title></rect>`+"\n",
+		x, y, width, height, color, html.EscapeString(title))
+	s.grow(x+width, y+height)
+}
+
+// text writes text at x, y; anchor is start, middle or end.
+func (s *svgWriter) text(x, y int, anchor, text string) {
+	fmt.Fprintf(&s.elements, `<text x="%d" y="%d" text-anchor="%s">%s</text>`+"\n", x, y, anchor, html.EscapeString(text))
+	s.grow(x, y+4)
+}
+
+func (s *svgWriter) String() string {
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Helvetica, Arial, sans-serif" font-size="12" fill="#24292f">`+"\n"+
+		`<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n%s</svg>\n",
+		s.width+20, s.height+20, s.width+20, s.height+20, s.elements.String())
+}
+
+// scale returns n of largest in pixels of size.
+func scale(n, largest, size int) int {
+	if largest <= 0 || n <= 0 {
+		return 0
+	}
+	return int(int64(n) * int64(size) / int64(largest))
+}
+
+// monthlyChart draws a pair of insertion and deletion columns per month.
+func monthlyChart(s *svgWriter, globalStats GlobalStats) {
+	const height, column, top = 240, 18, 40
+	months := sortedMonths(globalStats)
+	totals := make(map[string]ChangesStats)
+	largest := 0
+	for _, month := range months {
+		var total ChangesStats
+		for _, stats := range globalStats.Stats {
+			total = addStats(total, stats[month])
+		}
+		totals[month] = total
+		largest = max(largest, total.Insertions, total.Deletions)
+	}
+
+	s.text(20, 24, "start", "Insertions and deletions per month")
+	for i, month := range months {
+		x := 60 + i*(3*column)
+		total := totals[month]
+		ins, del := scale(total.Insertions, largest, height), scale(total.Deletions, largest, height)
+		s.rect(x, top+height-ins, column, ins, "#2da44e", fmt.Sprintf("%s: %d insertions", month, total.Insertions))
+		s.rect(x+column, top+height-del, column, del, "#cf222e", fmt.Sprintf("%s: %d deletions", month, total.Deletions))
+		s.text(x+column, top+height+16, "middle", periodKey(month))
+	}
+	s.text(52, top+8, "end", fmt.Sprint(largest))
+	s.text(52, top+height, "end", "0")
+	s.grow(60+len(months)*3*column, top+height+20)
+}
+
+// contributorsChart draws a bar per top contributor.
+func contributorsChart(s *svgWriter, globalStats GlobalStats, order authorOrder, value func(ChangesStats) int) {
+	const width, row, left = 400, 22, 220
+	totals := authorTotals(globalStats)
+	authors := rankedAuthors(totals, order, value)
+	if len(authors) > chartAuthors {
+		authors = authors[:chartAuthors]
+	}
+	largest := 0
+	for _, author := range authors {
+		largest = max(largest, value(totals[author]))
+	}
+
+	s.text(20, 24, "start", "Top contributors")
+	for i, author := range authors {
+		y := 40 + i*row
+		n := value(totals[author])
+		s.text(left-8, y+14, "end", author)
+		s.rect(left, y, max(scale(n, largest, width), 1), row-6, "#2da44e", fmt.Sprintf("%s: %d", author, n))
+		s.text(left+scale(n, largest, width)+6, y+14, "start", fmt.Sprint(n))
+	}
+	s.grow(left+width+60, 40+len(authors)*row)
+}
+
+// repositoriesChart draws a bar per repository, stacked by the top authors overall and
+// the others.
+func repositoriesChart(s *svgWriter, globalStats GlobalStats, order authorOrder, value func(ChangesStats) int) {
+	const width, row, left = 400, 22, 220
+	top := rankedAuthors(authorTotals(globalStats), order, value)
+	if len(top) > len(chartColors)-1 {
+		top = top[:len(chartColors)-1]
+	}
+	colors := make(map[string]string)
+	for i, author := range top {
+		colors[author] = chartColors[i]
+	}
+
+	var names []string
+	repoTotals := make(map[string]int)
+	largest := 0
+	for name, repo := range globalStats.Repos {
+		names = append(names, name)
+		for _, stats := range authorTotals(*repo) {
+			repoTotals[name] += value(stats)
+		}
+		largest = max(largest, repoTotals[name])
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if repoTotals[names[i]] != repoTotals[names[j]] {
+			return repoTotals[names[i]] > repoTotals[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	s.text(20, 24, "start", "Contributions per repository")
+	for i, name := range names {
+		y := 40 + i*row
+		s.text(left-8, y+14, "end", name)
+		totals := authorTotals(*globalStats.Repos[name])
+		x, others := left, 0
+		for _, author := range top {
+			n := value(totals[author])
+			s.rect(x, y, scale(n, largest, width), row-6, colors[author], fmt.Sprintf("%s in %s: %d", author, name, n))
+			x += scale(n, largest, width)
+		}
+		for author, stats := range totals {
+			if colors[author] == "" {
+				others += value(stats)
+			}
+		}
+		s.rect(x, y, scale(others, largest, width), row-6, chartColors[len(chartColors)-1], fmt.Sprintf("others in %s: %d", name, others))
+		s.text(left+scale(repoTotals[name], largest, width)+6, y+14, "start", fmt.Sprint(repoTotals[name]))
+	}
+
+	// Legend below the bars
+	y := 40 + len(names)*row + 10
+	for i, author := range append(top, "others") {
+		s.rect(left, y+i*row, 12, 12, chartColors[min(i, len(chartColors)-1)], author)
+		s.text(left+18, y+i*row+11, "start", author)
+	}
+	s.grow(left+width+60, y+(len(top)+1)*row)
+}
+
+// authorTotals returns the changes of each author over all periods.
+func authorTotals(globalStats GlobalStats) map[string]ChangesStats {
+	totals := make(map[string]ChangesStats)
+	for author, months := range globalStats.Stats {
+		for _, stats := range months {
+			totals[author] = addStats(totals[author], stats)
+		}
+	}
+	return totals
+}
+
+// rankedAuthors returns the authors of totals with a value, in order.
+func rankedAuthors(totals map[string]ChangesStats, order authorOrder, value func(ChangesStats) int) []string {
+	var authors []string
+	for author, stats := range totals {
+		if value(stats) > 0 {
+			authors = append(authors, author)
+		}
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		return order.less(authors[i], totals[authors[i]], authors[j], totals[authors[j]])
+	})
+	return authors
+}
@@ -0,0 +1,177 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// borderStyle holds the characters used to draw a table.
+type borderStyle struct {
+	Horizontal, Vertical               string
+	TopLeft, TopMid, TopRight          string
+	MidLeft, MidMid, MidRight          string
+	BottomLeft, BottomMid, BottomRight string
+	noBorder                           bool
+	markdown                           bool // GitHub-flavored Markdown table
+}
+
+var borderStyles = map[string]borderStyle{
+	"ascii": {
+		Horizontal: "-", Vertical: "|",
+		TopLeft: "+", TopMid: "+", TopRight: "+",
+		MidLeft: "+", MidMid: "+", MidRight: "+",
+		BottomLeft: "+", BottomMid: "+", BottomRight: "+",
+	},
+	"unicode-box": {
+		Horizontal: "─", Vertical: "│",
+		TopLeft: "┌", TopMid: "┬", TopRight: "┐",
+		MidLeft: "├", MidMid: "┼", MidRight: "┤",
+		BottomLeft: "└", BottomMid: "┴", BottomRight: "┘",
+	},
+	"none":     {noBorder: true},
+	"markdown": {markdown: true},
+}
+
+// table is a minimal aligned table. Cells may contain ANSI color codes, which are
+// ignored when measuring widths. Columns flagged in rightAlign are right aligned.
+type table struct {
+	header     []string
+	rows       [][]string
+	footer     []string
+	rightAlign []bool
+}
+
+var ansiRegex = regexp.MustCompile(`\033\[[0-9;]*m`)
+
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiRegex.ReplaceAllString(s, ""))
+}
+
+func (t *table) addRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *table) render(w io.Writer, style borderStyle) {
+	if style.markdown {
+		t.renderMarkdown(w)
+		return
+	}
+
+	widths := make([]int, len(t.header))
+	for _, row := range append(append([][]string{t.header}, t.rows...), t.footer) {
+		for i, cell := range row {
+			if width := visibleWidth(cell); i < len(widths) && width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	line := func(left, mid, right string) {
+		if style.noBorder {
+			return
+		}
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			parts[i] = strings.Repeat(style.Horizontal, width+2)
+		}
+		fmt.Fprintf(w, "  %s%s%s\n", left, strings.Join(parts, mid), right)
+	}
+	row := func(cells []string) {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			padding := strings.Repeat(" ", width-visibleWidth(cell))
+			if i < len(t.rightAlign) && t.rightAlign[i] {
+				parts[i] = padding + cell
+			} else {
+				parts[i] = cell + padding
+			}
+		}
+		if style.noBorder {
+			fmt.Fprintf(w, "  %s\n", strings.TrimRight(strings.Join(parts, "  "), " "))
+			return
+		}
+		sep := " " + style.Vertical + " "
+		fmt.Fprintf(w, "  %s %s %s\n", style.Vertical, strings.Join(parts, sep), style.Vertical)
+	}
+
+	line(style.TopLeft, style.TopMid, style.TopRight)
+	row(t.header)
+	line(style.MidLeft, style.MidMid, style.MidRight)
+	for _, cells := range t.rows {
+		row(cells)
+	}
+	if t.footer != nil {
+		line(style.MidLeft, style.MidMid, style.MidRight)
+		row(t.footer)
+	}
+	line(style.BottomLeft, style.BottomMid, style.BottomRight)
+}
+
+// renderMarkdown renders the table as a GitHub-flavored Markdown table. The footer
+// becomes the last row, with its label in bold, and pipes in cells are escaped.
+func (t *table) renderMarkdown(w io.Writer) {
+	var rows [][]string
+	for r, cells := range append(append([][]string{t.header}, t.rows...), t.footer) {
+		if cells == nil {
+			continue
+		}
+		escaped := make([]string, len(cells))
+		for i, cell := range cells {
+			escaped[i] = strings.ReplaceAll(ansiRegex.ReplaceAllString(cell, ""), "|", `\|`)
+			if r == len(t.rows)+1 && i == 0 && escaped[i] != "" {
+				escaped[i] = "**" + escaped[i] + "**"
+			}
+		}
+		rows = append(rows, escaped)
+	}
+	// Separator cells need at least three dashes
+	widths := make([]int, len(t.header))
+	for i := range widths {
+		widths[i] = 3
+	}
+	for _, cells := range rows {
+		for i, cell := range cells {
+			if width := visibleWidth(cell); i < len(widths) && width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	row := func(cells []string) {
+		parts := make([]string, len(widths))
+		for i, width := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			padding := strings.Repeat(" ", width-visibleWidth(cell))
+			if i < len(t.rightAlign) && t.rightAlign[i] {
+				parts[i] = padding + cell
+			} else {
+				parts[i] = cell + padding
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(parts, " | "))
+	}
+
+	row(rows[0])
+	separators := make([]string, len(widths))
+	for i, width := range widths {
+		if i < len(t.rightAlign) && t.rightAlign[i] {
+			separators[i] = strings.Repeat("-", width-1) + ":"
+		} else {
+			separators[i] = strings.Repeat("-", width)
+		}
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(separators, " | "))
+	for _, cells := range rows[1:] {
+		row(cells)
+	}
+}
@@ -0,0 +1,147 @@
+package gitstats
+
+import "time"
+
+// Team is a group of authors reported as one by GroupByTeam.
+type Team struct {
+	Name    string
+	Members []string // Author patterns: globs, substrings or /regexps/ as in Options.Authors
+}
+
+// NoTeam is the team of the authors matching no Team.
+const NoTeam = "(no team)"
+
+// teamOf returns the name of the first of teams with a member matching email, or NoTeam.
+func teamOf(email string, teams []Team) string {
+	for _, team := range teams {
+		if matchAuthor(email, team.Members) {
+			return team.Name
+		}
+	}
+	return NoTeam
+}
+
+// GroupByTeam returns a copy of globalStats, and of its Repos and Squashes, with the
+// changes, tags and binary files of each team's authors summed under the team name and
+// the most recent commit of its authors. Display names, pull requests and outlier
+// commits are kept per person.
+func GroupByTeam(globalStats *GlobalStats, teams []Team) (*GlobalStats, error) {
+	for _, team := range teams {
+		if err := checkAuthorPatterns(team.Members); err != nil {
+			return nil, err
+		}
+	}
+	return groupByTeam(globalStats, teams), nil
+}
+
+func groupByTeam(globalStats *GlobalStats, teams []Team) *GlobalStats {
+	return renameAuthors(globalStats, func(author string) string { return teamOf(author, teams) })
+}
+
+// renameAuthors returns a copy of globalStats, and of its Repos and Squashes, with the
+// changes, active days, commit sizes, heatmap, last commit, languages, components,
+// tickets, metrics, changed files, tags and binary files of each author moved to rename(author), summing those renamed alike.
+func renameAuthors(globalStats *GlobalStats, rename func(author string) string) *GlobalStats {
+	grouped := *globalStats
+	grouped.Stats = make(map[string]map[string]ChangesStats)
+	for author, months := range globalStats.Stats {
+		to := rename(author)
+		if grouped.Stats[to] == nil {
+			grouped.Stats[to] = make(map[string]ChangesStats)
+		}
+		addChanges(grouped.Stats[to], months)
+	}
+	if globalStats.ActiveDays != nil {
+		grouped.ActiveDays = make(map[string]map[string]bool)
+		for author, days := range globalStats.ActiveDays {
+			addDays(grouped.ActiveDays, rename(author), days)
+		}
+	}
+	if globalStats.CommitSizes != nil {
+		grouped.CommitSizes = make(map[string][]int)
+		for author, sizes := range globalStats.CommitSizes {
+			to := rename(author)
+			grouped.CommitSizes[to] = append(grouped.CommitSizes[to], sizes...)
+		}
+	}
+	if globalStats.Heatmap != nil {
+		grouped.Heatmap = make(map[string]Heatmap)
+		for author, hours := range globalStats.Heatmap {
+			addHeatmap(grouped.Heatmap, rename(author), hours)
+		}
+	}
+	grouped.LastCommit = make(map[string]time.Time)
+	for author, last := range globalStats.LastCommit {
+		if to := rename(author); last.After(grouped.LastCommit[to]) {
+			grouped.LastCommit[to] = last
+		}
+	}
+	if globalStats.Languages != nil {
+		grouped.Languages = make(map[string]map[string]ChangesStats)
+		for author, languages := range globalStats.Languages {
+			to := rename(author)
+			if grouped.Languages[to] == nil {
+				grouped.Languages[to] = make(map[string]ChangesStats)
+			}
+			addChanges(grouped.Languages[to], languages)
+		}
+	}
+	if globalStats.Components != nil {
+		grouped.Components = make(map[string]map[string]ChangesStats)
+		for author, components := range globalStats.Components {
+			for component, stats := range components {
+				grouped.addComponent(component, map[string]ChangesStats{rename(author): stats})
+			}
+		}
+	}
+	if globalStats.Tickets != nil {
+		grouped.Tickets = make(map[string]map[string]ChangesStats)
+		for author, tickets := range globalStats.Tickets {
+			grouped.addTickets(rename(author), tickets)
+		}
+	}
+	if globalStats.Owners != nil {
+		grouped.Owners = make(map[string]map[string]ChangesStats)
+		for author, owners := range globalStats.Owners {
+			grouped.addOwners(rename(author), owners)
+		}
+	}
+	if globalStats.Metrics != nil {
+		grouped.Metrics = make(map[string]map[string]map[string]int)
+		for author, months := range globalStats.Metrics {
+			for month, values := range months {
+				grouped.addMetrics(rename(author), month, values)
+			}
+		}
+	}
+	if globalStats.Files != nil {
+		grouped.Files = make(map[string]map[string]map[string]bool)
+		for author, periods := range globalStats.Files {
+			for period, files := range periods {
+				grouped.addFiles(rename(author), period, files)
+			}
+		}
+	}
+	if globalStats.Tags != nil {
+		grouped.Tags = make(map[string]int)
+		for author, tags := range globalStats.Tags {
+			grouped.Tags[rename(author)] += tags
+		}
+	}
+	if globalStats.Binary != nil {
+		grouped.Binary = make(map[string]int)
+		for author, files := range globalStats.Binary {
+			grouped.Binary[rename(author)] += files
+		}
+	}
+	if globalStats.Repos != nil {
+		grouped.Repos = make(map[string]*GlobalStats)
+		for name, repo := range globalStats.Repos {
+			grouped.Repos[name] = renameAuthors(repo, rename)
+		}
+	}
+	if globalStats.Squashes != nil {
+		grouped.Squashes = renameAuthors(globalStats.Squashes, rename)
+	}
+	return &grouped
+}
@@ -0,0 +1,44 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// TemplateFuncs are the functions available to report templates besides the built-in
+// ones of text/template: add and sub do integer arithmetic, percent returns part of
+// whole in percent rounded down, and 0 for an empty whole.
+var TemplateFuncs = template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+	"sub": func(a, b int) int { return a - b },
+	"percent": func(part, whole int) int {
+		if whole == 0 {
+			return 0
+		}
+		return part * 100 / whole
+	},
+}
+
+// ParseTemplate parses a report template for RenderOptions.Template, with
+// TemplateFuncs.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(TemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse report template: %s", err)
+	}
+	return tmpl, nil
+}
+
+// printTemplate executes tmpl on the Summary of globalStats, the months in
+// chronological order (.Periods), each with its totals and sorted authors, and the
+// totals per author over all of them (.Total).
+func printTemplate(w io.Writer, globalStats GlobalStats, tmpl *template.Template, order authorOrder) error {
+	if tmpl == nil {
+		return fmt.Errorf("the template format needs RenderOptions.Template")
+	}
+	if err := tmpl.Execute(w, NewSummary(globalStats, order.column, order.reverse)); err != nil {
+		return fmt.Errorf("failed to execute report template: %s", err)
+	}
+	return nil
+}
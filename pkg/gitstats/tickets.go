@@ -0,0 +1,87 @@
+package gitstats
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DefaultTicketPattern matches JIRA-style ticket IDs such as PROJ-123.
+const DefaultTicketPattern = `\b[A-Z][A-Z0-9]+-[0-9]+\b`
+
+// ParseTickets parses `git log --pretty=%x00%H%x09%aE%x09%B%x1e --shortstat` output and
+// returns the changes per author email and ticket ID matched by pattern in the commit
+// messages. A commit naming several tickets counts fully for each of them; commits
+// naming none are left out.
+func ParseTickets(output string, pattern *regexp.Regexp, caseSensitive bool) map[string]map[string]ChangesStats {
+	tickets := make(map[string]map[string]ChangesStats)
+	for _, record := range strings.Split(output, "\x00") {
+		header, stat, ok := strings.Cut(record, "\x1e")
+		if !ok {
+			continue
+		}
+		fields := strings.SplitN(header, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		ids := pattern.FindAllString(fields[2], -1)
+		if len(ids) == 0 {
+			continue
+		}
+		author := strings.TrimSpace(fields[1])
+		if !caseSensitive {
+			author = strings.ToLower(author)
+		}
+		changes := ChangesStats{Commits: 1}
+		for _, line := range strings.Split(stat, "\n") {
+			if ins, del, ok := parseShortstat(line); ok {
+				changes.Insertions, changes.Deletions = ins, del
+			}
+		}
+		if tickets[author] == nil {
+			tickets[author] = make(map[string]ChangesStats)
+		}
+		counted := make(map[string]bool)
+		for _, id := range ids {
+			if !counted[id] {
+				counted[id] = true
+				tickets[author][id] = addStats(tickets[author][id], changes)
+			}
+		}
+	}
+	return tickets
+}
+
+// processTickets returns the changes per author and ticket of the commits of dir in the
+// analyzed window.
+func (c *collector) processTickets(dir string, pattern *regexp.Regexp) (map[string]map[string]ChangesStats, error) {
+	args := append(c.gitArgs(dir), "log", "--pretty=%x00%H%x09%aE%x09%B%x1e", "--shortstat")
+	args = append(args, c.diffArgs()...)
+	args = append(args, c.revArgs()...)
+	args = append(args, c.mergeArgs())
+	args = append(args, c.window.ArgsIn(c.opts.Location)...)
+	args = append(args, c.pathspec(dir)...)
+
+	output, err := c.git(args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTickets(string(output), pattern, c.opts.CaseSensitiveEmails), nil
+}
+
+// addTickets accumulates the changes per ticket of author.
+func (gb *GlobalStats) addTickets(author string, tickets map[string]ChangesStats) {
+	if gb.Tickets == nil {
+		gb.Tickets = make(map[string]map[string]ChangesStats)
+	}
+	if gb.Tickets[author] == nil {
+		gb.Tickets[author] = make(map[string]ChangesStats)
+	}
+	addChanges(gb.Tickets[author], tickets)
+}
+
+// printTickets prints the changes per ticket, most net lines first, with the authors
+// who worked on each.
+func printTickets(w io.Writer, globalStats GlobalStats, style borderStyle) {
+	printBreakdown(w, globalStats.Tickets, "Tickets (from commit messages):", "Ticket", "No commit message names a ticket", style)
+}
@@ -0,0 +1,124 @@
+package gitstats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// TrendThreshold is how steep a trend line is before Trend flags it: a change per
+// month of a quarter of the monthly mean.
+const TrendThreshold = 0.25
+
+// Trend signals, see Trend.Signal.
+const (
+	TrendRising   = "rising"
+	TrendDropping = "dropping"
+)
+
+// Trend is the least-squares line through a monthly series such as the insertions of
+// an author, oldest month first.
+type Trend struct {
+	Slope    float64 // Change per month along the line
+	Mean     float64 // Mean of the months
+	Last     int     // Value of the last month
+	Previous int     // Value of the month before it
+	Forecast int     // Next month along the line, 0 at the least
+	// Signal is TrendRising or TrendDropping when the slope is at least TrendThreshold
+	// of the mean over three months or more, and "" otherwise
+	Signal string
+}
+
+// NewTrend returns the Trend of values, one per month; fewer than two months have no
+// slope.
+func NewTrend(values []int) Trend {
+	var trend Trend
+	n := float64(len(values))
+	if len(values) == 0 {
+		return trend
+	}
+	trend.Last = values[len(values)-1]
+	if len(values) > 1 {
+		trend.Previous = values[len(values)-2]
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, v := range values {
+		x, y := float64(i), float64(v)
+		sumX, sumY, sumXY, sumXX = sumX+x, sumY+y, sumXY+x*y, sumXX+x*x
+	}
+	trend.Mean = sumY / n
+	if len(values) < 2 {
+		trend.Forecast = trend.Last
+		return trend
+	}
+	trend.Slope = (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	intercept := (sumY - trend.Slope*sumX) / n
+	trend.Forecast = max(0, int(math.Round(intercept+trend.Slope*n)))
+	if len(values) >= 3 && trend.Mean > 0 {
+		switch relative := trend.Slope / trend.Mean; {
+		case relative >= TrendThreshold:
+			trend.Signal = TrendRising
+		case relative <= -TrendThreshold:
+			trend.Signal = TrendDropping
+		}
+	}
+	return trend
+}
+
+// Change returns the percent change of the last month over the one before, and false
+// when the month before had nothing to compare with.
+func (t Trend) Change() (float64, bool) {
+	if t.Previous == 0 {
+		return 0, false
+	}
+	return float64(t.Last-t.Previous) * 100 / float64(t.Previous), true
+}
+
+// printTrends prints the trend of the insertions per month, overall and per author in
+// the order of the developer table, with a sparkline of the months.
+func printTrends(w io.Writer, globalStats GlobalStats, order authorOrder, style borderStyle) {
+	red := "\033[31m"
+	green := "\033[32m"
+	blue := "\033[94m"
+	reset := "\033[0m"
+
+	months := analyzedMonths(globalStats)
+	fmt.Fprintf(w, "\n%sTrends of insertions per month:%s\n", blue, reset)
+	if len(months) < 2 {
+		fmt.Fprintf(w, "  Trends need at least two months\n")
+		return
+	}
+
+	row := func(label string, series []int) []string {
+		trend := NewTrend(series)
+		change := "-"
+		if percent, ok := trend.Change(); ok {
+			change = fmt.Sprintf("%+.0f%%", percent)
+		}
+		signal := ""
+		switch trend.Signal {
+		case TrendRising:
+			signal = green + "▲ " + TrendRising + reset
+		case TrendDropping:
+			signal = red + "▼ " + TrendDropping + reset
+		}
+		return []string{label, sparkline(series), fmt.Sprintf("%+.1f", trend.Slope), change, strconv.Itoa(trend.Forecast), signal}
+	}
+
+	totals := make(map[string]ChangesStats)
+	for author, authorMonths := range globalStats.Stats {
+		for _, stats := range authorMonths {
+			totals[author] = addStats(totals[author], stats)
+		}
+	}
+	t := table{
+		header:     []string{"Author", periodKey(months[0]) + " - " + periodKey(months[len(months)-1]), "Slope/month", "Last month", "Forecast", ""},
+		rightAlign: []bool{false, false, true, true, true, false},
+	}
+	for _, author := range rankedAuthors(totals, order, func(stats ChangesStats) int { return stats.Commits }) {
+		t.addRow(row(author, insertionSeries(globalStats, author, months))...)
+	}
+	t.footer = row("Total", insertionSeries(globalStats, "", months))
+	t.render(w, style)
+}
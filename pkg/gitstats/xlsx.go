@@ -0,0 +1,198 @@
+package gitstats
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xlsxColumns are the columns of every sheet of the workbook; the author column is
+// named Team with RenderOptions.Teams.
+var xlsxColumns = []string{"Author", "Name", "Commits", "Insertions", "Deletions", "Net"}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+%s</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+// xlsxStyles has the cell styles 0 (plain) and 1 (bold, for headers and totals), and
+// the differential style 0 the Net column's negative values are shown in.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+<dxfs count="1"><dxf><font><color rgb="FF9C0006"/></font><fill><patternFill><bgColor rgb="FFFFC7CE"/></patternFill></fill></dxf></dxfs>
+</styleSheet>`
+
+// RenderXLSX writes globalStats as an Excel workbook: a Summary sheet with the totals
+// per author over all periods, then a sheet per month, authors sorted like the text
+// report. Each sheet ends with a total row, shades the insertions with data bars and
+// highlights negative net lines.
+func RenderXLSX(w io.Writer, globalStats GlobalStats, opts RenderOptions) error {
+	summary := NewSummary(globalStats, opts.Sort, opts.Reverse)
+	periods := append([]SummaryPeriod{summary.Total}, summary.Periods...)
+	names := xlsxSheetNames(periods)
+
+	z := zip.NewWriter(w)
+	write := func(name, content string) error {
+		f, err := z.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, content)
+		return err
+	}
+
+	var overrides, sheets, rels strings.Builder
+	for i, name := range names {
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`+"\n", i+1)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`+"\n", i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`+"\n", len(names)+1)
+
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, overrides.String())},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+` + rels.String() + `</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, period := range periods {
+		files = append(files, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), xlsxSheet(period, opts.Teams)})
+	}
+	for _, file := range files {
+		if err := write(file.name, file.content); err != nil {
+			return fmt.Errorf("failed to write workbook: %s", err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		return fmt.Errorf("failed to write workbook: %s", err)
+	}
+	return nil
+}
+
+// xlsxSheetNames returns the sheet name of each period: Summary for the totals, then
+// the month labels, shortened to the 31 characters Excel allows and without the
+// characters it rejects.
+func xlsxSheetNames(periods []SummaryPeriod) []string {
+	names := make([]string, len(periods))
+	used := make(map[string]bool)
+	for i, period := range periods {
+		name := "Summary"
+		if i > 0 {
+			name = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(`[]:*?/\`, r) {
+					return '-'
+				}
+				return r
+			}, strings.Trim(period.Label, "'"))
+		}
+		if runes := []rune(name); len(runes) > 31 {
+			name = string(runes[:31])
+		}
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			suffix := fmt.Sprintf(" (%d)", n)
+			runes := []rune(name)
+			if len(runes)+len(suffix) > 31 {
+				runes = runes[:31-len(suffix)]
+			}
+			name = string(runes) + suffix
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// xlsxSheet returns the worksheet of period: a header row, a row per author and the
+// total row, summing the columns with formulas that keep the totals as cached values.
+func xlsxSheet(period SummaryPeriod, teams bool) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>
+<cols><col min="1" max="1" width="36" customWidth="1"/><col min="2" max="2" width="24" customWidth="1"/><col min="3" max="6" width="12" customWidth="1"/></cols>
+<sheetData>`)
+
+	header := append([]string(nil), xlsxColumns...)
+	if teams {
+		header[0] = "Team"
+	}
+	b.WriteString(`<row r="1">`)
+	for i, column := range header {
+		xlsxString(&b, i, 1, column, 1)
+	}
+	b.WriteString("</row>")
+
+	for i, author := range period.Authors {
+		row := i + 2
+		fmt.Fprintf(&b, `<row r="%d">`, row)
+		xlsxString(&b, 0, row, author.Author, 0)
+		if author.Name != "" {
+			xlsxString(&b, 1, row, author.Name, 0)
+		}
+		for j, value := range []int{author.Commits, author.Insertions, author.Deletions, author.Net} {
+			fmt.Fprintf(&b, `<c r="%c%d"><v>%d</v></c>`, 'C'+j, row, value)
+		}
+		b.WriteString("</row>")
+	}
+
+	last := len(period.Authors) + 1
+	total := last + 1
+	fmt.Fprintf(&b, `<row r="%d">`, total)
+	xlsxString(&b, 0, total, "Total", 1)
+	for j, value := range []int{period.Commits, period.Insertions, period.Deletions, period.Net} {
+		column := 'C' + j
+		if last < 2 {
+			fmt.Fprintf(&b, `<c r="%c%d" s="1"><v>%d</v></c>`, column, total, value)
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%c%d" s="1"><f>SUM(%c2:%c%d)</f><v>%d</v></c>`, column, total, column, column, last, value)
+	}
+	b.WriteString("</row></sheetData>")
+
+	if last >= 2 {
+		fmt.Fprintf(&b, `<conditionalFormatting sqref="D2:D%d"><cfRule type="dataBar" priority="1"><dataBar><cfvo type="min"/><cfvo type="max"/><color rgb="FF63BE7B"/></dataBar></cfRule></conditionalFormatting>`, last)
+		fmt.Fprintf(&b, `<conditionalFormatting sqref="F2:F%d"><cfRule type="cellIs" dxfId="0" priority="2" operator="lessThan"><formula>0</formula></cfRule></conditionalFormatting>`, last)
+	}
+	b.WriteString("</worksheet>")
+	return b.String()
+}
+
+// xlsxString writes an inline string cell in column (0 = A) of row with style.
+func xlsxString(b *strings.Builder, column, row int, value string, style int) {
+	fmt.Fprintf(b, `<c r="%c%d" t="inlineStr"`, 'A'+column, row)
+	if style != 0 {
+		fmt.Fprintf(b, ` s="%d"`, style)
+	}
+	fmt.Fprintf(b, `><is><t xml:space="preserve">%s</t></is></c>`, xlsxEscape(value))
+}
+
+// xlsxEscape escapes text for XML, dropping the control characters XML 1.0 cannot
+// hold.
+func xlsxEscape(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(text)
+}